@@ -17,10 +17,64 @@ type EgsFreeGame struct {
 	ThumbnailUrl string
 	StartDate    pgtype.Timestamptz
 	EndDate      pgtype.Timestamptz
+	Status       string
+	Reminded     bool
+	Source       string
+	OpenEnded    bool
+}
+
+type EgsFreeGameAnnouncement struct {
+	ID         int64
+	FreeGameID int64
+	ChannelID  string
+	MessageID  string
+	Expired    bool
+}
+
+type EgsFreeGameOutbox struct {
+	ID          int64
+	FreeGameID  int64
+	ChannelID   string
+	Kind        string
+	PingRoleID  string
+	MessageID   string
+	Sent        bool
+	Attempts    int32
+	NextAttempt pgtype.Timestamptz
+	Created     pgtype.Timestamptz
+}
+
+type GuildSetting struct {
+	GuildID         string
+	DealsChannelID  string
+	AlertsChannelID string
+	Region          string
+	Locale          string
+	PingRoleID      string
+	Updated         pgtype.Timestamptz
+}
+
+type JobRun struct {
+	Job        string
+	Started    pgtype.Timestamptz
+	DurationMs int64
+	Error      string
+}
+
+type WowTokenAlert struct {
+	ID        int64
+	UserID    string
+	GuildID   string
+	Region    string
+	Direction string
+	Threshold int64
+	Triggered bool
+	Created   pgtype.Timestamptz
 }
 
 type WowTokenPrice struct {
 	ID      int64
 	Updated pgtype.Timestamptz
 	Price   int64
+	Region  string
 }
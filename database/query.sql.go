@@ -19,11 +19,15 @@ INSERT INTO egs_free_games (
     url,
     thumbnail_url,
     start_date,
-    end_date
+    end_date,
+    status,
+    source,
+    open_ended
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 )
-RETURNING id, store_id, title, description, url, thumbnail_url, start_date, end_date
+ON CONFLICT DO NOTHING
+RETURNING id, store_id, title, description, url, thumbnail_url, start_date, end_date, status, reminded, source, open_ended
 `
 
 type AddFreeGameParams struct {
@@ -34,6 +38,9 @@ type AddFreeGameParams struct {
 	ThumbnailUrl string
 	StartDate    pgtype.Timestamptz
 	EndDate      pgtype.Timestamptz
+	Status       string
+	Source       string
+	OpenEnded    bool
 }
 
 func (q *Queries) AddFreeGame(ctx context.Context, arg AddFreeGameParams) (EgsFreeGame, error) {
@@ -45,6 +52,9 @@ func (q *Queries) AddFreeGame(ctx context.Context, arg AddFreeGameParams) (EgsFr
 		arg.ThumbnailUrl,
 		arg.StartDate,
 		arg.EndDate,
+		arg.Status,
+		arg.Source,
+		arg.OpenEnded,
 	)
 	var i EgsFreeGame
 	err := row.Scan(
@@ -56,33 +66,175 @@ func (q *Queries) AddFreeGame(ctx context.Context, arg AddFreeGameParams) (EgsFr
 		&i.ThumbnailUrl,
 		&i.StartDate,
 		&i.EndDate,
+		&i.Status,
+		&i.Reminded,
+		&i.Source,
+		&i.OpenEnded,
+	)
+	return i, err
+}
+
+const addFreeGameAnnouncement = `-- name: AddFreeGameAnnouncement :exec
+INSERT INTO egs_free_game_announcements (
+    free_game_id, channel_id, message_id
+) VALUES (
+    $1, $2, $3
+)
+`
+
+type AddFreeGameAnnouncementParams struct {
+	FreeGameID int64
+	ChannelID  string
+	MessageID  string
+}
+
+func (q *Queries) AddFreeGameAnnouncement(ctx context.Context, arg AddFreeGameAnnouncementParams) error {
+	_, err := q.db.Exec(ctx, addFreeGameAnnouncement, arg.FreeGameID, arg.ChannelID, arg.MessageID)
+	return err
+}
+
+const addFreeGameOutbox = `-- name: AddFreeGameOutbox :exec
+INSERT INTO egs_free_game_outbox (
+    free_game_id, channel_id, kind, ping_role_id
+) VALUES (
+    $1, $2, $3, $4
+)
+`
+
+type AddFreeGameOutboxParams struct {
+	FreeGameID int64
+	ChannelID  string
+	Kind       string
+	PingRoleID string
+}
+
+func (q *Queries) AddFreeGameOutbox(ctx context.Context, arg AddFreeGameOutboxParams) error {
+	_, err := q.db.Exec(ctx, addFreeGameOutbox,
+		arg.FreeGameID,
+		arg.ChannelID,
+		arg.Kind,
+		arg.PingRoleID,
+	)
+	return err
+}
+
+const addTokenAlert = `-- name: AddTokenAlert :one
+INSERT INTO wow_token_alerts (
+    user_id, guild_id, region, direction, threshold
+) VALUES (
+    $1, $2, $3, $4, $5
+)
+RETURNING id, user_id, guild_id, region, direction, threshold, triggered, created
+`
+
+type AddTokenAlertParams struct {
+	UserID    string
+	GuildID   string
+	Region    string
+	Direction string
+	Threshold int64
+}
+
+func (q *Queries) AddTokenAlert(ctx context.Context, arg AddTokenAlertParams) (WowTokenAlert, error) {
+	row := q.db.QueryRow(ctx, addTokenAlert,
+		arg.UserID,
+		arg.GuildID,
+		arg.Region,
+		arg.Direction,
+		arg.Threshold,
+	)
+	var i WowTokenAlert
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.GuildID,
+		&i.Region,
+		&i.Direction,
+		&i.Threshold,
+		&i.Triggered,
+		&i.Created,
 	)
 	return i, err
 }
 
 const addTokenPrice = `-- name: AddTokenPrice :one
 INSERT INTO wow_token_prices (
-    updated, price
+    region, updated, price
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 )
-RETURNING id, updated, price
+RETURNING id, updated, price, region
 `
 
 type AddTokenPriceParams struct {
+	Region  string
 	Updated pgtype.Timestamptz
 	Price   int64
 }
 
 func (q *Queries) AddTokenPrice(ctx context.Context, arg AddTokenPriceParams) (WowTokenPrice, error) {
-	row := q.db.QueryRow(ctx, addTokenPrice, arg.Updated, arg.Price)
+	row := q.db.QueryRow(ctx, addTokenPrice, arg.Region, arg.Updated, arg.Price)
 	var i WowTokenPrice
-	err := row.Scan(&i.ID, &i.Updated, &i.Price)
+	err := row.Scan(
+		&i.ID,
+		&i.Updated,
+		&i.Price,
+		&i.Region,
+	)
 	return i, err
 }
 
+const claimFreeGameOutbox = `-- name: ClaimFreeGameOutbox :execrows
+UPDATE egs_free_game_outbox SET next_attempt = $2
+WHERE id = $1 AND NOT sent AND next_attempt <= NOW()
+`
+
+type ClaimFreeGameOutboxParams struct {
+	ID          int64
+	NextAttempt pgtype.Timestamptz
+}
+
+func (q *Queries) ClaimFreeGameOutbox(ctx context.Context, arg ClaimFreeGameOutboxParams) (int64, error) {
+	result, err := q.db.Exec(ctx, claimFreeGameOutbox, arg.ID, arg.NextAttempt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const deleteTokenAlert = `-- name: DeleteTokenAlert :execrows
+DELETE FROM wow_token_alerts WHERE id = $1 AND user_id = $2
+`
+
+type DeleteTokenAlertParams struct {
+	ID     int64
+	UserID string
+}
+
+func (q *Queries) DeleteTokenAlert(ctx context.Context, arg DeleteTokenAlertParams) (int64, error) {
+	result, err := q.db.Exec(ctx, deleteTokenAlert, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const extendFreeGame = `-- name: ExtendFreeGame :exec
+UPDATE egs_free_games SET end_date = $2 WHERE id = $1 AND open_ended
+`
+
+type ExtendFreeGameParams struct {
+	ID      int64
+	EndDate pgtype.Timestamptz
+}
+
+func (q *Queries) ExtendFreeGame(ctx context.Context, arg ExtendFreeGameParams) error {
+	_, err := q.db.Exec(ctx, extendFreeGame, arg.ID, arg.EndDate)
+	return err
+}
+
 const getAllFreeGames = `-- name: GetAllFreeGames :many
-SELECT id, store_id, title, description, url, thumbnail_url, start_date, end_date from egs_free_games ORDER BY id DESC
+SELECT id, store_id, title, description, url, thumbnail_url, start_date, end_date, status, reminded, source, open_ended from egs_free_games ORDER BY id DESC
 `
 
 func (q *Queries) GetAllFreeGames(ctx context.Context) ([]EgsFreeGame, error) {
@@ -103,6 +255,42 @@ func (q *Queries) GetAllFreeGames(ctx context.Context) ([]EgsFreeGame, error) {
 			&i.ThumbnailUrl,
 			&i.StartDate,
 			&i.EndDate,
+			&i.Status,
+			&i.Reminded,
+			&i.Source,
+			&i.OpenEnded,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getAllGuildSettings = `-- name: GetAllGuildSettings :many
+SELECT guild_id, deals_channel_id, alerts_channel_id, region, locale, ping_role_id, updated FROM guild_settings ORDER BY guild_id
+`
+
+func (q *Queries) GetAllGuildSettings(ctx context.Context) ([]GuildSetting, error) {
+	rows, err := q.db.Query(ctx, getAllGuildSettings)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GuildSetting
+	for rows.Next() {
+		var i GuildSetting
+		if err := rows.Scan(
+			&i.GuildID,
+			&i.DealsChannelID,
+			&i.AlertsChannelID,
+			&i.Region,
+			&i.Locale,
+			&i.PingRoleID,
+			&i.Updated,
 		); err != nil {
 			return nil, err
 		}
@@ -115,7 +303,7 @@ func (q *Queries) GetAllFreeGames(ctx context.Context) ([]EgsFreeGame, error) {
 }
 
 const getAllTokenPrices = `-- name: GetAllTokenPrices :many
-SELECT id, updated, price FROM wow_token_prices ORDER BY id DESC
+SELECT id, updated, price, region FROM wow_token_prices ORDER BY id DESC
 `
 
 func (q *Queries) GetAllTokenPrices(ctx context.Context) ([]WowTokenPrice, error) {
@@ -127,7 +315,12 @@ func (q *Queries) GetAllTokenPrices(ctx context.Context) ([]WowTokenPrice, error
 	var items []WowTokenPrice
 	for rows.Next() {
 		var i WowTokenPrice
-		if err := rows.Scan(&i.ID, &i.Updated, &i.Price); err != nil {
+		if err := rows.Scan(
+			&i.ID,
+			&i.Updated,
+			&i.Price,
+			&i.Region,
+		); err != nil {
 			return nil, err
 		}
 		items = append(items, i)
@@ -139,16 +332,21 @@ func (q *Queries) GetAllTokenPrices(ctx context.Context) ([]WowTokenPrice, error
 }
 
 const getAllTokenPricesSince = `-- name: GetAllTokenPricesSince :many
-SELECT price, updated FROM wow_token_prices WHERE updated >= $1 ORDER BY id DESC
+SELECT price, updated FROM wow_token_prices WHERE region = $1 AND updated >= $2 ORDER BY id DESC
 `
 
+type GetAllTokenPricesSinceParams struct {
+	Region  string
+	Updated pgtype.Timestamptz
+}
+
 type GetAllTokenPricesSinceRow struct {
 	Price   int64
 	Updated pgtype.Timestamptz
 }
 
-func (q *Queries) GetAllTokenPricesSince(ctx context.Context, updated pgtype.Timestamptz) ([]GetAllTokenPricesSinceRow, error) {
-	rows, err := q.db.Query(ctx, getAllTokenPricesSince, updated)
+func (q *Queries) GetAllTokenPricesSince(ctx context.Context, arg GetAllTokenPricesSinceParams) ([]GetAllTokenPricesSinceRow, error) {
+	rows, err := q.db.Query(ctx, getAllTokenPricesSince, arg.Region, arg.Updated)
 	if err != nil {
 		return nil, err
 	}
@@ -168,7 +366,7 @@ func (q *Queries) GetAllTokenPricesSince(ctx context.Context, updated pgtype.Tim
 }
 
 const getCurrentFreeGames = `-- name: GetCurrentFreeGames :many
-SELECT id, store_id, title, description, url, thumbnail_url, start_date, end_date from egs_free_games WHERE start_date < NOW() AND end_date > NOW() ORDER BY id DESC
+SELECT id, store_id, title, description, url, thumbnail_url, start_date, end_date, status, reminded, source, open_ended from egs_free_games WHERE start_date < NOW() AND end_date > NOW() ORDER BY id DESC
 `
 
 func (q *Queries) GetCurrentFreeGames(ctx context.Context) ([]EgsFreeGame, error) {
@@ -189,6 +387,137 @@ func (q *Queries) GetCurrentFreeGames(ctx context.Context) ([]EgsFreeGame, error
 			&i.ThumbnailUrl,
 			&i.StartDate,
 			&i.EndDate,
+			&i.Status,
+			&i.Reminded,
+			&i.Source,
+			&i.OpenEnded,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getExpiredFreeGameAnnouncements = `-- name: GetExpiredFreeGameAnnouncements :many
+SELECT a.id, a.channel_id, a.message_id, g.title
+FROM egs_free_game_announcements a
+JOIN egs_free_games g ON g.id = a.free_game_id
+WHERE g.end_date <= NOW() AND NOT a.expired
+ORDER BY a.id
+`
+
+type GetExpiredFreeGameAnnouncementsRow struct {
+	ID        int64
+	ChannelID string
+	MessageID string
+	Title     string
+}
+
+func (q *Queries) GetExpiredFreeGameAnnouncements(ctx context.Context) ([]GetExpiredFreeGameAnnouncementsRow, error) {
+	rows, err := q.db.Query(ctx, getExpiredFreeGameAnnouncements)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetExpiredFreeGameAnnouncementsRow
+	for rows.Next() {
+		var i GetExpiredFreeGameAnnouncementsRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.MessageID,
+			&i.Title,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getFreeGamesEndingBefore = `-- name: GetFreeGamesEndingBefore :many
+SELECT id, store_id, title, description, url, thumbnail_url, start_date, end_date, status, reminded, source, open_ended from egs_free_games
+WHERE status = 'live' AND NOT reminded AND NOT open_ended AND end_date > NOW() AND end_date <= $1
+ORDER BY end_date
+`
+
+func (q *Queries) GetFreeGamesEndingBefore(ctx context.Context, endDate pgtype.Timestamptz) ([]EgsFreeGame, error) {
+	rows, err := q.db.Query(ctx, getFreeGamesEndingBefore, endDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EgsFreeGame
+	for rows.Next() {
+		var i EgsFreeGame
+		if err := rows.Scan(
+			&i.ID,
+			&i.StoreID,
+			&i.Title,
+			&i.Description,
+			&i.Url,
+			&i.ThumbnailUrl,
+			&i.StartDate,
+			&i.EndDate,
+			&i.Status,
+			&i.Reminded,
+			&i.Source,
+			&i.OpenEnded,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getGuildSettings = `-- name: GetGuildSettings :one
+SELECT guild_id, deals_channel_id, alerts_channel_id, region, locale, ping_role_id, updated FROM guild_settings WHERE guild_id = $1
+`
+
+func (q *Queries) GetGuildSettings(ctx context.Context, guildID string) (GuildSetting, error) {
+	row := q.db.QueryRow(ctx, getGuildSettings, guildID)
+	var i GuildSetting
+	err := row.Scan(
+		&i.GuildID,
+		&i.DealsChannelID,
+		&i.AlertsChannelID,
+		&i.Region,
+		&i.Locale,
+		&i.PingRoleID,
+		&i.Updated,
+	)
+	return i, err
+}
+
+const getJobRuns = `-- name: GetJobRuns :many
+SELECT job, started, duration_ms, error FROM job_runs ORDER BY job
+`
+
+func (q *Queries) GetJobRuns(ctx context.Context) ([]JobRun, error) {
+	rows, err := q.db.Query(ctx, getJobRuns)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []JobRun
+	for rows.Next() {
+		var i JobRun
+		if err := rows.Scan(
+			&i.Job,
+			&i.Started,
+			&i.DurationMs,
+			&i.Error,
 		); err != nil {
 			return nil, err
 		}
@@ -201,12 +530,441 @@ func (q *Queries) GetCurrentFreeGames(ctx context.Context) ([]EgsFreeGame, error
 }
 
 const getLatestTokenPrice = `-- name: GetLatestTokenPrice :one
-SELECT id, updated, price FROM wow_token_prices ORDER BY id DESC LIMIT 1
+SELECT id, updated, price, region FROM wow_token_prices WHERE region = $1 ORDER BY id DESC LIMIT 1
 `
 
-func (q *Queries) GetLatestTokenPrice(ctx context.Context) (WowTokenPrice, error) {
-	row := q.db.QueryRow(ctx, getLatestTokenPrice)
+func (q *Queries) GetLatestTokenPrice(ctx context.Context, region string) (WowTokenPrice, error) {
+	row := q.db.QueryRow(ctx, getLatestTokenPrice, region)
 	var i WowTokenPrice
-	err := row.Scan(&i.ID, &i.Updated, &i.Price)
+	err := row.Scan(
+		&i.ID,
+		&i.Updated,
+		&i.Price,
+		&i.Region,
+	)
+	return i, err
+}
+
+const getOpenEndedFreeGame = `-- name: GetOpenEndedFreeGame :one
+SELECT id, store_id, title, description, url, thumbnail_url, start_date, end_date, status, reminded, source, open_ended FROM egs_free_games WHERE source = $1 AND store_id = $2 AND open_ended FOR UPDATE
+`
+
+type GetOpenEndedFreeGameParams struct {
+	Source  string
+	StoreID string
+}
+
+func (q *Queries) GetOpenEndedFreeGame(ctx context.Context, arg GetOpenEndedFreeGameParams) (EgsFreeGame, error) {
+	row := q.db.QueryRow(ctx, getOpenEndedFreeGame, arg.Source, arg.StoreID)
+	var i EgsFreeGame
+	err := row.Scan(
+		&i.ID,
+		&i.StoreID,
+		&i.Title,
+		&i.Description,
+		&i.Url,
+		&i.ThumbnailUrl,
+		&i.StartDate,
+		&i.EndDate,
+		&i.Status,
+		&i.Reminded,
+		&i.Source,
+		&i.OpenEnded,
+	)
+	return i, err
+}
+
+const getPendingFreeGameOutbox = `-- name: GetPendingFreeGameOutbox :many
+SELECT o.id, o.channel_id, o.kind, o.ping_role_id, o.attempts, g.id, g.store_id, g.title, g.description, g.url, g.thumbnail_url, g.start_date, g.end_date, g.status, g.reminded, g.source, g.open_ended
+FROM egs_free_game_outbox o
+JOIN egs_free_games g ON g.id = o.free_game_id
+WHERE NOT o.sent AND o.attempts < $1 AND o.next_attempt <= NOW() AND g.end_date > NOW()
+ORDER BY o.id
+`
+
+type GetPendingFreeGameOutboxRow struct {
+	ID          int64
+	ChannelID   string
+	Kind        string
+	PingRoleID  string
+	Attempts    int32
+	EgsFreeGame EgsFreeGame
+}
+
+func (q *Queries) GetPendingFreeGameOutbox(ctx context.Context, attempts int32) ([]GetPendingFreeGameOutboxRow, error) {
+	rows, err := q.db.Query(ctx, getPendingFreeGameOutbox, attempts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetPendingFreeGameOutboxRow
+	for rows.Next() {
+		var i GetPendingFreeGameOutboxRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.ChannelID,
+			&i.Kind,
+			&i.PingRoleID,
+			&i.Attempts,
+			&i.EgsFreeGame.ID,
+			&i.EgsFreeGame.StoreID,
+			&i.EgsFreeGame.Title,
+			&i.EgsFreeGame.Description,
+			&i.EgsFreeGame.Url,
+			&i.EgsFreeGame.ThumbnailUrl,
+			&i.EgsFreeGame.StartDate,
+			&i.EgsFreeGame.EndDate,
+			&i.EgsFreeGame.Status,
+			&i.EgsFreeGame.Reminded,
+			&i.EgsFreeGame.Source,
+			&i.EgsFreeGame.OpenEnded,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTokenAlertsForRegion = `-- name: GetTokenAlertsForRegion :many
+SELECT id, user_id, guild_id, region, direction, threshold, triggered, created FROM wow_token_alerts WHERE region = $1 ORDER BY id
+`
+
+func (q *Queries) GetTokenAlertsForRegion(ctx context.Context, region string) ([]WowTokenAlert, error) {
+	rows, err := q.db.Query(ctx, getTokenAlertsForRegion, region)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WowTokenAlert
+	for rows.Next() {
+		var i WowTokenAlert
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.GuildID,
+			&i.Region,
+			&i.Direction,
+			&i.Threshold,
+			&i.Triggered,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTokenAlertsForUser = `-- name: GetTokenAlertsForUser :many
+SELECT id, user_id, guild_id, region, direction, threshold, triggered, created FROM wow_token_alerts WHERE user_id = $1 ORDER BY id
+`
+
+func (q *Queries) GetTokenAlertsForUser(ctx context.Context, userID string) ([]WowTokenAlert, error) {
+	rows, err := q.db.Query(ctx, getTokenAlertsForUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WowTokenAlert
+	for rows.Next() {
+		var i WowTokenAlert
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.GuildID,
+			&i.Region,
+			&i.Direction,
+			&i.Threshold,
+			&i.Triggered,
+			&i.Created,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTokenPricesBetween = `-- name: GetTokenPricesBetween :many
+SELECT price, updated FROM wow_token_prices
+WHERE region = $1 AND updated >= $2 AND updated <= $3
+ORDER BY updated ASC
+`
+
+type GetTokenPricesBetweenParams struct {
+	Region string
+	Since  pgtype.Timestamptz
+	Until  pgtype.Timestamptz
+}
+
+type GetTokenPricesBetweenRow struct {
+	Price   int64
+	Updated pgtype.Timestamptz
+}
+
+func (q *Queries) GetTokenPricesBetween(ctx context.Context, arg GetTokenPricesBetweenParams) ([]GetTokenPricesBetweenRow, error) {
+	rows, err := q.db.Query(ctx, getTokenPricesBetween, arg.Region, arg.Since, arg.Until)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetTokenPricesBetweenRow
+	for rows.Next() {
+		var i GetTokenPricesBetweenRow
+		if err := rows.Scan(&i.Price, &i.Updated); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getTokenPricesPage = `-- name: GetTokenPricesPage :many
+SELECT id, updated, price, region FROM wow_token_prices WHERE id > $1 ORDER BY id ASC LIMIT $2
+`
+
+type GetTokenPricesPageParams struct {
+	ID    int64
+	Limit int32
+}
+
+func (q *Queries) GetTokenPricesPage(ctx context.Context, arg GetTokenPricesPageParams) ([]WowTokenPrice, error) {
+	rows, err := q.db.Query(ctx, getTokenPricesPage, arg.ID, arg.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []WowTokenPrice
+	for rows.Next() {
+		var i WowTokenPrice
+		if err := rows.Scan(
+			&i.ID,
+			&i.Updated,
+			&i.Price,
+			&i.Region,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getUnexpiredFreeGames = `-- name: GetUnexpiredFreeGames :many
+SELECT id, store_id, title, description, url, thumbnail_url, start_date, end_date, status, reminded, source, open_ended from egs_free_games WHERE end_date > NOW() ORDER BY id DESC
+`
+
+func (q *Queries) GetUnexpiredFreeGames(ctx context.Context) ([]EgsFreeGame, error) {
+	rows, err := q.db.Query(ctx, getUnexpiredFreeGames)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []EgsFreeGame
+	for rows.Next() {
+		var i EgsFreeGame
+		if err := rows.Scan(
+			&i.ID,
+			&i.StoreID,
+			&i.Title,
+			&i.Description,
+			&i.Url,
+			&i.ThumbnailUrl,
+			&i.StartDate,
+			&i.EndDate,
+			&i.Status,
+			&i.Reminded,
+			&i.Source,
+			&i.OpenEnded,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markFreeGameAnnouncementExpired = `-- name: MarkFreeGameAnnouncementExpired :exec
+UPDATE egs_free_game_announcements SET expired = TRUE WHERE id = $1
+`
+
+func (q *Queries) MarkFreeGameAnnouncementExpired(ctx context.Context, id int64) error {
+	_, err := q.db.Exec(ctx, markFreeGameAnnouncementExpired, id)
+	return err
+}
+
+const markFreeGameLive = `-- name: MarkFreeGameLive :execrows
+UPDATE egs_free_games SET status = 'live' WHERE id = $1 AND status = 'upcoming'
+`
+
+func (q *Queries) MarkFreeGameLive(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, markFreeGameLive, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const markFreeGameOutboxFailed = `-- name: MarkFreeGameOutboxFailed :exec
+UPDATE egs_free_game_outbox SET attempts = attempts + 1, next_attempt = $2 WHERE id = $1
+`
+
+type MarkFreeGameOutboxFailedParams struct {
+	ID          int64
+	NextAttempt pgtype.Timestamptz
+}
+
+func (q *Queries) MarkFreeGameOutboxFailed(ctx context.Context, arg MarkFreeGameOutboxFailedParams) error {
+	_, err := q.db.Exec(ctx, markFreeGameOutboxFailed, arg.ID, arg.NextAttempt)
+	return err
+}
+
+const markFreeGameOutboxSent = `-- name: MarkFreeGameOutboxSent :exec
+UPDATE egs_free_game_outbox SET sent = TRUE, message_id = $2 WHERE id = $1
+`
+
+type MarkFreeGameOutboxSentParams struct {
+	ID        int64
+	MessageID string
+}
+
+func (q *Queries) MarkFreeGameOutboxSent(ctx context.Context, arg MarkFreeGameOutboxSentParams) error {
+	_, err := q.db.Exec(ctx, markFreeGameOutboxSent, arg.ID, arg.MessageID)
+	return err
+}
+
+const markFreeGameReminded = `-- name: MarkFreeGameReminded :execrows
+UPDATE egs_free_games SET reminded = TRUE WHERE id = $1 AND NOT reminded
+`
+
+func (q *Queries) MarkFreeGameReminded(ctx context.Context, id int64) (int64, error) {
+	result, err := q.db.Exec(ctx, markFreeGameReminded, id)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordJobRun = `-- name: RecordJobRun :exec
+INSERT INTO job_runs (
+    job, started, duration_ms, error
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (job) DO UPDATE SET
+    started = excluded.started,
+    duration_ms = excluded.duration_ms,
+    error = excluded.error
+`
+
+type RecordJobRunParams struct {
+	Job        string
+	Started    pgtype.Timestamptz
+	DurationMs int64
+	Error      string
+}
+
+func (q *Queries) RecordJobRun(ctx context.Context, arg RecordJobRunParams) error {
+	_, err := q.db.Exec(ctx, recordJobRun,
+		arg.Job,
+		arg.Started,
+		arg.DurationMs,
+		arg.Error,
+	)
+	return err
+}
+
+const renewFreeGame = `-- name: RenewFreeGame :exec
+UPDATE egs_free_games SET start_date = $2, end_date = $3 WHERE id = $1 AND open_ended
+`
+
+type RenewFreeGameParams struct {
+	ID        int64
+	StartDate pgtype.Timestamptz
+	EndDate   pgtype.Timestamptz
+}
+
+func (q *Queries) RenewFreeGame(ctx context.Context, arg RenewFreeGameParams) error {
+	_, err := q.db.Exec(ctx, renewFreeGame, arg.ID, arg.StartDate, arg.EndDate)
+	return err
+}
+
+const setTokenAlertTriggered = `-- name: SetTokenAlertTriggered :exec
+UPDATE wow_token_alerts SET triggered = $2 WHERE id = $1
+`
+
+type SetTokenAlertTriggeredParams struct {
+	ID        int64
+	Triggered bool
+}
+
+func (q *Queries) SetTokenAlertTriggered(ctx context.Context, arg SetTokenAlertTriggeredParams) error {
+	_, err := q.db.Exec(ctx, setTokenAlertTriggered, arg.ID, arg.Triggered)
+	return err
+}
+
+const upsertGuildSettings = `-- name: UpsertGuildSettings :one
+INSERT INTO guild_settings (
+    guild_id, deals_channel_id, alerts_channel_id, region, locale, ping_role_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (guild_id) DO UPDATE SET
+    deals_channel_id = excluded.deals_channel_id,
+    alerts_channel_id = excluded.alerts_channel_id,
+    region = excluded.region,
+    locale = excluded.locale,
+    ping_role_id = excluded.ping_role_id,
+    updated = NOW()
+RETURNING guild_id, deals_channel_id, alerts_channel_id, region, locale, ping_role_id, updated
+`
+
+type UpsertGuildSettingsParams struct {
+	GuildID         string
+	DealsChannelID  string
+	AlertsChannelID string
+	Region          string
+	Locale          string
+	PingRoleID      string
+}
+
+func (q *Queries) UpsertGuildSettings(ctx context.Context, arg UpsertGuildSettingsParams) (GuildSetting, error) {
+	row := q.db.QueryRow(ctx, upsertGuildSettings,
+		arg.GuildID,
+		arg.DealsChannelID,
+		arg.AlertsChannelID,
+		arg.Region,
+		arg.Locale,
+		arg.PingRoleID,
+	)
+	var i GuildSetting
+	err := row.Scan(
+		&i.GuildID,
+		&i.DealsChannelID,
+		&i.AlertsChannelID,
+		&i.Region,
+		&i.Locale,
+		&i.PingRoleID,
+		&i.Updated,
+	)
 	return i, err
 }
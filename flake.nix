@@ -27,7 +27,7 @@
             inherit version;
 
             src = ./.;
-            vendorHash = "sha256-8w/c6d/AZUfi20SLbYA0N6bohA8R8EGqbjhcUS2h+q8=";
+            vendorHash = "sha256-MH3P/Q6s8OwxShhl5rej8eRmryajC9TzdHhof8wOsoo=";
 
             env.CGO_ENABLED = 0;
 
@@ -61,6 +61,7 @@
             mkIf
             mkEnableOption
             mkOption
+            mkRenamedOptionModule
             ;
 
           cfg = config.services.aml-discord-bot;
@@ -69,6 +70,13 @@
           configFile = format.generate "config.json" cfg.settings;
         in
         {
+          imports = [
+            (mkRenamedOptionModule
+              [ "services" "aml-discord-bot" "settings" "epicGamesStore" "reminderHours" ]
+              [ "services" "aml-discord-bot" "settings" "freeGames" "reminderHours" ]
+            )
+          ];
+
           options.services.aml-discord-bot = {
             enable = mkEnableOption "Enable aml-discord-bot";
 
@@ -85,6 +93,17 @@
             };
 
             settings = {
+              discord = {
+                interactions = mkOption {
+                  type = types.enum [
+                    "gateway"
+                    "http"
+                  ];
+                  description = "Receive interactions over the gateway, or over HTTP at /interactions";
+                  default = "gateway";
+                };
+              };
+
               http = {
                 listenPort = mkOption {
                   type = types.int;
@@ -107,6 +126,16 @@
                   description = "The external host URL to present";
                   default = "http://localhost:5000";
                 };
+                chartCacheSize = mkOption {
+                  type = types.int;
+                  description = "Number of rendered WoW token charts kept in memory";
+                  default = 128;
+                };
+                chartCacheDir = mkOption {
+                  type = types.str;
+                  description = "Directory to additionally keep rendered charts in, such as /var/cache/discord-bot. Disabled when empty";
+                  default = "";
+                };
               };
 
               blizzard = {
@@ -115,10 +144,46 @@
                   description = "The URL used to fetch an auth token from Blizzard";
                   default = "https://us.battle.net/oauth/token?grant_type=client_credentials";
                 };
-                tokenPriceUrl = mkOption {
-                  type = types.str;
-                  description = "The URL used to fetch the current WoW token price";
-                  default = "https://us.api.blizzard.com/data/wow/token/index?namespace=dynamic-us";
+                authTokenUrls = mkOption {
+                  type = with types; attrsOf str;
+                  description = "Per-region overrides for the auth token URL";
+                  default = {
+                    cn = "https://oauth.battlenet.com.cn/token?grant_type=client_credentials";
+                  };
+                };
+                tokenPriceUrls = mkOption {
+                  type = with types; attrsOf str;
+                  description = "The URLs used to fetch the current WoW token price, keyed by region";
+                  default = {
+                    us = "https://us.api.blizzard.com/data/wow/token/index?namespace=dynamic-us";
+                    eu = "https://eu.api.blizzard.com/data/wow/token/index?namespace=dynamic-eu";
+                    kr = "https://kr.api.blizzard.com/data/wow/token/index?namespace=dynamic-kr";
+                    tw = "https://tw.api.blizzard.com/data/wow/token/index?namespace=dynamic-tw";
+                    cn = "https://gateway.battlenet.com.cn/data/wow/token/index?namespace=dynamic-cn";
+                  };
+                };
+                regions = mkOption {
+                  type = with types; listOf str;
+                  description = "Regions to track WoW token prices for, the first one is used as the default";
+                  default = [ "us" ];
+                };
+                alertRearmPercent = mkOption {
+                  type = types.int;
+                  description = "How far, in percent of the threshold, the price has to move back before a price alert re-arms";
+                  default = 2;
+                };
+                maxAlertsPerUser = mkOption {
+                  type = types.int;
+                  description = "Maximum number of WoW token price alerts a single user may have";
+                  default = 10;
+                };
+              };
+
+              freeGames = {
+                reminderHours = mkOption {
+                  type = types.int;
+                  description = "Hours before a free game's promotion ends to post a reminder, disabled when 0";
+                  default = 24;
                 };
               };
 
@@ -134,6 +199,59 @@
                   default = "https://store-site-backend-static.ak.epicgames.com/freeGamesPromotions?locale=en-US&country=US&allowCountries=US";
                 };
               };
+
+              gog = {
+                enabled = mkEnableOption "announcing games given away on GOG";
+                productBaseUrl = mkOption {
+                  type = types.str;
+                  description = "The URL used as a base for building a url to a product on GOG";
+                  default = "https://www.gog.com/en/game/";
+                };
+                catalogApiUrl = mkOption {
+                  type = types.str;
+                  description = "The URL of the GOG catalog search listing the games discounted to a price of 0";
+                  default = "https://catalog.gog.com/v1/catalog?limit=48&price=between:0,0&discounted=eq:true&productType=in:game,pack&countryCode=US&locale=en-US&currencyCode=USD";
+                };
+              };
+
+              steam = {
+                enabled = mkEnableOption "announcing games discounted by 100% on Steam";
+                appBaseUrl = mkOption {
+                  type = types.str;
+                  description = "The URL used as a base for building a url to an app on Steam";
+                  default = "https://store.steampowered.com/app/";
+                };
+                featuredApiUrl = mkOption {
+                  type = types.str;
+                  description = "The URL of the Steam store API's featured categories, whose specials are searched for free games";
+                  default = "https://store.steampowered.com/api/featuredcategories?cc=US&l=english";
+                };
+              };
+
+              jobs = mkOption {
+                type = with types; attrsOf (submodule {
+                  options = {
+                    schedule = mkOption {
+                      type = types.nullOr types.str;
+                      description = "A cron expression such as \"0 * * * *\", or an interval such as \"@every 5m\", or null to keep the default of the job";
+                      default = null;
+                    };
+                    runOnStart = mkOption {
+                      type = types.nullOr types.bool;
+                      description = "Whether the job also runs as soon as the bot starts running jobs, or null to keep the default of the job";
+                      default = null;
+                    };
+                  };
+                });
+                description = "Schedules of the background jobs, keyed by job name";
+                default = {
+                  wowTokenPrices = { schedule = "@every 5m"; runOnStart = true; };
+                  freeGames = { schedule = "@every 1h"; runOnStart = true; };
+                  freeGameReminders.schedule = "@every 10m";
+                  freeGameExpiry.schedule = "@every 10m";
+                  freeGameOutbox.schedule = "@every 1m";
+                };
+              };
             };
           };
 
@@ -220,6 +338,9 @@
                   StateDirectory = "discord-bot";
                   StateDirectoryMode = "0750";
 
+                  CacheDirectory = "discord-bot";
+                  CacheDirectoryMode = "0750";
+
                   RuntimeDirectory = "discord-bot";
                   RuntimeDirectoryMode = "0755";
                 };
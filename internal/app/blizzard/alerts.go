@@ -0,0 +1,107 @@
+package blizzard
+
+import (
+	"log"
+	"time"
+
+	"github.com/aloop/discord-bot/database"
+)
+
+const (
+	AlertBelow string = "below"
+	AlertAbove string = "above"
+
+	// Period covered by the price statistics passed to the AlertNotifier
+	AlertStatsPeriod = 24 * time.Hour
+)
+
+// AlertNotifier is called for every alert whose threshold was crossed by a new price, along with
+// statistics for the last AlertStatsPeriod, which include the new price as the current price
+type AlertNotifier func(alert database.WowTokenAlert, stats TokenPriceStats)
+
+// OnAlert registers the function used to notify users about triggered alerts
+func (b *BlizzardClient) OnAlert(notifier AlertNotifier) {
+	b.alertNotifier = notifier
+}
+
+// checkAlerts compares a newly stored token price against the alerts for its region.
+//
+// An alert fires once when its threshold is crossed, and is only re-armed after the price
+// moves back past the threshold by the configured margin, so a price hovering around the
+// threshold does not notify repeatedly.
+func (b *BlizzardClient) checkAlerts(region string, price WowTokenPrice) {
+	alerts, err := b.db.GetTokenAlertsForRegion(b.ctx, region)
+	if err != nil {
+		log.Printf("WoW Token Alerts: Failed to get alerts from the database\n%v", err)
+		return
+	}
+
+	// Only calculated once an alert actually needs to be sent
+	var stats *TokenPriceStats
+
+	for _, alert := range alerts {
+		margin := alert.Threshold * b.config.Blizzard.AlertRearmPercent / 100
+
+		var crossed, rearm bool
+		switch alert.Direction {
+		case AlertBelow:
+			crossed = price.Price <= alert.Threshold
+			rearm = price.Price > alert.Threshold+margin
+		case AlertAbove:
+			crossed = price.Price >= alert.Threshold
+			rearm = price.Price < alert.Threshold-margin
+		default:
+			log.Printf(
+				`WoW Token Alerts: Alert %d has invalid direction "%s"`,
+				alert.ID,
+				alert.Direction,
+			)
+			continue
+		}
+
+		if !alert.Triggered && crossed {
+			// Stored first, as an alert that cannot be marked as triggered would notify on every price
+			if !b.setAlertTriggered(alert.ID, true) {
+				continue
+			}
+
+			if b.alertNotifier != nil {
+				if stats == nil {
+					stats = b.alertStats(region, price)
+				}
+				b.alertNotifier(alert, *stats)
+			}
+		} else if alert.Triggered && rearm {
+			b.setAlertTriggered(alert.ID, false)
+		}
+	}
+}
+
+func (b *BlizzardClient) alertStats(region string, price WowTokenPrice) *TokenPriceStats {
+	history, err := b.GetTokenPriceHistory(region, price.Updated.Add(-AlertStatsPeriod), price.Updated)
+	if err != nil {
+		log.Printf("WoW Token Alerts: Failed to get price history for alert statistics\n%v", err)
+	}
+
+	stats, ok := ComputeTokenPriceStats(history)
+	if !ok || !stats.Current.Updated.Equal(price.Updated) {
+		// Without any history, the new price is all there is to go on
+		stats, _ = ComputeTokenPriceStats([]WowTokenPrice{price})
+	}
+
+	return &stats
+}
+
+// setAlertTriggered reports whether the alert was updated
+func (b *BlizzardClient) setAlertTriggered(id int64, triggered bool) bool {
+	err := b.db.SetTokenAlertTriggered(b.ctx, database.SetTokenAlertTriggeredParams{
+		ID:        id,
+		Triggered: triggered,
+	})
+	if err != nil {
+		log.Printf("WoW Token Alerts: Failed to update alert %d\n%v", id, err)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,100 @@
+package blizzard
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+func TestCheckAlerts(t *testing.T) {
+	tests := []struct {
+		name         string
+		alert        database.WowTokenAlert
+		price        int64
+		expectNotify bool
+		// Triggered state stored for the alert, if it is updated
+		expectStored []bool
+	}{
+		{
+			name:         "crossed below",
+			alert:        database.WowTokenAlert{ID: 1, Direction: AlertBelow, Threshold: 250_000},
+			price:        240_000,
+			expectNotify: true,
+			expectStored: []bool{true},
+		},
+		{
+			name:  "not crossed",
+			alert: database.WowTokenAlert{ID: 1, Direction: AlertAbove, Threshold: 250_000},
+			price: 240_000,
+		},
+		{
+			name: "already triggered",
+			alert: database.WowTokenAlert{
+				ID: 1, Direction: AlertAbove, Threshold: 250_000, Triggered: true,
+			},
+			price: 260_000,
+		},
+		{
+			name: "rearmed",
+			alert: database.WowTokenAlert{
+				ID: 1, Direction: AlertBelow, Threshold: 250_000, Triggered: true,
+			},
+			price:        260_000,
+			expectStored: []bool{false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := fakes.NewDB()
+			db.SetRows("GetTokenAlertsForRegion", tt.alert)
+
+			b := newTestClient(db, fakes.NewBlizzardAPI(t))
+
+			var notified bool
+			b.OnAlert(func(alert database.WowTokenAlert, stats TokenPriceStats) {
+				notified = true
+
+				if stats.Current.Price != tt.price {
+					t.Errorf("expected the new price in the statistics, got %d", stats.Current.Price)
+				}
+			})
+
+			b.checkAlerts("us", WowTokenPrice{Price: tt.price, Updated: time.Now()})
+
+			if notified != tt.expectNotify {
+				t.Errorf("expected notified to be %t", tt.expectNotify)
+			}
+
+			calls := db.Calls("SetTokenAlertTriggered")
+			if len(calls) != len(tt.expectStored) {
+				t.Fatalf("expected %d updates, got %d", len(tt.expectStored), len(calls))
+			}
+
+			for i, call := range calls {
+				if call[0] != tt.alert.ID || call[1] != tt.expectStored[i] {
+					t.Errorf("expected alert %d to be stored as %t, got %v",
+						tt.alert.ID, tt.expectStored[i], call)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckAlertsSkipsNotifyingWhenNotStored(t *testing.T) {
+	db := fakes.NewDB()
+	db.SetRows("GetTokenAlertsForRegion", database.WowTokenAlert{
+		ID: 1, Direction: AlertBelow, Threshold: 250_000,
+	})
+	db.SetError("SetTokenAlertTriggered", errors.New("connection refused"))
+
+	b := newTestClient(db, fakes.NewBlizzardAPI(t))
+	b.OnAlert(func(database.WowTokenAlert, TokenPriceStats) {
+		t.Error("expected an alert that could not be marked as triggered not to notify")
+	})
+
+	b.checkAlerts("us", WowTokenPrice{Price: 240_000, Updated: time.Now()})
+}
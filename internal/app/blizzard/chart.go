@@ -0,0 +1,418 @@
+package blizzard
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/wcharczuk/go-chart/v2"
+	"github.com/wcharczuk/go-chart/v2/drawing"
+)
+
+const (
+	chartSMAColor     string = "f0b232"
+	chartEMAColor     string = "5865f2"
+	chartBandColor    string = "b5bac1"
+	chartHighColor    string = "ed4245"
+	chartLowColor     string = "57f287"
+	chartLegendHeight int    = 15 * multiplier
+	maxOverlayWindow  int    = 2000
+	overlayWindowHelp string = "must be a number of samples between 2 and 2000"
+)
+
+// ChartFormat is the image format a chart is rendered in
+type ChartFormat string
+
+const (
+	ChartFormatPNG ChartFormat = "png"
+	ChartFormatSVG ChartFormat = "svg"
+)
+
+// ContentType returns the media type of charts rendered in the format
+func (f ChartFormat) ContentType() string {
+	if f == ChartFormatSVG {
+		return "image/svg+xml"
+	}
+
+	return "image/png"
+}
+
+func (f ChartFormat) renderer() chart.RendererProvider {
+	if f == ChartFormatSVG {
+		return chart.SVG
+	}
+
+	return chart.PNG
+}
+
+// renderChart renders the chart, turning panics raised by go-chart on unexpected data into errors
+func renderChart(graph chart.Chart, format ChartFormat) (buffer *bytes.Buffer, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic while rendering chart: %v", r)
+		}
+	}()
+
+	buffer = bytes.NewBuffer([]byte{})
+	if err := graph.Render(format.renderer(), buffer); err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+
+	return buffer, nil
+}
+
+// RenderPlaceholderChart renders an image the size of a price chart containing only a message,
+// to be served in place of a chart that could not be generated
+func RenderPlaceholderChart(message string, format ChartFormat) (*bytes.Buffer, error) {
+	r, err := format.renderer()(chartWidth, chartHeight)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create renderer for placeholder chart: %w", err)
+	}
+
+	font, err := chart.GetDefaultFont()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load font for placeholder chart: %w", err)
+	}
+
+	r.SetDPI(chartDPI)
+
+	chart.Draw.Box(r, chart.NewBox(0, 0, chartWidth, chartHeight), chart.Style{
+		FillColor:   drawing.ColorFromHex(chartBg),
+		StrokeColor: drawing.ColorFromHex(chartBg),
+	})
+
+	chart.Draw.TextWithin(
+		r,
+		message,
+		chart.NewBox(0, 0, chartWidth, chartHeight),
+		chart.Style{
+			Font:                font,
+			FontColor:           drawing.ColorWhite.WithAlpha(160),
+			FontSize:            9,
+			TextWrap:            chart.TextWrapWord,
+			TextHorizontalAlign: chart.TextHorizontalAlignCenter,
+			TextVerticalAlign:   chart.TextVerticalAlignMiddle,
+		},
+	)
+
+	buffer := bytes.NewBuffer([]byte{})
+	if err := r.Save(buffer); err != nil {
+		return nil, fmt.Errorf("failed to render placeholder chart: %w", err)
+	}
+
+	return buffer, nil
+}
+
+// ChartOverlays are optional series drawn on top of the token price chart. Windows are given
+// in number of samples, a window of 0 disables the overlay.
+type ChartOverlays struct {
+	// Simple moving average
+	SMA int
+	// Exponential moving average
+	EMA int
+	// Band between the rolling minimum and maximum price
+	Band int
+	// Horizontal lines marking the period high and low
+	HighLow bool
+}
+
+// Enabled reports whether any overlay is enabled
+func (o ChartOverlays) Enabled() bool {
+	return o.SMA > 0 || o.EMA > 0 || o.Band > 0 || o.HighLow
+}
+
+// Query encodes the overlays as query parameters for the chart route
+func (o ChartOverlays) Query() url.Values {
+	query := url.Values{}
+
+	if o.SMA > 0 {
+		query.Set("sma", strconv.Itoa(o.SMA))
+	}
+
+	if o.EMA > 0 {
+		query.Set("ema", strconv.Itoa(o.EMA))
+	}
+
+	if o.Band > 0 {
+		query.Set("band", strconv.Itoa(o.Band))
+	}
+
+	if o.HighLow {
+		query.Set("highlow", "1")
+	}
+
+	return query
+}
+
+// ParseChartOverlays reads the overlays from the query parameters of a chart request
+func ParseChartOverlays(query url.Values) (ChartOverlays, error) {
+	var (
+		overlays ChartOverlays
+		err      error
+	)
+
+	windows := map[string]*int{
+		"sma":  &overlays.SMA,
+		"ema":  &overlays.EMA,
+		"band": &overlays.Band,
+	}
+
+	for name, window := range windows {
+		v := query.Get(name)
+		if v == "" {
+			continue
+		}
+
+		*window, err = strconv.Atoi(v)
+		if err != nil || *window < 2 || *window > maxOverlayWindow {
+			return ChartOverlays{}, fmt.Errorf("%s %s", name, overlayWindowHelp)
+		}
+	}
+
+	if v := query.Get("highlow"); v != "" {
+		overlays.HighLow, err = strconv.ParseBool(v)
+		if err != nil {
+			return ChartOverlays{}, fmt.Errorf("highlow must be a boolean")
+		}
+	}
+
+	return overlays, nil
+}
+
+// DefaultOverlayWindow returns a window, in samples, suited to charts of the given unit.
+// Prices are published roughly every 20 minutes.
+func DefaultOverlayWindow(unit string) int {
+	switch unit {
+	case "days":
+		// 1 day
+		return 72
+	case "months":
+		// 1 week
+		return 504
+	default:
+		// 3 hours
+		return 9
+	}
+}
+
+// rollingBandSeries draws the band between the rolling minimum and maximum of a series
+type rollingBandSeries struct {
+	Name  string
+	Style chart.Style
+
+	xValues []float64
+	lower   []float64
+	upper   []float64
+}
+
+func newRollingBandSeries(
+	name string,
+	style chart.Style,
+	dates []time.Time,
+	prices []float64,
+	window int,
+) *rollingBandSeries {
+	band := &rollingBandSeries{
+		Name:    name,
+		Style:   style,
+		xValues: make([]float64, len(dates)),
+		lower:   make([]float64, len(prices)),
+		upper:   make([]float64, len(prices)),
+	}
+
+	for i := range prices {
+		start := max(0, i-window+1)
+		band.xValues[i] = chart.TimeToFloat64(dates[i])
+		band.lower[i] = prices[start]
+		band.upper[i] = prices[start]
+
+		for _, price := range prices[start : i+1] {
+			band.lower[i] = min(band.lower[i], price)
+			band.upper[i] = max(band.upper[i], price)
+		}
+	}
+
+	return band
+}
+
+func (s *rollingBandSeries) GetName() string {
+	return s.Name
+}
+
+func (s *rollingBandSeries) GetStyle() chart.Style {
+	return s.Style
+}
+
+func (s *rollingBandSeries) GetYAxis() chart.YAxisType {
+	return chart.YAxisPrimary
+}
+
+func (s *rollingBandSeries) Len() int {
+	return len(s.xValues)
+}
+
+func (s *rollingBandSeries) GetBoundedValues(index int) (x, y1, y2 float64) {
+	return s.xValues[index], s.upper[index], s.lower[index]
+}
+
+func (s *rollingBandSeries) Validate() error {
+	if len(s.xValues) == 0 {
+		return fmt.Errorf("rolling band series has no values")
+	}
+
+	return nil
+}
+
+func (s *rollingBandSeries) Render(
+	r chart.Renderer,
+	canvasBox chart.Box,
+	xrange, yrange chart.Range,
+	defaults chart.Style,
+) {
+	chart.Draw.BoundedSeries(r, canvasBox, xrange, yrange, s.Style.InheritFrom(defaults), s)
+}
+
+// overlaySeries builds the series for the enabled overlays. Dates and prices must be ordered from
+// oldest to newest. The band is returned separately, as it has to be drawn below the price.
+func overlaySeries(
+	overlays ChartOverlays,
+	priceSeries *chart.TimeSeries,
+	dates []time.Time,
+	prices []float64,
+) (below []chart.Series, above []chart.Series) {
+	if overlays.Band > 0 {
+		below = append(below, newRollingBandSeries(
+			fmt.Sprintf("Range (%d)", overlays.Band),
+			chart.Style{
+				StrokeColor: drawing.ColorFromHex(chartBandColor).WithAlpha(96),
+				FillColor:   drawing.ColorFromHex(chartBandColor).WithAlpha(32),
+				StrokeWidth: chartLineThickness / 2,
+			},
+			dates,
+			prices,
+			overlays.Band,
+		))
+	}
+
+	if overlays.SMA > 0 {
+		above = append(above, &chart.SMASeries{
+			Name: fmt.Sprintf("SMA (%d)", overlays.SMA),
+			Style: chart.Style{
+				StrokeColor: drawing.ColorFromHex(chartSMAColor),
+				StrokeWidth: chartLineThickness,
+			},
+			Period:      overlays.SMA,
+			InnerSeries: priceSeries,
+		})
+	}
+
+	if overlays.EMA > 0 {
+		above = append(above, &chart.EMASeries{
+			Name: fmt.Sprintf("EMA (%d)", overlays.EMA),
+			Style: chart.Style{
+				StrokeColor: drawing.ColorFromHex(chartEMAColor),
+				StrokeWidth: chartLineThickness,
+			},
+			Period:      overlays.EMA,
+			InnerSeries: priceSeries,
+		})
+	}
+
+	if overlays.HighLow {
+		stats, ok := ComputeTokenPriceStats(toTokenPrices(dates, prices))
+		if ok {
+			above = append(
+				above,
+				horizontalLine(dates, stats.High, chartHighColor),
+				horizontalLine(dates, stats.Low, chartLowColor),
+				chart.AnnotationSeries{
+					Annotations: []chart.Value2{
+						priceAnnotation("High", stats.High, chartHighColor),
+						priceAnnotation("Low", stats.Low, chartLowColor),
+					},
+				},
+			)
+		}
+	}
+
+	return below, above
+}
+
+func toTokenPrices(dates []time.Time, prices []float64) []WowTokenPrice {
+	tokenPrices := make([]WowTokenPrice, 0, len(dates))
+	for i := range dates {
+		tokenPrices = append(tokenPrices, WowTokenPrice{
+			Updated: dates[i],
+			Price:   int64(prices[i]),
+		})
+	}
+
+	return tokenPrices
+}
+
+func horizontalLine(dates []time.Time, price WowTokenPrice, color string) *chart.TimeSeries {
+	return &chart.TimeSeries{
+		XValues: []time.Time{dates[0], dates[len(dates)-1]},
+		YValues: []float64{float64(price.Price), float64(price.Price)},
+		Style: chart.Style{
+			StrokeColor:     drawing.ColorFromHex(color).WithAlpha(160),
+			StrokeWidth:     chartLineThickness / 2,
+			StrokeDashArray: []float64{5 * float64(multiplier), 5 * float64(multiplier)},
+		},
+	}
+}
+
+func priceAnnotation(label string, price WowTokenPrice, color string) chart.Value2 {
+	return chart.Value2{
+		Label:  p.Sprintf("%s: %d", label, price.Price),
+		XValue: chart.TimeToFloat64(price.Updated),
+		YValue: float64(price.Price),
+		Style: chart.Style{
+			FontColor:   drawing.ColorWhite,
+			FillColor:   drawing.ColorFromHex(chartBg),
+			StrokeColor: drawing.ColorFromHex(color),
+		},
+	}
+}
+
+// chartLegend draws a single row legend in the space reserved below the X axis, as the legends
+// provided by go-chart are drawn over the title or the chart itself
+func chartLegend(graph *chart.Chart) chart.Renderable {
+	return func(r chart.Renderer, canvasBox chart.Box, defaults chart.Style) {
+		const (
+			lineLength = 15 * multiplier
+			gap        = 4 * multiplier
+		)
+
+		r.SetFont(defaults.GetFont())
+		r.SetFontColor(drawing.ColorWhite)
+		r.SetFontSize(8)
+
+		x := canvasBox.Left
+		y := graph.GetHeight() - 4*multiplier
+
+		for _, s := range graph.Series {
+			name := s.GetName()
+			if name == "" {
+				continue
+			}
+
+			style := s.GetStyle()
+			textBox := r.MeasureText(name)
+			lineY := y - textBox.Height()/2
+
+			r.SetStrokeColor(style.StrokeColor)
+			r.SetStrokeWidth(style.StrokeWidth)
+			r.SetStrokeDashArray(nil)
+			r.MoveTo(x, lineY)
+			r.LineTo(x+lineLength, lineY)
+			r.Stroke()
+
+			x += lineLength + gap
+			r.Text(name, x, y)
+			x += textBox.Width() + gap*3
+		}
+	}
+}
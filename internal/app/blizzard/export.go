@@ -0,0 +1,70 @@
+package blizzard
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+// Number of rows read from the database at a time while exporting
+const exportPageSize int32 = 1000
+
+// ExportTokenPricesCSV writes the token price history of every region to w as CSV. Rows are
+// read and written a page at a time, so the full history is never held in memory.
+func ExportTokenPricesCSV(ctx context.Context, db storage.TokenPrices, w io.Writer) error {
+	out := csv.NewWriter(w)
+
+	if err := out.Write([]string{"id", "region", "updated", "price"}); err != nil {
+		return err
+	}
+
+	var lastID int64
+	for {
+		rows, err := db.GetTokenPricesPage(ctx, database.GetTokenPricesPageParams{
+			ID:    lastID,
+			Limit: exportPageSize,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to get token prices from database: %w", err)
+		}
+
+		for _, row := range rows {
+			err := out.Write([]string{
+				strconv.FormatInt(row.ID, 10),
+				row.Region,
+				row.Updated.Time.UTC().Format(time.RFC3339),
+				strconv.FormatInt(row.Price, 10),
+			})
+			if err != nil {
+				return err
+			}
+
+			lastID = row.ID
+		}
+
+		out.Flush()
+		if err := out.Error(); err != nil {
+			return err
+		}
+
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+
+		if len(rows) < int(exportPageSize) {
+			return nil
+		}
+	}
+}
+
+// ExportTokenPricesCSV writes the token price history of every region to w as CSV
+func (b *BlizzardClient) ExportTokenPricesCSV(ctx context.Context, w io.Writer) error {
+	return ExportTokenPricesCSV(ctx, b.db, w)
+}
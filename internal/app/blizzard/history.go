@@ -0,0 +1,106 @@
+package blizzard
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+)
+
+// TokenPriceBucket summarizes the token prices within a window of time
+type TokenPriceBucket struct {
+	// Start of the bucket
+	Updated time.Time `json:"updated"`
+	// Last price within the bucket
+	Price   int64   `json:"price"`
+	Min     int64   `json:"min"`
+	Max     int64   `json:"max"`
+	Avg     float64 `json:"avg"`
+	Samples int     `json:"samples"`
+}
+
+// GetLatestTokenPrice returns the most recently stored token price for a region, without
+// fetching a new one from the API
+func (b *BlizzardClient) GetLatestTokenPrice(region string) (WowTokenPrice, error) {
+	data, err := b.db.GetLatestTokenPrice(b.ctx, region)
+	if err != nil {
+		return WowTokenPrice{}, err
+	}
+
+	return WowTokenPrice{
+		Updated: data.Updated.Time,
+		Price:   data.Price,
+	}, nil
+}
+
+// GetTokenPriceHistory returns the stored token prices for a region between since and until,
+// ordered from oldest to newest
+func (b *BlizzardClient) GetTokenPriceHistory(
+	region string,
+	since time.Time,
+	until time.Time,
+) ([]WowTokenPrice, error) {
+	rows, err := b.db.GetTokenPricesBetween(b.ctx, database.GetTokenPricesBetweenParams{
+		Region: region,
+		Since:  pgtype.Timestamptz{Time: since, Valid: true},
+		Until:  pgtype.Timestamptz{Time: until, Valid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token price history from database: %w", err)
+	}
+
+	prices := make([]WowTokenPrice, 0, len(rows))
+	for _, row := range rows {
+		prices = append(prices, WowTokenPrice{
+			Updated: row.Updated.Time,
+			Price:   row.Price,
+		})
+	}
+
+	return prices, nil
+}
+
+// BucketTokenPrices groups prices, ordered from oldest to newest, into buckets of the given
+// resolution aligned to origin. Buckets without any prices are omitted. A resolution of 0
+// places every price in its own bucket.
+func BucketTokenPrices(
+	prices []WowTokenPrice,
+	origin time.Time,
+	resolution time.Duration,
+) []TokenPriceBucket {
+	buckets := make([]TokenPriceBucket, 0)
+
+	var (
+		current *TokenPriceBucket
+		sum     int64
+	)
+
+	for _, price := range prices {
+		start := price.Updated
+		if resolution > 0 {
+			start = origin.Add(price.Updated.Sub(origin).Truncate(resolution))
+		}
+
+		if current == nil || !current.Updated.Equal(start) {
+			buckets = append(buckets, TokenPriceBucket{
+				Updated: start,
+				Min:     price.Price,
+				Max:     price.Price,
+			})
+			current = &buckets[len(buckets)-1]
+			sum = 0
+		}
+
+		current.Price = price.Price
+		current.Min = min(current.Min, price.Price)
+		current.Max = max(current.Max, price.Price)
+		current.Samples++
+
+		sum += price.Price
+		current.Avg = float64(sum) / float64(current.Samples)
+	}
+
+	return buckets
+}
@@ -4,10 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
@@ -18,6 +22,7 @@ import (
 	"github.com/aloop/discord-bot/database"
 	"github.com/aloop/discord-bot/internal/pkg/config"
 	"github.com/aloop/discord-bot/internal/pkg/secrets"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
 	"github.com/aloop/discord-bot/internal/pkg/utils"
 )
 
@@ -35,14 +40,27 @@ const (
 var (
 	httpClient = &http.Client{Timeout: 30 * time.Second}
 	p          = message.NewPrinter(message.MatchLanguage("en"))
+
+	// Regions supported by the Blizzard game data APIs
+	Regions = []string{"us", "eu", "kr", "tw", "cn"}
+
+	ErrNotEnoughPriceHistory = errors.New("not enough price history to generate chart")
+
+	// Returned along with a price that was fetched, but could not be stored in the database
+	ErrTokenPriceNotStored = errors.New("WoW Token: Failed to add new price to database")
 )
 
 type BlizzardClient struct {
 	config  *config.Config
 	secrets *secrets.Secrets
-	db      *database.Queries
-	token   *BlizzardClientToken
+	db      storage.Store
 	ctx     context.Context
+
+	// Auth tokens keyed by the url they were obtained from
+	tokens   map[string]*BlizzardClientToken
+	tokensMu sync.Mutex
+
+	alertNotifier AlertNotifier
 }
 
 type BlizzardClientToken struct {
@@ -61,34 +79,64 @@ type WowTokenPriceAPIResponse struct {
 }
 
 type WowTokenPrice struct {
-	Updated time.Time
-	Price   int64
+	Updated time.Time `json:"updated"`
+	Price   int64     `json:"price"`
 }
 
 func New(
 	ctx context.Context,
 	config *config.Config,
 	secrets *secrets.Secrets,
-	db *database.Queries,
+	db storage.Store,
 ) *BlizzardClient {
 	return &BlizzardClient{
 		config:  config,
 		secrets: secrets,
 		db:      db,
 		ctx:     ctx,
-		token: &BlizzardClientToken{
-			token:     "",
-			expiresAt: 0,
-		},
+		tokens:  make(map[string]*BlizzardClientToken),
+	}
+}
+
+// DefaultRegion returns the region used when none is specified
+func (b *BlizzardClient) DefaultRegion() string {
+	return b.config.Blizzard.Regions[0]
+}
+
+// HasRegion reports whether token prices are being tracked for the given region
+func (b *BlizzardClient) HasRegion(region string) bool {
+	return slices.Contains(b.config.Blizzard.Regions, region)
+}
+
+// RegionName returns the display name for a region
+func RegionName(region string) string {
+	return strings.ToUpper(region)
+}
+
+func (b *BlizzardClient) authTokenUrl(region string) string {
+	if tokenUrl, ok := b.config.Blizzard.AuthTokenUrls[region]; ok && tokenUrl != "" {
+		return tokenUrl
 	}
+
+	return b.config.Blizzard.AuthTokenUrl
 }
 
-func (b *BlizzardClient) fetchAuthToken(clientId string, clientSecret string) (string, error) {
-	if b.token.token != "" && b.token.expiresAt > time.Now().Unix() {
-		return b.token.token, nil
+func (b *BlizzardClient) fetchAuthToken(
+	region string,
+	clientId string,
+	clientSecret string,
+) (string, error) {
+	tokenUrl := b.authTokenUrl(region)
+
+	b.tokensMu.Lock()
+	defer b.tokensMu.Unlock()
+
+	token, ok := b.tokens[tokenUrl]
+	if ok && token.token != "" && token.expiresAt > time.Now().Unix() {
+		return token.token, nil
 	}
 
-	req, err := http.NewRequest(http.MethodPost, b.config.Blizzard.AuthTokenUrl, nil)
+	req, err := http.NewRequest(http.MethodPost, tokenUrl, nil)
 	if err != nil {
 		return "", err
 	}
@@ -115,20 +163,62 @@ func (b *BlizzardClient) fetchAuthToken(clientId string, clientSecret string) (s
 	var result BlizzardAuthTokenAPIResponse
 	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
 		return "",
-			fmt.Errorf("failed to fetch latest free games from the EGS api:\n%w", err)
+			fmt.Errorf("failed to read auth token response from the Blizzard API:\n%w", err)
 	}
 
-	b.token.token = result.AccessToken
-	b.token.expiresAt = time.Now().Unix() + result.ExpiresIn
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("the Blizzard API responded without an auth token")
+	}
+
+	b.tokens[tokenUrl] = &BlizzardClientToken{
+		token:     result.AccessToken,
+		expiresAt: time.Now().Unix() + result.ExpiresIn,
+	}
+
+	return result.AccessToken, nil
+}
+
+// forgetAuthToken drops the cached auth token used for a region, so a new one is fetched
+func (b *BlizzardClient) forgetAuthToken(region string) {
+	b.tokensMu.Lock()
+	defer b.tokensMu.Unlock()
+
+	delete(b.tokens, b.authTokenUrl(region))
+}
+
+func (b *BlizzardClient) requestTokenPrice(region string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, b.config.Blizzard.TokenPriceUrls[region], nil)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := b.fetchAuthToken(
+		region,
+		b.secrets.Blizzard.ClientID,
+		b.secrets.Blizzard.ClientSecret,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Add(
+		"Authorization",
+		fmt.Sprintf("Bearer %s", token),
+	)
 
-	return b.token.token, nil
+	return httpClient.Do(req)
 }
 
-func (b *BlizzardClient) FetchTokenPrice() (WowTokenPrice, error) {
-	data, err := b.db.GetLatestTokenPrice(b.ctx)
+func (b *BlizzardClient) FetchTokenPrice(region string) (WowTokenPrice, error) {
+	if !b.HasRegion(region) {
+		return WowTokenPrice{}, fmt.Errorf(`WoW Token: region "%s" is not being tracked`, region)
+	}
+
+	data, err := b.db.GetLatestTokenPrice(b.ctx, region)
 	if err != nil {
 		log.Printf(
-			"Failed to get latest token price from the database. Falling back to API request\n%v",
+			"Failed to get latest %s token price from the database. Falling back to API request\n%v",
+			RegionName(region),
 			err,
 		)
 	} else {
@@ -142,34 +232,31 @@ func (b *BlizzardClient) FetchTokenPrice() (WowTokenPrice, error) {
 		}
 	}
 
-	log.Println("Fetching latest WoW token price")
+	log.Printf("Fetching latest %s WoW token price", RegionName(region))
 
-	req, err := http.NewRequest(http.MethodGet, b.config.Blizzard.TokenPriceUrl, nil)
+	res, err := b.requestTokenPrice(region)
 	if err != nil {
 		return WowTokenPrice{}, err
 	}
 
-	token, err := b.fetchAuthToken(b.secrets.Blizzard.ClientID, b.secrets.Blizzard.ClientSecret)
-	if err != nil {
-		return WowTokenPrice{}, err
-	}
-
-	req.Header.Add(
-		"Authorization",
-		fmt.Sprintf("Bearer %s", token),
-	)
+	// Auth tokens can be revoked before they expire, so get a new one and try again once
+	if res.StatusCode == http.StatusUnauthorized {
+		res.Body.Close()
+		b.forgetAuthToken(region)
 
-	res, err := httpClient.Do(req)
-	if err != nil {
-		return WowTokenPrice{}, err
+		res, err = b.requestTokenPrice(region)
+		if err != nil {
+			return WowTokenPrice{}, err
+		}
 	}
 
 	defer res.Body.Close()
 
 	if res.StatusCode < 200 || res.StatusCode > 299 {
 		return WowTokenPrice{}, fmt.Errorf(
-			"HTTP error %s while attempting to fetch WoW Token price",
+			"HTTP error %s while attempting to fetch %s WoW Token price",
 			res.Status,
+			RegionName(region),
 		)
 	}
 
@@ -181,6 +268,13 @@ func (b *BlizzardClient) FetchTokenPrice() (WowTokenPrice, error) {
 		)
 	}
 
+	if result.Updated <= 0 || result.Price <= 0 {
+		return WowTokenPrice{}, fmt.Errorf(
+			"the Blizzard API responded without a %s WoW Token price",
+			RegionName(region),
+		)
+	}
+
 	result.Price = result.Price / 100 / 100
 
 	resultTime := time.UnixMilli(result.Updated)
@@ -191,6 +285,7 @@ func (b *BlizzardClient) FetchTokenPrice() (WowTokenPrice, error) {
 	}
 
 	_, err = b.db.AddTokenPrice(b.ctx, database.AddTokenPriceParams{
+		Region: region,
 		Updated: pgtype.Timestamptz{
 			Time:  resultTime,
 			Valid: true,
@@ -198,47 +293,36 @@ func (b *BlizzardClient) FetchTokenPrice() (WowTokenPrice, error) {
 		Price: newTokenPrice.Price,
 	})
 	if err != nil {
-		return WowTokenPrice{}, fmt.Errorf(
-			"WoW Token: Failed to add new price to database\n%w",
-			err,
-		)
+		// The fetched price is still accurate, so callers can choose to show it anyway
+		return newTokenPrice, fmt.Errorf("%w (%s)\n%w", ErrTokenPriceNotStored, RegionName(region), err)
 	}
 
-	log.Println("fetched latest token price, returning it")
+	b.checkAlerts(region, newTokenPrice)
+
+	log.Printf("fetched latest %s token price, returning it", RegionName(region))
 
 	return newTokenPrice, nil
 }
 
-func (b *BlizzardClient) StartWowTokenFetchInterval(ctx context.Context, period time.Duration) {
-	ticker := time.NewTicker(period)
-
-	// Do an initial fetch before deferring to the timer
-	_, err := b.FetchTokenPrice()
-	if err != nil {
-		log.Println(err)
+// FetchAllTokenPrices fetches the token price of every tracked region. A region failing does not
+// keep the other regions from being fetched.
+func (b *BlizzardClient) FetchAllTokenPrices() error {
+	var errs []error
+	for _, region := range b.config.Blizzard.Regions {
+		if _, err := b.FetchTokenPrice(region); err != nil {
+			errs = append(errs, err)
+		}
 	}
 
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				log.Println("WoW Token Timer: Attempting to fetch latest token price")
-				_, err := b.FetchTokenPrice()
-				if err != nil {
-					log.Println(err)
-				}
-			case <-ctx.Done():
-				ticker.Stop()
-				log.Println("WoW Token Timer: stopped")
-				return
-			}
-		}
-	}()
+	return errors.Join(errs...)
 }
 
 func (b *BlizzardClient) GeneratePriceChart(
+	region string,
 	unit string,
 	period int,
+	overlays ChartOverlays,
+	format ChartFormat,
 ) (*bytes.Buffer, time.Time, error) {
 	var t time.Time
 
@@ -258,9 +342,12 @@ func (b *BlizzardClient) GeneratePriceChart(
 		return bytes.NewBuffer([]byte{}), time.Now(), err
 	}
 
-	rows, err := b.db.GetAllTokenPricesSince(b.ctx, pgtype.Timestamptz{Time: t, Valid: true})
+	rows, err := b.db.GetAllTokenPricesSince(b.ctx, database.GetAllTokenPricesSinceParams{
+		Region:  region,
+		Updated: pgtype.Timestamptz{Time: t, Valid: true},
+	})
 	if err != nil {
-		err := fmt.Errorf("failed to get token prices from database")
+		err := fmt.Errorf("failed to get token prices from database: %w", err)
 		return bytes.NewBuffer([]byte{}), time.Now(), err
 	}
 
@@ -269,14 +356,14 @@ func (b *BlizzardClient) GeneratePriceChart(
 	dates := make([]time.Time, 0, numRows)
 	prices := make([]float64, 0, numRows)
 
-	for _, token := range rows {
-		dates = append(dates, token.Updated.Time)
-		prices = append(prices, float64(token.Price))
+	// Rows are ordered from newest to oldest, but overlays need to be calculated from oldest to newest
+	for i := numRows - 1; i >= 0; i-- {
+		dates = append(dates, rows[i].Updated.Time)
+		prices = append(prices, float64(rows[i].Price))
 	}
 
 	if len(dates) < 2 {
-		err := fmt.Errorf("not enough price history to generate chart")
-		return bytes.NewBuffer([]byte{}), time.Now(), err
+		return bytes.NewBuffer([]byte{}), time.Now(), ErrNotEnoughPriceHistory
 	}
 
 	formattedUnit := unit
@@ -285,12 +372,14 @@ func (b *BlizzardClient) GeneratePriceChart(
 	}
 
 	title := fmt.Sprintf(
-		"WoW Token Price History - Last %d %s",
+		"WoW Token Price History (%s) - Last %d %s",
+		RegionName(region),
 		period,
 		formattedUnit,
 	)
 
 	timeSeries := &chart.TimeSeries{
+		Name:    "Price",
 		XValues: dates,
 		YValues: prices,
 		Style: chart.Style{
@@ -342,18 +431,24 @@ func (b *BlizzardClient) GeneratePriceChart(
 				return ""
 			},
 		},
-		Series: []chart.Series{
-			timeSeries,
-		},
 	}
 
-	buffer := bytes.NewBuffer([]byte{})
-	err = graph.Render(chart.PNG, buffer)
+	below, above := overlaySeries(overlays, timeSeries, dates, prices)
+	graph.Series = append(graph.Series, below...)
+	graph.Series = append(graph.Series, timeSeries)
+	graph.Series = append(graph.Series, above...)
+
+	if overlays.Enabled() {
+		graph.Background.Padding.Bottom += chartLegendHeight
+		graph.Elements = []chart.Renderable{chartLegend(&graph)}
+	}
+
+	buffer, err := renderChart(graph, format)
 	if err != nil {
-		log.Fatalf("Failed to render graph: %v", err)
+		return bytes.NewBuffer([]byte{}), time.Now(), err
 	}
 
-	lastUpdate := dates[0]
+	lastUpdate := dates[len(dates)-1]
 
 	return buffer, lastUpdate, nil
 }
@@ -0,0 +1,276 @@
+package blizzard
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/pkg/config"
+	"github.com/aloop/discord-bot/internal/pkg/secrets"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+func newTestClient(db *fakes.DB, api *fakes.BlizzardAPI) *BlizzardClient {
+	return New(
+		context.Background(),
+		&config.Config{
+			Blizzard: config.BlizzardConfig{
+				AuthTokenUrl:   api.AuthTokenURL(),
+				TokenPriceUrls: api.TokenPriceURLs("us", "eu"),
+				Regions:        []string{"us", "eu"},
+			},
+		},
+		&secrets.Secrets{
+			Blizzard: secrets.BlizzardSecrets{
+				ClientID:     api.ClientID,
+				ClientSecret: api.ClientSecret,
+			},
+		},
+		storage.NewPostgres(db),
+	)
+}
+
+func storedPrice(region string, price int64, updated time.Time) database.WowTokenPrice {
+	return database.WowTokenPrice{
+		ID:      1,
+		Updated: pgtype.Timestamptz{Time: updated, Valid: true},
+		Price:   price,
+		Region:  region,
+	}
+}
+
+func TestFetchTokenPrice(t *testing.T) {
+	updated := time.Now().Add(-3 * time.Minute).Truncate(time.Millisecond)
+
+	api := fakes.NewBlizzardAPI(t)
+	api.SetPrice("eu", 321_000, updated)
+
+	db := fakes.NewDB()
+	db.SetRows("AddTokenPrice", storedPrice("eu", 321_000, updated))
+
+	price, err := newTestClient(db, api).FetchTokenPrice("eu")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if price.Price != 321_000 || !price.Updated.Equal(updated) {
+		t.Errorf("expected 321000 gold at %s, got %+v", updated, price)
+	}
+
+	calls := db.Calls("AddTokenPrice")
+	if len(calls) != 1 {
+		t.Fatalf("expected the price to be stored once, stored %d times", len(calls))
+	}
+
+	if region, gold := calls[0][0], calls[0][2]; region != "eu" || gold != int64(321_000) {
+		t.Errorf("expected 321000 gold to be stored for eu, stored %v for %v", gold, region)
+	}
+}
+
+func TestFetchTokenPriceNotStored(t *testing.T) {
+	updated := time.Now().Truncate(time.Millisecond)
+
+	api := fakes.NewBlizzardAPI(t)
+	api.SetPrice("us", 270_000, updated)
+
+	db := fakes.NewDB()
+	db.SetError("AddTokenPrice", errors.New("connection refused"))
+
+	price, err := newTestClient(db, api).FetchTokenPrice("us")
+	if !errors.Is(err, ErrTokenPriceNotStored) {
+		t.Fatalf("expected ErrTokenPriceNotStored, got %v", err)
+	}
+
+	if price.Price != 270_000 {
+		t.Errorf("expected the fetched price of 270000 gold along with the error, got %d", price.Price)
+	}
+
+	if n := len(db.Calls("GetTokenAlertsForRegion")); n != 0 {
+		t.Errorf("expected alerts not to be checked, checked %d times", n)
+	}
+}
+
+func TestFetchTokenPriceUsesRecentStoredPrice(t *testing.T) {
+	api := fakes.NewBlizzardAPI(t)
+
+	db := fakes.NewDB()
+	db.SetRows("GetLatestTokenPrice", storedPrice("us", 250_000, time.Now().Add(-5*time.Minute)))
+
+	price, err := newTestClient(db, api).FetchTokenPrice("us")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if price.Price != 250_000 {
+		t.Errorf("expected the stored price of 250000 gold, got %d", price.Price)
+	}
+
+	if n := api.PriceRequests(); n != 0 {
+		t.Errorf("expected no API requests, got %d", n)
+	}
+}
+
+func TestFetchTokenPriceRefreshesStalePrice(t *testing.T) {
+	api := fakes.NewBlizzardAPI(t)
+	api.SetPrice("us", 260_000, time.Now())
+
+	db := fakes.NewDB()
+	db.SetRows("GetLatestTokenPrice", storedPrice("us", 250_000, time.Now().Add(-time.Hour)))
+	db.SetRows("AddTokenPrice", storedPrice("us", 260_000, time.Now()))
+
+	price, err := newTestClient(db, api).FetchTokenPrice("us")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if price.Price != 260_000 {
+		t.Errorf("expected the new price of 260000 gold, got %d", price.Price)
+	}
+}
+
+func TestFetchTokenPriceErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response fakes.Response
+	}{
+		{"server error", fakes.Response{Status: http.StatusServiceUnavailable}},
+		{"not found", fakes.Response{Status: http.StatusNotFound, Body: `{"code":404}`}},
+		{"truncated body", fakes.Response{Body: `{"last_updated_timestamp": 17`}},
+		{"wrong types", fakes.Response{Body: `{"last_updated_timestamp": "soon", "price": "lots"}`}},
+		{"missing fields", fakes.Response{Body: `{}`}},
+		{"html", fakes.Response{Body: `<html>Maintenance</html>`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := fakes.NewBlizzardAPI(t)
+			api.QueuePriceResponses(tt.response)
+
+			db := fakes.NewDB()
+
+			price, err := newTestClient(db, api).FetchTokenPrice("us")
+			if err == nil {
+				t.Fatalf("expected an error, got %+v", price)
+			}
+
+			if n := len(db.Calls("AddTokenPrice")); n != 0 {
+				t.Errorf("expected nothing to be stored, stored %d prices", n)
+			}
+		})
+	}
+}
+
+func TestFetchTokenPriceUntrackedRegion(t *testing.T) {
+	api := fakes.NewBlizzardAPI(t)
+
+	if _, err := newTestClient(fakes.NewDB(), api).FetchTokenPrice("kr"); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if n := api.PriceRequests(); n != 0 {
+		t.Errorf("expected no API requests, got %d", n)
+	}
+}
+
+func TestFetchTokenPriceRenewsRevokedAuthToken(t *testing.T) {
+	api := fakes.NewBlizzardAPI(t)
+	api.SetPrice("us", 250_000, time.Now())
+
+	db := fakes.NewDB()
+	db.SetRows("AddTokenPrice", storedPrice("us", 250_000, time.Now()))
+	client := newTestClient(db, api)
+
+	if _, err := client.FetchTokenPrice("us"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	api.ExpireTokens()
+
+	if _, err := client.FetchTokenPrice("us"); err != nil {
+		t.Fatalf("unexpected error after the auth token was revoked: %v", err)
+	}
+
+	if n := api.AuthRequests(); n != 2 {
+		t.Errorf("expected a new auth token to be requested, got %d auth requests", n)
+	}
+}
+
+func TestFetchAuthTokenIsCached(t *testing.T) {
+	api := fakes.NewBlizzardAPI(t)
+	client := newTestClient(fakes.NewDB(), api)
+
+	first, err := client.fetchAuthToken("us", api.ClientID, api.ClientSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Regions sharing an auth token URL share their token
+	second, err := client.fetchAuthToken("eu", api.ClientID, api.ClientSecret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if first != second {
+		t.Errorf("expected the cached token %q, got %q", first, second)
+	}
+
+	if n := api.AuthRequests(); n != 1 {
+		t.Errorf("expected 1 auth request, got %d", n)
+	}
+}
+
+func TestFetchAuthTokenRenewsExpiredToken(t *testing.T) {
+	api := fakes.NewBlizzardAPI(t)
+	api.SetTokenLifetime(0)
+	client := newTestClient(fakes.NewDB(), api)
+
+	for range 2 {
+		if _, err := client.fetchAuthToken("us", api.ClientID, api.ClientSecret); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if n := api.AuthRequests(); n != 2 {
+		t.Errorf("expected the expired token to be renewed, got %d auth requests", n)
+	}
+}
+
+func TestFetchAuthTokenErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		secret   string
+		response *fakes.Response
+	}{
+		{name: "invalid credentials", secret: "wrong"},
+		{name: "server error", response: &fakes.Response{Status: http.StatusBadGateway}},
+		{name: "truncated body", response: &fakes.Response{Body: `{"access_token": "abc`}},
+		{name: "missing token", response: &fakes.Response{Body: `{"expires_in": 86399}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := fakes.NewBlizzardAPI(t)
+			if tt.response != nil {
+				api.QueueAuthResponses(*tt.response)
+			}
+
+			secret := api.ClientSecret
+			if tt.secret != "" {
+				secret = tt.secret
+			}
+
+			client := newTestClient(fakes.NewDB(), api)
+
+			token, err := client.fetchAuthToken("us", api.ClientID, secret)
+			if err == nil {
+				t.Fatalf("expected an error, got token %q", token)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package blizzard
+
+import "slices"
+
+// TokenPriceStats summarizes the token prices over a period of time
+type TokenPriceStats struct {
+	// Most recent price in the period
+	Current WowTokenPrice `json:"current"`
+	// Earliest price in the period
+	Start WowTokenPrice `json:"start"`
+	// Price and time of the period's highest and lowest price, the earliest one wins ties
+	High    WowTokenPrice `json:"high"`
+	Low     WowTokenPrice `json:"low"`
+	Average float64       `json:"average"`
+	Median  float64       `json:"median"`
+	// Change of the current price compared to the start of the period
+	Change        int64   `json:"change"`
+	ChangePercent float64 `json:"changePercent"`
+	// Change of the current price compared to the sample before it
+	LastChange int64 `json:"lastChange"`
+	Samples    int   `json:"samples"`
+}
+
+// ComputeTokenPriceStats calculates statistics for the given prices, which may be in any order.
+// ok is false when there are no prices to calculate statistics for.
+func ComputeTokenPriceStats(prices []WowTokenPrice) (stats TokenPriceStats, ok bool) {
+	if len(prices) == 0 {
+		return TokenPriceStats{}, false
+	}
+
+	sorted := slices.Clone(prices)
+	slices.SortStableFunc(sorted, func(a, b WowTokenPrice) int {
+		return a.Updated.Compare(b.Updated)
+	})
+
+	stats = TokenPriceStats{
+		Start:   sorted[0],
+		Current: sorted[len(sorted)-1],
+		High:    sorted[0],
+		Low:     sorted[0],
+		Samples: len(sorted),
+	}
+
+	var sum int64
+	values := make([]int64, 0, len(sorted))
+
+	for _, price := range sorted {
+		sum += price.Price
+		values = append(values, price.Price)
+
+		if price.Price > stats.High.Price {
+			stats.High = price
+		}
+
+		if price.Price < stats.Low.Price {
+			stats.Low = price
+		}
+	}
+
+	stats.Average = float64(sum) / float64(len(sorted))
+
+	slices.Sort(values)
+	if middle := len(values) / 2; len(values)%2 == 0 {
+		stats.Median = float64(values[middle-1]+values[middle]) / 2
+	} else {
+		stats.Median = float64(values[middle])
+	}
+
+	stats.Change = stats.Current.Price - stats.Start.Price
+	if stats.Start.Price != 0 {
+		stats.ChangePercent = float64(stats.Change) / float64(stats.Start.Price) * 100
+	}
+
+	if len(sorted) > 1 {
+		stats.LastChange = stats.Current.Price - sorted[len(sorted)-2].Price
+	}
+
+	return stats, true
+}
@@ -0,0 +1,267 @@
+package discordbot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/app/blizzard"
+)
+
+var (
+	minAlertThreshold float64 = 1
+
+	alertCommandGroup = &discordgo.ApplicationCommandOption{
+		Name:        "alert",
+		Description: "Manage WoW token price alerts",
+		Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+		Options: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "add",
+				Description: "Get notified when the WoW token price goes below or above a price",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "direction",
+						Description: "Whether to notify when the price goes below or above the threshold",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    true,
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{
+								Name:  "Below",
+								Value: blizzard.AlertBelow,
+							},
+							{
+								Name:  "Above",
+								Value: blizzard.AlertAbove,
+							},
+						},
+					},
+					{
+						Name:        "gold",
+						Description: "The price threshold in gold",
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Required:    true,
+						MinValue:    &minAlertThreshold,
+					},
+					{
+						Name:        "region",
+						Description: "The region to watch the token price for",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Choices:     regionChoices(),
+					},
+				},
+			},
+			{
+				Name:        "list",
+				Description: "Lists your WoW token price alerts",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        "remove",
+				Description: "Removes one of your WoW token price alerts",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "id",
+						Description: "The ID of the alert, as shown by /wowtoken alert list",
+						Type:        discordgo.ApplicationCommandOptionInteger,
+						Required:    true,
+					},
+				},
+			},
+		},
+	}
+)
+
+func interactionUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil {
+		return i.Member.User
+	}
+
+	return i.User
+}
+
+func handleWowTokenAlert(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	options []*discordgo.ApplicationCommandInteractionDataOption,
+) (*discordgo.InteractionResponseData, error) {
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no alert subcommand given")
+	}
+
+	user := interactionUser(i)
+	if user == nil {
+		return nil, fmt.Errorf("interaction has no user")
+	}
+
+	settings := guildSettings(context.Background(), i.GuildID)
+	p := guildPrinter(settings)
+	optionMap := optionsToMap(options[0].Options)
+
+	switch options[0].Name {
+	case "add":
+		region := guildRegion(settings)
+		if option, ok := optionMap["region"]; ok {
+			region = option.StringValue()
+		}
+
+		if !blizzardClient.HasRegion(region) {
+			return nil, userErrorf(
+				"WoW token prices are not being tracked for the %s region",
+				blizzard.RegionName(region),
+			)
+		}
+
+		existing, err := db.GetTokenAlertsForUser(context.Background(), user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get alerts for user: %w", err)
+		}
+
+		if len(existing) >= config.Blizzard.MaxAlertsPerUser {
+			return nil, userErrorf(
+				"You already have %d alerts, remove one with `/wowtoken alert remove` first",
+				len(existing),
+			)
+		}
+
+		alert, err := db.AddTokenAlert(context.Background(), database.AddTokenAlertParams{
+			UserID:    user.ID,
+			GuildID:   i.GuildID,
+			Region:    region,
+			Direction: optionMap["direction"].StringValue(),
+			Threshold: optionMap["gold"].IntValue(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add alert to database: %w", err)
+		}
+
+		return ephemeralMessage(p.Sprintf(
+			"Alert #%d added, you will be notified when the %s token price goes %s 🪙 **%d** gold",
+			alert.ID,
+			blizzard.RegionName(alert.Region),
+			alert.Direction,
+			alert.Threshold,
+		)), nil
+	case "list":
+		alerts, err := db.GetTokenAlertsForUser(context.Background(), user.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get alerts for user: %w", err)
+		}
+
+		if len(alerts) == 0 {
+			return ephemeralMessage("You have no WoW token price alerts"), nil
+		}
+
+		var sb strings.Builder
+		for _, alert := range alerts {
+			sb.WriteString(p.Sprintf(
+				"**#%d** - %s %s 🪙 **%d** gold\n",
+				alert.ID,
+				blizzard.RegionName(alert.Region),
+				alert.Direction,
+				alert.Threshold,
+			))
+		}
+
+		return ephemeralMessage(sb.String()), nil
+	case "remove":
+		id := optionMap["id"].IntValue()
+
+		removed, err := db.DeleteTokenAlert(context.Background(), database.DeleteTokenAlertParams{
+			ID:     id,
+			UserID: user.ID,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to remove alert: %w", err)
+		}
+
+		if removed == 0 {
+			return nil, userErrorf("You have no alert with the ID #%d", id)
+		}
+
+		return ephemeralMessage(fmt.Sprintf("Alert #%d removed", id)), nil
+	default:
+		return nil, fmt.Errorf(`unknown alert subcommand "%s"`, options[0].Name)
+	}
+}
+
+// notifyTokenAlert lets a user know their alert was triggered, either through the alerts channel
+// of the guild the alert was set up in or a direct message
+func notifyTokenAlert(
+	s *discordgo.Session,
+	alert database.WowTokenAlert,
+	stats blizzard.TokenPriceStats,
+) {
+	settings := guildSettings(context.Background(), alert.GuildID)
+	p := guildPrinter(settings)
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf(
+			"WoW Token Price Alert (%s)",
+			blizzard.RegionName(alert.Region),
+		),
+		Description: p.Sprintf(
+			"The WoW token price is now %s your alert of 🪙 **%d** gold",
+			alert.Direction,
+			alert.Threshold,
+		),
+		Fields: []*discordgo.MessageEmbedField{
+			{
+				Name:  "Current Price",
+				Value: p.Sprintf("🪙 **%d** gold", stats.Current.Price),
+			},
+			{
+				Name: "24-hour Change",
+				Value: p.Sprintf(
+					"%s (%+.2f%%)",
+					formatPriceChange(p, stats.Change),
+					stats.ChangePercent,
+				),
+				Inline: true,
+			},
+			{
+				Name: "24-hour Range",
+				Value: p.Sprintf(
+					"🪙 **%d** - **%d** gold",
+					stats.Low.Price,
+					stats.High.Price,
+				),
+				Inline: true,
+			},
+		},
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Alert #%d", alert.ID),
+		},
+	}
+
+	channelID := alertsChannel(settings)
+	content := fmt.Sprintf("<@%s>", alert.UserID)
+
+	if channelID == "" {
+		channel, err := s.UserChannelCreate(alert.UserID)
+		if err != nil {
+			log.Printf("WoW Token Alerts: Failed to open DM channel for alert %d\n%v", alert.ID, err)
+			return
+		}
+
+		channelID = channel.ID
+		content = ""
+	}
+
+	_, err := s.ChannelMessageSendComplex(channelID, &discordgo.MessageSend{
+		Content: content,
+		Embeds:  []*discordgo.MessageEmbed{embed},
+		AllowedMentions: &discordgo.MessageAllowedMentions{
+			Users: []string{alert.UserID},
+		},
+	})
+	if err != nil {
+		log.Printf("WoW Token Alerts: Failed to send notification for alert %d\n%v", alert.ID, err)
+	}
+}
@@ -0,0 +1,232 @@
+package discordbot
+
+import (
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+type botCommand struct {
+	definition *discordgo.ApplicationCommand
+	// Global commands are available in every guild. Others are only registered in the configured
+	// guild, and not at all when there is none, as they are meant for the bot owner.
+	global  bool
+	handler commandHandler
+}
+
+func findCommand(name string) *botCommand {
+	for _, cmd := range commands {
+		if cmd.definition.Name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+// syncCommands makes the commands registered with Discord match the declared commands.
+//
+// The registered commands are only overwritten when they differ from the declared ones, and
+// are left in place on shutdown, so restarts don't churn commands.
+func syncCommands(s *discordgo.Session, appID string, guildID string) error {
+	global, guild := commandScopes(guildID)
+
+	if err := syncCommandScope(s, appID, "", global); err != nil {
+		return err
+	}
+
+	if guildID == "" {
+		return nil
+	}
+
+	return syncCommandScope(s, appID, guildID, guild)
+}
+
+// commandScopes splits the declared commands into the global ones and the ones of the guild
+func commandScopes(guildID string) (global, guild []*discordgo.ApplicationCommand) {
+	global = make([]*discordgo.ApplicationCommand, 0, len(commands))
+	guild = make([]*discordgo.ApplicationCommand, 0, len(commands))
+
+	for _, cmd := range commands {
+		if cmd.global {
+			global = append(global, cmd.definition)
+		} else if guildID != "" {
+			guild = append(guild, cmd.definition)
+		}
+	}
+
+	return global, guild
+}
+
+func syncCommandScope(
+	s *discordgo.Session,
+	appID string,
+	guildID string,
+	desired []*discordgo.ApplicationCommand,
+) error {
+	scope := "global"
+	if guildID != "" {
+		scope = "guild"
+	}
+
+	registered, err := s.ApplicationCommands(appID, guildID)
+	if err != nil {
+		return fmt.Errorf("cannot get registered %s commands: %w", scope, err)
+	}
+
+	changes := diffCommands(registered, desired)
+	if len(changes) == 0 {
+		log.Printf("Commands: %d %s commands are up to date", len(desired), scope)
+		return nil
+	}
+
+	for _, change := range changes {
+		log.Printf("Commands: %s", change)
+	}
+
+	_, err = s.ApplicationCommandBulkOverwrite(appID, guildID, desired)
+	if err != nil {
+		return fmt.Errorf("cannot overwrite %s commands: %w", scope, err)
+	}
+
+	log.Printf("Commands: Updated %s commands", scope)
+
+	return nil
+}
+
+// diffCommands describes the changes needed to turn the registered commands into the desired
+// ones. An empty result means they already match.
+func diffCommands(registered, desired []*discordgo.ApplicationCommand) []string {
+	changes := make([]string, 0)
+
+	existing := make(map[string]*discordgo.ApplicationCommand, len(registered))
+	for _, cmd := range registered {
+		existing[cmd.Name] = cmd
+	}
+
+	for _, cmd := range desired {
+		current, ok := existing[cmd.Name]
+		if !ok {
+			changes = append(changes, fmt.Sprintf("adding /%s", cmd.Name))
+			continue
+		}
+
+		delete(existing, cmd.Name)
+
+		if !reflect.DeepEqual(normalizeCommand(current), normalizeCommand(cmd)) {
+			changes = append(changes, fmt.Sprintf("updating /%s", cmd.Name))
+		}
+	}
+
+	for name := range existing {
+		changes = append(changes, fmt.Sprintf("removing /%s", name))
+	}
+
+	return changes
+}
+
+type normalizedCommand struct {
+	Type                     discordgo.ApplicationCommandType
+	Name                     string
+	Description              string
+	DefaultMemberPermissions int64
+	DMPermission             bool
+	NSFW                     bool
+	Options                  []normalizedOption
+}
+
+type normalizedOption struct {
+	Type         discordgo.ApplicationCommandOptionType
+	Name         string
+	Description  string
+	ChannelTypes []discordgo.ChannelType
+	Required     bool
+	Autocomplete bool
+	Choices      []normalizedChoice
+	MinValue     string
+	MaxValue     float64
+	MinLength    string
+	MaxLength    int
+	Options      []normalizedOption
+}
+
+type normalizedChoice struct {
+	Name  string
+	Value string
+}
+
+// normalizeCommand strips the fields Discord fills in on registered commands, and applies
+// Discord's defaults to unset fields, so declared and registered commands can be compared
+func normalizeCommand(cmd *discordgo.ApplicationCommand) normalizedCommand {
+	normalized := normalizedCommand{
+		Type:                     cmd.Type,
+		Name:                     cmd.Name,
+		Description:              cmd.Description,
+		DefaultMemberPermissions: -1,
+		DMPermission:             true,
+		Options:                  normalizeOptions(cmd.Options),
+	}
+
+	if normalized.Type == 0 {
+		normalized.Type = discordgo.ChatApplicationCommand
+	}
+
+	if cmd.DefaultMemberPermissions != nil {
+		normalized.DefaultMemberPermissions = *cmd.DefaultMemberPermissions
+	}
+
+	if cmd.DMPermission != nil {
+		normalized.DMPermission = *cmd.DMPermission
+	}
+
+	if cmd.NSFW != nil {
+		normalized.NSFW = *cmd.NSFW
+	}
+
+	return normalized
+}
+
+func normalizeOptions(options []*discordgo.ApplicationCommandOption) []normalizedOption {
+	if len(options) == 0 {
+		return nil
+	}
+
+	normalized := make([]normalizedOption, 0, len(options))
+	for _, opt := range options {
+		option := normalizedOption{
+			Type:         opt.Type,
+			Name:         opt.Name,
+			Description:  opt.Description,
+			Required:     opt.Required,
+			Autocomplete: opt.Autocomplete,
+			MaxValue:     opt.MaxValue,
+			MaxLength:    opt.MaxLength,
+			Options:      normalizeOptions(opt.Options),
+		}
+
+		if len(opt.ChannelTypes) > 0 {
+			option.ChannelTypes = opt.ChannelTypes
+		}
+
+		if opt.MinValue != nil {
+			option.MinValue = fmt.Sprint(*opt.MinValue)
+		}
+
+		if opt.MinLength != nil {
+			option.MinLength = fmt.Sprint(*opt.MinLength)
+		}
+
+		for _, choice := range opt.Choices {
+			option.Choices = append(option.Choices, normalizedChoice{
+				Name:  choice.Name,
+				Value: fmt.Sprint(choice.Value),
+			})
+		}
+
+		normalized = append(normalized, option)
+	}
+
+	return normalized
+}
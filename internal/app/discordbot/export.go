@@ -0,0 +1,83 @@
+package discordbot
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/aloop/discord-bot/internal/app/blizzard"
+	appsecrets "github.com/aloop/discord-bot/internal/pkg/secrets"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+// Export implements the "export" subcommand, which writes the WoW token price history as CSV
+// to stdout or a file
+func Export(
+	ctx context.Context,
+	args []string,
+	getenv func(string) string,
+	workdir string,
+	stdout io.Writer,
+) error {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+
+	secretsPath := flags.String(
+		"secrets",
+		credentialsPath(getenv, workdir)+"/secrets.json",
+		"Path to the secrets file (JSON format), used when no database url is given",
+	)
+	dbUrl := flags.String("database-url", "", "Supply a database url")
+	outputPath := flags.String("o", "", "Path to write the CSV to, defaults to stdout")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *dbUrl == "" {
+		// Only the connection string is needed, so the other secrets are not validated
+		secrets := &appsecrets.Secrets{}
+		secrets.Load(*secretsPath)
+
+		if secrets.Database.ConnectionString == "" {
+			return fmt.Errorf("no database url given, and none set in %s", *secretsPath)
+		}
+		dbUrl = &secrets.Database.ConnectionString
+	}
+
+	store, err := storage.Open(ctx, *dbUrl)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	out := stdout
+	var file *os.File
+	if *outputPath != "" {
+		file, err = os.Create(*outputPath)
+		if err != nil {
+			return fmt.Errorf("could not create export file: %w", err)
+		}
+		// Only closes the file when the export fails, as it is closed below otherwise
+		defer file.Close()
+
+		out = file
+	}
+
+	if err := blizzard.ExportTokenPricesCSV(ctx, store, out); err != nil {
+		return fmt.Errorf("failed to export token prices: %w", err)
+	}
+
+	if file != nil {
+		// Writes may only fail once the file is closed
+		if err := file.Close(); err != nil {
+			return fmt.Errorf("could not write export file: %w", err)
+		}
+
+		log.Printf("Exported token prices to %s", *outputPath)
+	}
+
+	return nil
+}
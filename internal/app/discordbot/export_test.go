@@ -0,0 +1,42 @@
+package discordbot
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+func TestExportOnlyNeedsDatabaseSecrets(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	dbUrl := "sqlite://" + filepath.Join(dir, "bot.db")
+
+	store, err := storage.Open(ctx, dbUrl)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	if err := store.Migrate(ctx); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	store.Close()
+
+	secretsPath := filepath.Join(dir, "secrets.json")
+	contents := `{"database": {"connectionString": "` + dbUrl + `"}}`
+	if err := os.WriteFile(secretsPath, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write secrets: %v", err)
+	}
+
+	var out bytes.Buffer
+	err = Export(ctx, []string{"-secrets", secretsPath}, os.Getenv, dir, &out)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	if out.Len() == 0 {
+		t.Errorf("export wrote nothing")
+	}
+}
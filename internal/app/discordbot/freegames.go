@@ -0,0 +1,145 @@
+package discordbot
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/aloop/discord-bot/internal/app/egs"
+)
+
+const (
+	freeGamesCurrent  = "current"
+	freeGamesUpcoming = "upcoming"
+	freeGamesPast     = "past"
+
+	freeGamesPageSize = 5
+)
+
+var (
+	minFreeGamesPage float64 = 1
+
+	freeGamesCommand = &botCommand{
+		definition: &discordgo.ApplicationCommand{
+			Name:        "freegames",
+			Description: "Shows the games given away for free",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "show",
+					Description: "Which free games to show, defaults to the ones free right now",
+					Type:        discordgo.ApplicationCommandOptionString,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{
+							Name:  "Free right now",
+							Value: freeGamesCurrent,
+						},
+						{
+							Name:  "Upcoming",
+							Value: freeGamesUpcoming,
+						},
+						{
+							Name:  "Past giveaways",
+							Value: freeGamesPast,
+						},
+					},
+				},
+				{
+					Name:        "page",
+					Description: "The page of past giveaways to show",
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					MinValue:    &minFreeGamesPage,
+				},
+			},
+		},
+		global:  true,
+		handler: handleFreeGames,
+	}
+)
+
+func handleFreeGames(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+) (*discordgo.InteractionResponseData, error) {
+	optionMap := optionsToMap(i.ApplicationCommandData().Options)
+
+	show := freeGamesCurrent
+	if option, ok := optionMap["show"]; ok {
+		show = option.StringValue()
+	}
+
+	switch show {
+	case freeGamesCurrent:
+		games, err := freeGamesClient.CurrentFreeGames(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		if len(games) == 0 {
+			return ephemeralMessage("There are no free games right now"), nil
+		}
+
+		return freeGamesMessage("Free right now", games), nil
+	case freeGamesUpcoming:
+		games, err := freeGamesClient.FetchUpcomingFreeGames()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(games) == 0 {
+			return ephemeralMessage("No upcoming free games have been announced"), nil
+		}
+
+		return freeGamesMessage("Coming soon for free", games), nil
+	case freeGamesPast:
+		page := 1
+		if option, ok := optionMap["page"]; ok {
+			page = int(option.IntValue())
+		}
+
+		games, pages, err := freeGamesClient.PastFreeGames(
+			context.Background(),
+			page,
+			freeGamesPageSize,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if pages == 0 {
+			return ephemeralMessage("There have been no past giveaways yet"), nil
+		}
+
+		if len(games) == 0 {
+			if pages == 1 {
+				return nil, userErrorf("There is only 1 page of past giveaways")
+			}
+
+			return nil, userErrorf("There are only %d pages of past giveaways", pages)
+		}
+
+		return freeGamesMessage(
+			fmt.Sprintf("Past giveaways, page %d of %d", page, pages),
+			games,
+		), nil
+	default:
+		return nil, fmt.Errorf(`unknown free games option "%s"`, show)
+	}
+}
+
+func freeGamesMessage(content string, games egs.FreeGames) *discordgo.InteractionResponseData {
+	if hidden := len(games) - egs.MaxEmbedsPerMessage; hidden > 0 {
+		games = games[:egs.MaxEmbedsPerMessage]
+
+		if hidden == 1 {
+			content += "\n_1 more game is not shown_"
+		} else {
+			content += fmt.Sprintf("\n_%d more games are not shown_", hidden)
+		}
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content: content,
+		Embeds:  egs.CreateDiscordMessageEmbeds(games),
+	}
+}
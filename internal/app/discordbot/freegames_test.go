@@ -0,0 +1,291 @@
+package discordbot
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/app/egs"
+	appconfig "github.com/aloop/discord-bot/internal/pkg/config"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+// setupFreeGamesTest points the package globals at a fake database and EGS API
+func setupFreeGamesTest(t *testing.T, fake *fakes.DB, api *fakes.EGSAPI) {
+	t.Helper()
+
+	prevConfig, prevDB, prevClient := config, db, freeGamesClient
+	t.Cleanup(func() {
+		config, db, freeGamesClient = prevConfig, prevDB, prevClient
+	})
+
+	config = &appconfig.Config{
+		EpicGamesStore: appconfig.EpicGamesStoreConfig{
+			ProductBaseUrl:  api.ProductBaseURL(),
+			FreeGamesApiUrl: api.FreeGamesURL(),
+		},
+	}
+	db = storage.NewPostgres(fake)
+	freeGamesClient = egs.New(config, db)
+}
+
+func freeGamesInteraction(
+	options ...*discordgo.ApplicationCommandInteractionDataOption,
+) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type: discordgo.InteractionApplicationCommand,
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name:    "freegames",
+				Options: options,
+			},
+		},
+	}
+}
+
+func freeGamesOption(show string) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name:  "show",
+		Type:  discordgo.ApplicationCommandOptionString,
+		Value: show,
+	}
+}
+
+func freeGamesPageOption(page int) *discordgo.ApplicationCommandInteractionDataOption {
+	return &discordgo.ApplicationCommandInteractionDataOption{
+		Name: "page",
+		Type: discordgo.ApplicationCommandOptionInteger,
+		// Discord sends numbers as JSON, which are decoded as floats
+		Value: float64(page),
+	}
+}
+
+func storedFreeGame(id int64, title string, start, end time.Time) database.EgsFreeGame {
+	return database.EgsFreeGame{
+		ID:          id,
+		StoreID:     fmt.Sprintf("store-%d", id),
+		Title:       title,
+		Description: "A game",
+		Url:         "https://store.example.com/" + strings.ToLower(title),
+		StartDate:   timestamptz(start),
+		EndDate:     timestamptz(end),
+		Status:      egs.StatusLive,
+		Source:      egs.SourceEpic,
+	}
+}
+
+func TestHandleFreeGamesCurrent(t *testing.T) {
+	now := time.Now()
+
+	fake := fakes.NewDB()
+	fake.SetRows("GetCurrentFreeGames",
+		storedFreeGame(2, "Second", now.Add(-time.Hour), now.Add(time.Hour)),
+		storedFreeGame(1, "First", now.Add(-time.Hour), now.Add(time.Hour)),
+	)
+	setupFreeGamesTest(t, fake, fakes.NewEGSAPI(t))
+
+	data, err := handleFreeGames(nil, freeGamesInteraction())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(data.Embeds) != 2 || data.Embeds[0].Title != "Second" {
+		t.Fatalf("expected an embed for each current game, got %+v", data.Embeds)
+	}
+
+	if embedField(data.Embeds[0], "Free Until") == nil {
+		t.Error("expected a Free Until field")
+	}
+}
+
+func TestHandleFreeGamesCurrentTooMany(t *testing.T) {
+	now := time.Now()
+
+	var rows []any
+	for id := int64(1); id <= 12; id++ {
+		rows = append(rows,
+			storedFreeGame(id, fmt.Sprintf("Game %d", id), now.Add(-time.Hour), now.Add(time.Hour)))
+	}
+
+	fake := fakes.NewDB()
+	fake.SetRows("GetCurrentFreeGames", rows...)
+	setupFreeGamesTest(t, fake, fakes.NewEGSAPI(t))
+
+	data, err := handleFreeGames(nil, freeGamesInteraction())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(data.Embeds) != egs.MaxEmbedsPerMessage {
+		t.Errorf("expected %d embeds, got %d", egs.MaxEmbedsPerMessage, len(data.Embeds))
+	}
+
+	if !strings.Contains(data.Content, "2 more games are not shown") {
+		t.Errorf("expected the games left out to be counted, got %q", data.Content)
+	}
+}
+
+func TestHandleFreeGamesCurrentWithoutGames(t *testing.T) {
+	setupFreeGamesTest(t, fakes.NewDB(), fakes.NewEGSAPI(t))
+
+	data, err := handleFreeGames(nil, freeGamesInteraction(freeGamesOption(freeGamesCurrent)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if data.Flags&discordgo.MessageFlagsEphemeral == 0 || len(data.Embeds) != 0 {
+		t.Errorf("expected an ephemeral message without embeds, got %+v", data)
+	}
+}
+
+func TestHandleFreeGamesUpcoming(t *testing.T) {
+	now := time.Now()
+
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(
+		fakes.EGSGame{
+			ID:          "current",
+			Title:       "Current Game",
+			ProductSlug: "current-game",
+			Start:       now.Add(-time.Hour),
+			End:         now.Add(time.Hour),
+		},
+		fakes.EGSGame{
+			ID:            "later",
+			Title:         "Later Game",
+			ProductSlug:   "later-game",
+			OriginalPrice: 2999,
+			Start:         now.Add(14 * 24 * time.Hour),
+			End:           now.Add(21 * 24 * time.Hour),
+			Upcoming:      true,
+		},
+		fakes.EGSGame{
+			ID:            "next",
+			Title:         "Next Game",
+			ProductSlug:   "next-game",
+			OriginalPrice: 1999,
+			Start:         now.Add(7 * 24 * time.Hour),
+			End:           now.Add(14 * 24 * time.Hour),
+			Upcoming:      true,
+		},
+		fakes.EGSGame{
+			ID:            "sale",
+			Title:         "Upcoming Sale",
+			ProductSlug:   "upcoming-sale",
+			OriginalPrice: 1999,
+			DiscountPrice: 999,
+			Start:         now.Add(7 * 24 * time.Hour),
+			End:           now.Add(14 * 24 * time.Hour),
+			Upcoming:      true,
+		},
+	)
+	setupFreeGamesTest(t, fakes.NewDB(), api)
+
+	data, err := handleFreeGames(nil, freeGamesInteraction(freeGamesOption(freeGamesUpcoming)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(data.Embeds) != 2 {
+		t.Fatalf("expected only the upcoming free games, got %+v", data.Embeds)
+	}
+
+	if data.Embeds[0].Title != "Next Game" || data.Embeds[1].Title != "Later Game" {
+		t.Errorf("expected the soonest game first, got %s and %s",
+			data.Embeds[0].Title, data.Embeds[1].Title)
+	}
+
+	if embedField(data.Embeds[0], "Free From") == nil {
+		t.Error("expected a Free From field")
+	}
+}
+
+func TestHandleFreeGamesUpcomingAPIError(t *testing.T) {
+	api := fakes.NewEGSAPI(t)
+	api.QueueResponses(fakes.Response{Status: 503})
+	setupFreeGamesTest(t, fakes.NewDB(), api)
+
+	_, err := handleFreeGames(nil, freeGamesInteraction(freeGamesOption(freeGamesUpcoming)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestHandleFreeGamesPast(t *testing.T) {
+	now := time.Now()
+
+	rows := []any{
+		storedFreeGame(8, "Current", now.Add(-time.Hour), now.Add(time.Hour)),
+	}
+	for id := int64(7); id > 0; id-- {
+		end := now.Add(-time.Duration(8-id) * 7 * 24 * time.Hour)
+		rows = append(rows, storedFreeGame(id, fmt.Sprintf("Past %d", id), end.Add(-7*24*time.Hour), end))
+	}
+
+	fake := fakes.NewDB()
+	fake.SetRows("GetAllFreeGames", rows...)
+	setupFreeGamesTest(t, fake, fakes.NewEGSAPI(t))
+
+	first, err := handleFreeGames(nil, freeGamesInteraction(freeGamesOption(freeGamesPast)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(first.Embeds) != freeGamesPageSize || first.Embeds[0].Title != "Past 7" {
+		t.Fatalf("expected a full page starting with the latest past game, got %+v", first.Embeds)
+	}
+
+	if !strings.Contains(first.Content, "page 1 of 2") {
+		t.Errorf("expected the page number to be shown, got %q", first.Content)
+	}
+
+	if embedField(first.Embeds[0], "Was Free Until") == nil {
+		t.Error("expected a Was Free Until field")
+	}
+
+	second, err := handleFreeGames(nil, freeGamesInteraction(
+		freeGamesOption(freeGamesPast),
+		freeGamesPageOption(2),
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(second.Embeds) != 2 || second.Embeds[1].Title != "Past 1" {
+		t.Errorf("expected the remaining past games, got %+v", second.Embeds)
+	}
+
+	_, err = handleFreeGames(nil, freeGamesInteraction(
+		freeGamesOption(freeGamesPast),
+		freeGamesPageOption(3),
+	))
+
+	var userErr *userError
+	if !errors.As(err, &userErr) {
+		t.Errorf("expected a user error for a page past the end, got %v", err)
+	}
+}
+
+func TestHandleFreeGamesPastSinglePage(t *testing.T) {
+	end := time.Now().Add(-24 * time.Hour)
+
+	fake := fakes.NewDB()
+	fake.SetRows("GetAllFreeGames", storedFreeGame(1, "Past", end.Add(-7*24*time.Hour), end))
+	setupFreeGamesTest(t, fake, fakes.NewEGSAPI(t))
+
+	_, err := handleFreeGames(nil, freeGamesInteraction(
+		freeGamesOption(freeGamesPast),
+		freeGamesPageOption(2),
+	))
+
+	var userErr *userError
+	if !errors.As(err, &userErr) || userErr.message != "There is only 1 page of past giveaways" {
+		t.Errorf("expected a user error about the single page, got %v", err)
+	}
+}
@@ -0,0 +1,334 @@
+package discordbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/app/blizzard"
+	"github.com/aloop/discord-bot/internal/app/egs"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+const defaultLocale = "en"
+
+// Settings that can be changed through /config, named after their command options
+const (
+	settingDealsChannel  = "deals_channel"
+	settingAlertsChannel = "alerts_channel"
+	settingRegion        = "region"
+	settingLocale        = "locale"
+	settingPingRole      = "ping_role"
+)
+
+var (
+	manageServerPermission int64 = discordgo.PermissionManageServer
+	configInDMs                  = false
+
+	announcementChannelTypes = []discordgo.ChannelType{
+		discordgo.ChannelTypeGuildText,
+		discordgo.ChannelTypeGuildNews,
+	}
+
+	configCommand = &botCommand{
+		definition: &discordgo.ApplicationCommand{
+			Name:                     "config",
+			Description:              "Configure the bot for this server",
+			DefaultMemberPermissions: &manageServerPermission,
+			DMPermission:             &configInDMs,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "show",
+					Description: "Shows the settings of this server",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "set",
+					Description: "Changes the given settings, leaving the others as they are",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:         settingDealsChannel,
+							Description:  "The channel free games are announced in",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: announcementChannelTypes,
+						},
+						{
+							Name:         settingAlertsChannel,
+							Description:  "The channel WoW token price alerts are sent to",
+							Type:         discordgo.ApplicationCommandOptionChannel,
+							ChannelTypes: announcementChannelTypes,
+						},
+						{
+							Name:        settingRegion,
+							Description: "The default region for WoW token prices",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Choices:     regionChoices(),
+						},
+						{
+							Name:        settingLocale,
+							Description: `The language used to format numbers, such as "en" or "de"`,
+							Type:        discordgo.ApplicationCommandOptionString,
+						},
+						{
+							Name:        settingPingRole,
+							Description: "The role pinged by free game reminders",
+							Type:        discordgo.ApplicationCommandOptionRole,
+						},
+					},
+				},
+				{
+					Name:        "clear",
+					Description: "Resets a setting to its default",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Name:        "setting",
+							Description: "The setting to reset",
+							Type:        discordgo.ApplicationCommandOptionString,
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "Deals channel", Value: settingDealsChannel},
+								{Name: "Alerts channel", Value: settingAlertsChannel},
+								{Name: "Region", Value: settingRegion},
+								{Name: "Locale", Value: settingLocale},
+								{Name: "Ping role", Value: settingPingRole},
+							},
+						},
+					},
+				},
+			},
+		},
+		global:  true,
+		handler: handleConfig,
+	}
+)
+
+func handleConfig(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+) (*discordgo.InteractionResponseData, error) {
+	if i.GuildID == "" || i.Member == nil {
+		return nil, userErrorf("The bot can only be configured from within a server")
+	}
+
+	if err := requirePermission(i, discordgo.PermissionManageServer, "Manage Server"); err != nil {
+		return nil, err
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no config subcommand given")
+	}
+
+	ctx := context.Background()
+
+	settings, err := db.GetGuildSettings(ctx, i.GuildID)
+	if errors.Is(err, storage.ErrNotFound) {
+		settings = database.GuildSetting{GuildID: i.GuildID}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get guild settings: %w", err)
+	}
+
+	optionMap := optionsToMap(options[0].Options)
+
+	switch options[0].Name {
+	case "show":
+		return configMessage("Settings for this server", settings), nil
+	case "set":
+		if len(optionMap) == 0 {
+			return nil, userErrorf("Choose at least one setting to change")
+		}
+
+		if option, ok := optionMap[settingDealsChannel]; ok {
+			settings.DealsChannelID = option.ChannelValue(nil).ID
+		}
+
+		if option, ok := optionMap[settingAlertsChannel]; ok {
+			settings.AlertsChannelID = option.ChannelValue(nil).ID
+		}
+
+		if option, ok := optionMap[settingRegion]; ok {
+			region := option.StringValue()
+			if !blizzardClient.HasRegion(region) {
+				return nil, userErrorf(
+					"WoW token prices are not being tracked for the %s region",
+					blizzard.RegionName(region),
+				)
+			}
+
+			settings.Region = region
+		}
+
+		if option, ok := optionMap[settingLocale]; ok {
+			tag, err := language.Parse(option.StringValue())
+			if err != nil {
+				return nil, userErrorf(`"%s" is not a valid locale`, option.StringValue())
+			}
+
+			settings.Locale = tag.String()
+		}
+
+		if option, ok := optionMap[settingPingRole]; ok {
+			settings.PingRoleID = option.RoleValue(nil, i.GuildID).ID
+		}
+	case "clear":
+		switch optionMap["setting"].StringValue() {
+		case settingDealsChannel:
+			settings.DealsChannelID = ""
+		case settingAlertsChannel:
+			settings.AlertsChannelID = ""
+		case settingRegion:
+			settings.Region = ""
+		case settingLocale:
+			settings.Locale = ""
+		case settingPingRole:
+			settings.PingRoleID = ""
+		default:
+			return nil, fmt.Errorf(`unknown setting "%s"`, optionMap["setting"].StringValue())
+		}
+	default:
+		return nil, fmt.Errorf(`unknown config subcommand "%s"`, options[0].Name)
+	}
+
+	settings, err = db.UpsertGuildSettings(ctx, database.UpsertGuildSettingsParams{
+		GuildID:         settings.GuildID,
+		DealsChannelID:  settings.DealsChannelID,
+		AlertsChannelID: settings.AlertsChannelID,
+		Region:          settings.Region,
+		Locale:          settings.Locale,
+		PingRoleID:      settings.PingRoleID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to save guild settings: %w", err)
+	}
+
+	return configMessage("Settings updated", settings), nil
+}
+
+func configMessage(title string, settings database.GuildSetting) *discordgo.InteractionResponseData {
+	orDefault := func(value, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+
+		return value
+	}
+
+	mention := func(format, id string) string {
+		if id == "" {
+			return "Not set"
+		}
+
+		return fmt.Sprintf(format, id)
+	}
+
+	region := blizzard.RegionName(orDefault(settings.Region, blizzardClient.DefaultRegion()))
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title: title,
+				Fields: []*discordgo.MessageEmbedField{
+					{Name: "Deals Channel", Value: mention("<#%s>", settings.DealsChannelID)},
+					{Name: "Alerts Channel", Value: mention("<#%s>", settings.AlertsChannelID)},
+					{Name: "Region", Value: region, Inline: true},
+					{Name: "Locale", Value: orDefault(settings.Locale, defaultLocale), Inline: true},
+					{Name: "Ping Role", Value: mention("<@&%s>", settings.PingRoleID)},
+				},
+			},
+		},
+		Flags: discordgo.MessageFlagsEphemeral,
+	}
+}
+
+// guildSettings returns the settings of a guild, or the defaults when it has none
+func guildSettings(ctx context.Context, guildID string) database.GuildSetting {
+	if guildID == "" {
+		return database.GuildSetting{}
+	}
+
+	settings, err := db.GetGuildSettings(ctx, guildID)
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Guild Settings: Failed to get settings for guild %s\n%v", guildID, err)
+		}
+
+		return database.GuildSetting{GuildID: guildID}
+	}
+
+	return settings
+}
+
+// guildRegion returns the default WoW token region of a guild
+func guildRegion(settings database.GuildSetting) string {
+	if settings.Region != "" && blizzardClient.HasRegion(settings.Region) {
+		return settings.Region
+	}
+
+	return blizzardClient.DefaultRegion()
+}
+
+// guildPrinter returns a printer formatting numbers for the locale of a guild
+func guildPrinter(settings database.GuildSetting) *message.Printer {
+	tag, err := language.Parse(settings.Locale)
+	if err != nil {
+		tag = language.Make(defaultLocale)
+	}
+
+	return message.NewPrinter(tag)
+}
+
+// dealsChannels lists the deals channel of every configured guild, along with the deals channel
+// from the secrets file, which keeps single server setups working without /config
+func dealsChannels(ctx context.Context) ([]egs.Channel, error) {
+	settings, err := db.GetAllGuildSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make([]egs.Channel, 0, len(settings)+1)
+	seen := make(map[string]bool, len(settings)+1)
+
+	for _, guild := range settings {
+		if guild.DealsChannelID == "" || seen[guild.DealsChannelID] {
+			continue
+		}
+
+		seen[guild.DealsChannelID] = true
+		channels = append(channels, egs.Channel{
+			ID:           guild.DealsChannelID,
+			ReminderRole: guild.PingRoleID,
+		})
+	}
+
+	if deals := secrets.Channels.Deals; deals != "" && !seen[deals] {
+		channels = append(channels, egs.Channel{
+			ID:           deals,
+			ReminderRole: secrets.Roles.FreeGameReminders,
+		})
+	}
+
+	return channels, nil
+}
+
+// alertsChannel returns the channel WoW token price alerts set up in a guild are sent to. The
+// alerts channel from the secrets file only applies to the guild from the secrets file, so
+// alerts never end up in another server.
+func alertsChannel(settings database.GuildSetting) string {
+	if settings.AlertsChannelID != "" {
+		return settings.AlertsChannelID
+	}
+
+	if secrets.Discord.GuildID == "" || secrets.Discord.GuildID == settings.GuildID {
+		return secrets.Channels.Alerts
+	}
+
+	return ""
+}
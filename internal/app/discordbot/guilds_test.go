@@ -0,0 +1,215 @@
+package discordbot
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/aloop/discord-bot/database"
+	appsecrets "github.com/aloop/discord-bot/internal/pkg/secrets"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+func guildSettingsRow(guildID, deals, alerts, region, locale, role string) database.GuildSetting {
+	return database.GuildSetting{
+		GuildID:         guildID,
+		DealsChannelID:  deals,
+		AlertsChannelID: alerts,
+		Region:          region,
+		Locale:          locale,
+		PingRoleID:      role,
+		Updated:         timestamptz(time.Now()),
+	}
+}
+
+func configInteraction(
+	permissions int64,
+	subcommand string,
+	options ...*discordgo.ApplicationCommandInteractionDataOption,
+) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild",
+			Member: &discordgo.Member{
+				User:        &discordgo.User{ID: "admin"},
+				Permissions: permissions,
+			},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "config",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{
+						Name:    subcommand,
+						Type:    discordgo.ApplicationCommandOptionSubCommand,
+						Options: options,
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestHandleConfigRequiresManageServer(t *testing.T) {
+	fake := fakes.NewDB()
+	setupWowTokenTest(t, fake, fakes.NewBlizzardAPI(t))
+
+	_, err := handleConfig(nil, configInteraction(discordgo.PermissionSendMessages, "show"))
+
+	var userErr *userError
+	if !errors.As(err, &userErr) {
+		t.Fatalf("expected a user error, got %v", err)
+	}
+
+	if n := len(fake.Calls("UpsertGuildSettings")); n != 0 {
+		t.Errorf("expected no settings to be saved, saved %d times", n)
+	}
+}
+
+func TestHandleConfigSet(t *testing.T) {
+	fake := fakes.NewDB()
+	fake.SetRows("GetGuildSettings", guildSettingsRow("guild", "old-deals", "alerts", "", "", "role"))
+	fake.SetRows("UpsertGuildSettings",
+		guildSettingsRow("guild", "deals", "alerts", "us", "de", "role"))
+	setupWowTokenTest(t, fake, fakes.NewBlizzardAPI(t))
+
+	data, err := handleConfig(nil, configInteraction(
+		discordgo.PermissionManageServer,
+		"set",
+		&discordgo.ApplicationCommandInteractionDataOption{
+			Name:  settingDealsChannel,
+			Type:  discordgo.ApplicationCommandOptionChannel,
+			Value: "deals",
+		},
+		&discordgo.ApplicationCommandInteractionDataOption{
+			Name:  settingLocale,
+			Type:  discordgo.ApplicationCommandOptionString,
+			Value: "DE",
+		},
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	calls := fake.Calls("UpsertGuildSettings")
+	if len(calls) != 1 {
+		t.Fatalf("expected the settings to be saved once, saved %d times", len(calls))
+	}
+
+	want := []any{"guild", "deals", "alerts", "", "de", "role"}
+	for i, value := range want {
+		if calls[0][i] != value {
+			t.Errorf("expected argument %d to be %q, got %q", i, value, calls[0][i])
+		}
+	}
+
+	if len(data.Embeds) != 1 || embedField(data.Embeds[0], "Deals Channel").Value != "<#deals>" {
+		t.Errorf("expected the new settings to be shown, got %+v", data.Embeds)
+	}
+}
+
+func TestHandleConfigSetInvalidValues(t *testing.T) {
+	tests := []struct {
+		name   string
+		option *discordgo.ApplicationCommandInteractionDataOption
+	}{
+		{"untracked region", &discordgo.ApplicationCommandInteractionDataOption{
+			Name:  settingRegion,
+			Type:  discordgo.ApplicationCommandOptionString,
+			Value: "kr",
+		}},
+		{"invalid locale", &discordgo.ApplicationCommandInteractionDataOption{
+			Name:  settingLocale,
+			Type:  discordgo.ApplicationCommandOptionString,
+			Value: "not a locale",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := fakes.NewDB()
+			setupWowTokenTest(t, fake, fakes.NewBlizzardAPI(t))
+
+			_, err := handleConfig(nil, configInteraction(
+				discordgo.PermissionManageServer,
+				"set",
+				tt.option,
+			))
+
+			var userErr *userError
+			if !errors.As(err, &userErr) {
+				t.Fatalf("expected a user error, got %v", err)
+			}
+
+			if n := len(fake.Calls("UpsertGuildSettings")); n != 0 {
+				t.Errorf("expected no settings to be saved, saved %d times", n)
+			}
+		})
+	}
+}
+
+func TestDealsChannels(t *testing.T) {
+	fake := fakes.NewDB()
+	fake.SetRows("GetAllGuildSettings",
+		guildSettingsRow("first", "first-deals", "", "", "", "first-role"),
+		guildSettingsRow("second", "", "", "eu", "", ""),
+		guildSettingsRow("third", "shared-deals", "", "", "", ""),
+	)
+	setupWowTokenTest(t, fake, fakes.NewBlizzardAPI(t))
+	secrets.Channels.Deals = "shared-deals"
+	secrets.Roles.FreeGameReminders = "secrets-role"
+
+	channels, err := dealsChannels(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(channels) != 2 {
+		t.Fatalf("expected each deals channel once, got %+v", channels)
+	}
+
+	if channels[0].ID != "first-deals" || channels[0].ReminderRole != "first-role" {
+		t.Errorf("unexpected channel %+v", channels[0])
+	}
+
+	if channels[1].ID != "shared-deals" || channels[1].ReminderRole != "" {
+		t.Errorf("expected the guild settings to take precedence, got %+v", channels[1])
+	}
+}
+
+func TestAlertsChannel(t *testing.T) {
+	prevSecrets := secrets
+	t.Cleanup(func() { secrets = prevSecrets })
+
+	tests := []struct {
+		name          string
+		secretsGuild  string
+		guild         string
+		guildChannel  string
+		expectChannel string
+	}{
+		{"guild channel", "guild", "guild", "guild-alerts", "guild-alerts"},
+		{"secrets channel for the secrets guild", "guild", "guild", "", "alerts"},
+		{"secrets channel without a secrets guild", "", "other", "", "alerts"},
+		{"no channel for other guilds", "guild", "other", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets = &appsecrets.Secrets{
+				Discord:  appsecrets.DiscordSecrets{GuildID: tt.secretsGuild},
+				Channels: appsecrets.ChannelsSecrets{Alerts: "alerts"},
+			}
+
+			channel := alertsChannel(database.GuildSetting{
+				GuildID:         tt.guild,
+				AlertsChannelID: tt.guildChannel,
+			})
+			if channel != tt.expectChannel {
+				t.Errorf("expected channel %q, got %q", tt.expectChannel, channel)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package discordbot
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Interactions received over HTTP that have not been responded to yet, keyed by interaction ID
+var pendingHTTPInteractions sync.Map
+
+// respond sends the initial response to an interaction, regardless of whether it was received
+// over the gateway or over HTTP. It must be called at most once per interaction.
+func respond(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	resp *discordgo.InteractionResponse,
+) error {
+	v, ok := pendingHTTPInteractions.Load(i.ID)
+	if !ok {
+		return s.InteractionRespond(i.Interaction, resp)
+	}
+
+	// Sent as the body of the HTTP response by interactionsHandler
+	v.(chan *discordgo.InteractionResponse) <- resp
+
+	return nil
+}
+
+func dispatchInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type != discordgo.InteractionApplicationCommand {
+		return
+	}
+
+	if cmd := findCommand(i.ApplicationCommandData().Name); cmd != nil {
+		runCommand(s, i, cmd)
+	}
+}
+
+// interactionsHandler receives interactions posted by Discord to the interactions endpoint
+// and dispatches them to the same handlers used for gateway interactions
+func interactionsHandler(s *discordgo.Session, publicKey string) (http.HandlerFunc, error) {
+	key, err := hex.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid Discord application public key")
+	}
+
+	return func(w http.ResponseWriter, req *http.Request) {
+		req.Body = http.MaxBytesReader(w, req.Body, 1<<20)
+
+		if !discordgo.VerifyInteraction(req, ed25519.PublicKey(key)) {
+			http.Error(w, "401 Unauthorized - Invalid request signature", http.StatusUnauthorized)
+			return
+		}
+
+		var interaction discordgo.Interaction
+		if err := json.NewDecoder(req.Body).Decode(&interaction); err != nil {
+			http.Error(w, "400 Bad Request", http.StatusBadRequest)
+			return
+		}
+
+		if interaction.Type == discordgo.InteractionPing {
+			writeInteractionResponse(w, &discordgo.InteractionResponse{
+				Type: discordgo.InteractionResponsePong,
+			})
+			return
+		}
+
+		responses := make(chan *discordgo.InteractionResponse, 1)
+		pendingHTTPInteractions.Store(interaction.ID, responses)
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			defer pendingHTTPInteractions.Delete(interaction.ID)
+			dispatchInteraction(s, &discordgo.InteractionCreate{Interaction: &interaction})
+		}()
+
+		// runCommand responds, or defers the response, before Discord's deadline. Anything
+		// sent after that is delivered through the interaction webhook instead.
+		select {
+		case resp := <-responses:
+			writeInteractionResponse(w, resp)
+		case <-done:
+			select {
+			case resp := <-responses:
+				writeInteractionResponse(w, resp)
+			default:
+				http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+			}
+		}
+	}, nil
+}
+
+func writeInteractionResponse(w http.ResponseWriter, resp *discordgo.InteractionResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Printf("Failed while writing interaction response\n%v\n", err)
+	}
+}
@@ -3,17 +3,19 @@ package discordbot
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"math"
+	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/jackc/pgx/v5/pgtype"
-	"github.com/jackc/pgx/v5/pgxpool"
 	"golang.org/x/text/message"
 
 	"github.com/aloop/discord-bot/database"
@@ -21,7 +23,10 @@ import (
 	"github.com/aloop/discord-bot/internal/app/egs"
 	"github.com/aloop/discord-bot/internal/app/webserver"
 	appconfig "github.com/aloop/discord-bot/internal/pkg/config"
+	"github.com/aloop/discord-bot/internal/pkg/leader"
+	"github.com/aloop/discord-bot/internal/pkg/scheduler"
 	appsecrets "github.com/aloop/discord-bot/internal/pkg/secrets"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
 	"github.com/aloop/discord-bot/internal/pkg/utils"
 )
 
@@ -34,206 +39,397 @@ var (
 	configFilePath  string
 	secretsFilePath string
 
-	config         *appconfig.Config
-	secrets        *appsecrets.Secrets
-	db             *database.Queries
-	DiscordSession *discordgo.Session
-	blizzardClient *blizzard.BlizzardClient
-	egsClient      *egs.EGSClient
+	config          *appconfig.Config
+	secrets         *appsecrets.Secrets
+	db              storage.Store
+	DiscordSession  *discordgo.Session
+	blizzardClient  *blizzard.BlizzardClient
+	freeGamesClient *egs.FreeGamesClient
+	jobs            *scheduler.Scheduler
 
-	commands = []*discordgo.ApplicationCommand{
+	commands = []*botCommand{
 		{
-			Name:        "wowtoken",
-			Description: "Displays the current WoW token price in gold",
-			Options: []*discordgo.ApplicationCommandOption{
-				{
-					Name:        "chart",
-					Description: "Define the time period used when generating the price history chart",
-					Type:        discordgo.ApplicationCommandOptionString,
-					Choices: []*discordgo.ApplicationCommandOptionChoice{
-						{
-							Name:  "24 hours",
-							Value: `{"period": 24, "unit": "hours"}`,
-						},
-						{
-							Name:  "48 hours",
-							Value: `{"period": 48, "unit": "hours"}`,
-						},
-						{
-							Name:  "10 days",
-							Value: `{"period": 10, "unit": "days"}`,
-						},
-						{
-							Name:  "30 days",
-							Value: `{"period": 30, "unit": "days"}`,
-						},
-						{
-							Name:  "3 months",
-							Value: `{"period": 3, "unit": "months"}`,
-						},
-						{
-							Name:  "6 months",
-							Value: `{"period": 6, "unit": "months"}`,
-						},
-						{
-							Name:  "9 months",
-							Value: `{"period": 9, "unit": "months"}`,
-						},
-						{
-							Name:  "12 months",
-							Value: `{"period": 12, "unit": "months"}`,
-						},
-					},
-				},
-			},
-		},
-	}
-
-	commandHandlers = map[string]func(s *discordgo.Session, i *discordgo.InteractionCreate){
-		"wowtoken": func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-			options := i.ApplicationCommandData().Options
-
-			optionMap := make(
-				map[string]*discordgo.ApplicationCommandInteractionDataOption,
-				len(options),
-			)
-			for _, opt := range options {
-				optionMap[opt.Name] = opt
-			}
-
-			chartOpts := chartTimePeriod{
-				Unit:   "hours",
-				Period: 48,
-			}
-
-			if option, ok := optionMap["chart"]; ok {
-				if err := json.Unmarshal([]byte(option.StringValue()), &chartOpts); err != nil {
-					log.Printf("Failed to parse /wowtoken options: %v", err)
-					// TODO: Should probably send back an error response to the user
-					return
-				}
-			}
-
-			p := message.NewPrinter(message.MatchLanguage("en"))
-
-			var t time.Time
-			switch chartOpts.Unit {
-			case "hours":
-				t = time.Now().UTC().Add(time.Hour * time.Duration(chartOpts.Period) * -1)
-			case "days":
-				t = time.Now().UTC().AddDate(0, 0, chartOpts.Period*-1)
-			case "months":
-				t = time.Now().UTC().AddDate(0, chartOpts.Period*-1, 0)
-			default:
-				log.Println(`Invalid unit given, must be one of "hours", "days", or "months"`)
-			}
-
-			// Fetch a new token price if available
-			_, err := blizzardClient.FetchTokenPrice()
-			if err != nil {
-				log.Println(err)
-				return
-			}
-
-			tokenHistory, err := db.GetAllTokenPricesSince(
-				context.Background(),
-				pgtype.Timestamptz{Time: t, Valid: true},
-			)
-			if err != nil {
-				log.Printf("Failed to fetch latest token price\n%v", err)
-				return
-			}
-
-			var (
-				lowestPrice  int64
-				highestPrice int64
-			)
-			for i, token := range tokenHistory {
-				if i == 0 {
-					lowestPrice = token.Price
-					highestPrice = token.Price
-				}
-
-				if token.Price < lowestPrice {
-					lowestPrice = token.Price
-				}
-
-				if token.Price > highestPrice {
-					highestPrice = token.Price
-				}
-			}
-
-			latestToken := tokenHistory[0]
-
-			timeSinceLastUpdate := int64(time.Now().UTC().Sub(latestToken.Updated.Time).Minutes())
-			nextUpdateDelta := blizzard.WowTokenGracePeriod - timeSinceLastUpdate
-
-			updateTimePluralStr := ""
-			if nextUpdateDelta > 1 {
-				updateTimePluralStr = "s"
-			} else {
-				nextUpdateDelta = 1
-			}
-
-			err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-				Type: discordgo.InteractionResponseChannelMessageWithSource,
-				Data: &discordgo.InteractionResponseData{
-					Embeds: []*discordgo.MessageEmbed{
-						{
-							Title: "World of Warcraft Token Price",
-							Fields: []*discordgo.MessageEmbedField{
-								{
-									Name:  "Current Price",
-									Value: p.Sprintf("🪙 **%d** gold", latestToken.Price),
-								},
-								{
-									Name: fmt.Sprintf(
-										"%d-%s High",
-										chartOpts.Period,
-										utils.Singularize(chartOpts.Unit),
-									),
-									Value:  p.Sprintf("🪙 **%d** gold", highestPrice),
-									Inline: true,
+			definition: &discordgo.ApplicationCommand{
+				Name:        "wowtoken",
+				Description: "WoW token prices and price alerts",
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "price",
+						Description: "Displays the current WoW token price in gold",
+						Type:        discordgo.ApplicationCommandOptionSubCommand,
+						Options: []*discordgo.ApplicationCommandOption{
+							{
+								Name:        "chart",
+								Description: "Define the time period used when generating the price history chart",
+								Type:        discordgo.ApplicationCommandOptionString,
+								Choices: []*discordgo.ApplicationCommandOptionChoice{
+									{
+										Name:  "24 hours",
+										Value: `{"period": 24, "unit": "hours"}`,
+									},
+									{
+										Name:  "48 hours",
+										Value: `{"period": 48, "unit": "hours"}`,
+									},
+									{
+										Name:  "10 days",
+										Value: `{"period": 10, "unit": "days"}`,
+									},
+									{
+										Name:  "30 days",
+										Value: `{"period": 30, "unit": "days"}`,
+									},
+									{
+										Name:  "3 months",
+										Value: `{"period": 3, "unit": "months"}`,
+									},
+									{
+										Name:  "6 months",
+										Value: `{"period": 6, "unit": "months"}`,
+									},
+									{
+										Name:  "9 months",
+										Value: `{"period": 9, "unit": "months"}`,
+									},
+									{
+										Name:  "12 months",
+										Value: `{"period": 12, "unit": "months"}`,
+									},
 								},
-								{
-									Name: fmt.Sprintf(
-										"%d-%s Low",
-										chartOpts.Period,
-										utils.Singularize(chartOpts.Unit),
-									),
-									Value:  p.Sprintf("🪙 **%d** gold", lowestPrice),
-									Inline: true,
-								},
-								{
-									Name: "Next Update",
-									Value: fmt.Sprintf(
-										"In approximately **%d** minute%s",
-										nextUpdateDelta,
-										updateTimePluralStr,
-									),
+							},
+							{
+								Name:        "overlay",
+								Description: "Draw additional series on top of the price history chart",
+								Type:        discordgo.ApplicationCommandOptionString,
+								Choices: []*discordgo.ApplicationCommandOptionChoice{
+									{
+										Name:  "Simple moving average",
+										Value: "sma",
+									},
+									{
+										Name:  "Exponential moving average",
+										Value: "ema",
+									},
+									{
+										Name:  "Rolling min/max band",
+										Value: "band",
+									},
+									{
+										Name:  "Period high and low",
+										Value: "highlow",
+									},
+									{
+										Name:  "All of the above",
+										Value: "all",
+									},
 								},
 							},
-							Image: &discordgo.MessageEmbedImage{
-								URL: fmt.Sprintf(
-									"%s/wow-token/chart/%s/%d?t=%d",
-									config.HTTP.Host,
-									chartOpts.Unit,
-									chartOpts.Period,
-									latestToken.Updated.Time.UnixMilli(),
-								),
+							{
+								Name:        "region",
+								Description: "The region to display the token price for",
+								Type:        discordgo.ApplicationCommandOptionString,
+								Choices:     regionChoices(),
 							},
 						},
 					},
-					Flags: discordgo.MessageFlagsEphemeral,
+					alertCommandGroup,
 				},
-			})
-			if err != nil {
-				log.Printf("Error while sending Discord Interaction Response\n%v\n", err)
-			}
+			},
+			global:  true,
+			handler: handleWowToken,
 		},
+		freeGamesCommand,
+		configCommand,
+		botStatusCommand,
 	}
 )
 
+func handleWowToken(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+) (*discordgo.InteractionResponseData, error) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no subcommand given")
+	}
+
+	switch options[0].Name {
+	case "price":
+		return handleWowTokenPrice(s, i, options[0].Options)
+	case "alert":
+		return handleWowTokenAlert(s, i, options[0].Options)
+	default:
+		return nil, fmt.Errorf(`unknown subcommand "%s"`, options[0].Name)
+	}
+}
+
+func optionsToMap(
+	options []*discordgo.ApplicationCommandInteractionDataOption,
+) map[string]*discordgo.ApplicationCommandInteractionDataOption {
+	optionMap := make(
+		map[string]*discordgo.ApplicationCommandInteractionDataOption,
+		len(options),
+	)
+	for _, opt := range options {
+		optionMap[opt.Name] = opt
+	}
+
+	return optionMap
+}
+
+func handleWowTokenPrice(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+	options []*discordgo.ApplicationCommandInteractionDataOption,
+) (*discordgo.InteractionResponseData, error) {
+	optionMap := optionsToMap(options)
+
+	chartOpts := chartTimePeriod{
+		Unit:   "hours",
+		Period: 48,
+	}
+
+	if option, ok := optionMap["chart"]; ok {
+		if err := json.Unmarshal([]byte(option.StringValue()), &chartOpts); err != nil {
+			return nil, fmt.Errorf("failed to parse chart option: %w", err)
+		}
+	}
+
+	settings := guildSettings(context.Background(), i.GuildID)
+
+	region := guildRegion(settings)
+	if option, ok := optionMap["region"]; ok {
+		region = option.StringValue()
+	}
+
+	if !blizzardClient.HasRegion(region) {
+		return nil, userErrorf(
+			"WoW token prices are not being tracked for the %s region",
+			blizzard.RegionName(region),
+		)
+	}
+
+	var overlays blizzard.ChartOverlays
+	if option, ok := optionMap["overlay"]; ok {
+		window := blizzard.DefaultOverlayWindow(chartOpts.Unit)
+		overlay := option.StringValue()
+
+		if overlay == "sma" || overlay == "all" {
+			overlays.SMA = window
+		}
+
+		if overlay == "ema" || overlay == "all" {
+			overlays.EMA = window
+		}
+
+		if overlay == "band" || overlay == "all" {
+			overlays.Band = window
+		}
+
+		overlays.HighLow = overlay == "highlow" || overlay == "all"
+	}
+
+	chartQuery := overlays.Query()
+
+	p := guildPrinter(settings)
+
+	var t time.Time
+	switch chartOpts.Unit {
+	case "hours":
+		t = time.Now().UTC().Add(time.Hour * time.Duration(chartOpts.Period) * -1)
+	case "days":
+		t = time.Now().UTC().AddDate(0, 0, chartOpts.Period*-1)
+	case "months":
+		t = time.Now().UTC().AddDate(0, chartOpts.Period*-1, 0)
+	default:
+		return nil, fmt.Errorf(
+			`invalid unit "%s" given, must be one of "hours", "days", or "months"`,
+			chartOpts.Unit,
+		)
+	}
+
+	// Fetch a new token price if available. Failing to do so is not fatal as long as there is
+	// price history to show, and the fetched price is shown even without any history, or when it
+	// couldn't be stored.
+	livePrice, fetchErr := blizzardClient.FetchTokenPrice(region)
+	fetched := fetchErr == nil || errors.Is(fetchErr, blizzard.ErrTokenPriceNotStored)
+	if fetchErr != nil {
+		log.Printf(
+			"Failed to fetch the current %s token price\n%v",
+			blizzard.RegionName(region),
+			fetchErr,
+		)
+	}
+
+	tokenHistory, historyErr := blizzardClient.GetTokenPriceHistory(region, t, time.Now().UTC())
+	if historyErr != nil {
+		log.Printf(
+			"Failed to get %s token price history\n%v",
+			blizzard.RegionName(region),
+			historyErr,
+		)
+	}
+
+	if fetched {
+		isNewer := len(tokenHistory) == 0 ||
+			livePrice.Updated.After(tokenHistory[len(tokenHistory)-1].Updated)
+		if isNewer {
+			tokenHistory = append(tokenHistory, livePrice)
+		}
+	}
+
+	stats, ok := blizzard.ComputeTokenPriceStats(tokenHistory)
+	if !ok {
+		if err := errors.Join(fetchErr, historyErr); err != nil {
+			return nil, fmt.Errorf("no token price available: %w", err)
+		}
+
+		return nil, userErrorf(
+			"No %s token price history is available yet",
+			blizzard.RegionName(region),
+		)
+	}
+
+	latestToken := stats.Current
+	chartQuery.Set("t", strconv.FormatInt(latestToken.Updated.UnixMilli(), 10))
+	periodName := fmt.Sprintf("%d-%s", chartOpts.Period, utils.Singularize(chartOpts.Unit))
+
+	timeSinceLastUpdate := int64(time.Now().UTC().Sub(latestToken.Updated).Minutes())
+	nextUpdateDelta := blizzard.WowTokenGracePeriod - timeSinceLastUpdate
+
+	updateTimePluralStr := ""
+	if nextUpdateDelta > 1 {
+		updateTimePluralStr = "s"
+	} else {
+		nextUpdateDelta = 1
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf(
+			"World of Warcraft Token Price (%s)",
+			blizzard.RegionName(region),
+		),
+	}
+
+	// Statistics and the chart need at least two prices to be meaningful
+	if stats.Samples < 2 {
+		embed.Description = "Not enough price history for a chart yet"
+		embed.Fields = []*discordgo.MessageEmbedField{
+			{
+				Name:  "Current Price",
+				Value: p.Sprintf("🪙 **%d** gold", latestToken.Price),
+			},
+		}
+	} else {
+		embed.Fields = []*discordgo.MessageEmbedField{
+			{
+				Name: "Current Price",
+				Value: p.Sprintf(
+					"🪙 **%d** gold\n%s since the previous update",
+					latestToken.Price,
+					formatPriceChange(p, stats.LastChange),
+				),
+			},
+			{
+				Name: periodName + " High",
+				Value: p.Sprintf(
+					"🪙 **%d** gold\n<t:%d:f>",
+					stats.High.Price,
+					stats.High.Updated.Unix(),
+				),
+				Inline: true,
+			},
+			{
+				Name: periodName + " Low",
+				Value: p.Sprintf(
+					"🪙 **%d** gold\n<t:%d:f>",
+					stats.Low.Price,
+					stats.Low.Updated.Unix(),
+				),
+				Inline: true,
+			},
+			{
+				Name: periodName + " Change",
+				Value: p.Sprintf(
+					"%s\n(%+.2f%%)",
+					formatPriceChange(p, stats.Change),
+					stats.ChangePercent,
+				),
+				Inline: true,
+			},
+			{
+				Name:   periodName + " Average",
+				Value:  p.Sprintf("🪙 **%d** gold", int64(math.Round(stats.Average))),
+				Inline: true,
+			},
+			{
+				Name:   periodName + " Median",
+				Value:  p.Sprintf("🪙 **%d** gold", int64(math.Round(stats.Median))),
+				Inline: true,
+			},
+		}
+		embed.Image = &discordgo.MessageEmbedImage{
+			URL: fmt.Sprintf(
+				"%s/wow-token/chart/%s/%s/%d?%s",
+				config.HTTP.Host,
+				region,
+				chartOpts.Unit,
+				chartOpts.Period,
+				chartQuery.Encode(),
+			),
+		}
+	}
+
+	embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+		Name: "Next Update",
+		Value: fmt.Sprintf(
+			"In approximately **%d** minute%s",
+			nextUpdateDelta,
+			updateTimePluralStr,
+		),
+	})
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		Flags:  discordgo.MessageFlagsEphemeral,
+	}, nil
+}
+
+// formatPriceChange formats a change in gold with its direction, such as "📈 **+1,234** gold"
+func formatPriceChange(p *message.Printer, change int64) string {
+	switch {
+	case change > 0:
+		return p.Sprintf("📈 **+%d** gold", change)
+	case change < 0:
+		return p.Sprintf("📉 **%d** gold", change)
+	default:
+		return "No change"
+	}
+}
+
+func regionChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(blizzard.Regions))
+	for _, region := range blizzard.Regions {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  blizzard.RegionName(region),
+			Value: region,
+		})
+	}
+
+	return choices
+}
+
+// credentialsPath returns the directory config & secrets are loaded from by default
+func credentialsPath(getenv func(string) string, workdir string) string {
+	// Use systemd credentials to load config & secrets if available
+	if basePath := getenv("CREDENTIALS_DIRECTORY"); basePath != "" {
+		return basePath
+	}
+
+	// Otherwise check the working directory instead
+	return workdir
+}
+
 func Run(
 	ctx context.Context,
 	getenv func(string) string,
@@ -242,13 +438,7 @@ func Run(
 	ctx, cancel := signal.NotifyContext(ctx, os.Interrupt)
 	defer cancel()
 
-	// Use systemd credentials to load config & secrets if available
-	basePath := getenv("CREDENTIALS_DIRECTORY")
-
-	if basePath == "" {
-		// Otherwise check the working directory instead
-		basePath = workdir
-	}
+	basePath := credentialsPath(getenv, workdir)
 
 	defaultConfigFilePath := basePath + "/config.json"
 	defaultSecretsFilePath := basePath + "/secrets.json"
@@ -266,6 +456,11 @@ func Run(
 		"Path to the secrets file (JSON format)",
 	)
 	dbUrl := flag.String("database-url", "", "Supply a database url")
+	migrateOnly := flag.Bool(
+		"migrate-only",
+		false,
+		"Apply database migrations and exit without starting the bot",
+	)
 
 	flag.Parse()
 
@@ -276,13 +471,22 @@ func Run(
 		dbUrl = &secrets.Database.ConnectionString
 	}
 
-	pool, err := pgxpool.New(ctx, *dbUrl)
+	store, err := storage.Open(ctx, *dbUrl)
 	if err != nil {
 		return err
 	}
-	defer pool.Close()
+	defer store.Close()
 
-	db = database.New(pool)
+	if err := store.Migrate(ctx); err != nil {
+		return err
+	}
+
+	if *migrateOnly {
+		log.Println("Migrations applied, exiting.")
+		return nil
+	}
+
+	db = store
 
 	DiscordSession, err := discordgo.New("Bot " + secrets.Discord.Token)
 	if err != nil {
@@ -293,76 +497,102 @@ func Run(
 		log.Printf("Logged in as: %v#%v", s.State.User.Username, s.State.User.Discriminator)
 	})
 
-	DiscordSession.AddHandler(func(s *discordgo.Session, i *discordgo.InteractionCreate) {
-		if h, ok := commandHandlers[i.ApplicationCommandData().Name]; ok {
-			h(s, i)
+	routes := make(map[string]http.Handler)
+
+	if config.Discord.Interactions == appconfig.InteractionsHTTP {
+		// Interactions are posted to the webserver by Discord, so no gateway connection is needed
+		handler, err := interactionsHandler(DiscordSession, secrets.Discord.PublicKey)
+		if err != nil {
+			return fmt.Errorf("cannot receive interactions over HTTP: %w", err)
 		}
-	})
 
-	err = DiscordSession.Open()
-	if err != nil {
-		return fmt.Errorf("cannot open the session: %w", err)
-	}
+		routes["POST /interactions"] = handler
+	} else {
+		DiscordSession.AddHandler(dispatchInteraction)
 
-	log.Println("Adding commands...")
-	registeredCommands := make([]*discordgo.ApplicationCommand, len(commands))
-	for i, v := range commands {
-		cmd, err := DiscordSession.ApplicationCommandCreate(
-			DiscordSession.State.User.ID,
-			secrets.Discord.GuildID,
-			v,
-		)
+		err = DiscordSession.Open()
 		if err != nil {
-			log.Panicf("Cannot create '%v' command: %v", v.Name, err)
+			return fmt.Errorf("cannot open the session: %w", err)
 		}
-		registeredCommands[i] = cmd
+
+		defer DiscordSession.Close()
 	}
 
-	defer DiscordSession.Close()
+	log.Println("Syncing commands...")
+	err = syncCommands(DiscordSession, secrets.Discord.ClientID, secrets.Discord.GuildID)
+	if err != nil {
+		return err
+	}
 
-	// Initialize Epic Games Store API client
-	egsClient = egs.New(config, db)
+	// Initialize the free games client, which fetches from every enabled store
+	freeGamesClient = egs.New(config, db)
 	// Initialize Blizzard API client
 	blizzardClient = blizzard.New(ctx, config, secrets, db)
+	blizzardClient.OnAlert(func(alert database.WowTokenAlert, stats blizzard.TokenPriceStats) {
+		notifyTokenAlert(DiscordSession, alert, stats)
+	})
 
-	err = webserver.Run(ctx, blizzardClient, config)
-	if err != nil {
+	jobs = scheduler.New(db)
+	if err := registerJobs(jobs, DiscordSession); err != nil {
 		return err
 	}
 
-	timerCtx, cancelTimers := context.WithCancel(ctx)
+	err = webserver.Run(ctx, blizzardClient, jobs, config, routes)
+	if err != nil {
+		return err
+	}
 
-	blizzardClient.StartWowTokenFetchInterval(timerCtx, 5*time.Minute)
-	log.Println("WoW Token Timer: Starting timer with interval of 5 Minutes")
+	jobsCtx, cancelJobs := context.WithCancel(ctx)
 
-	egsClient.StartFreeGamesFetchInterval(
-		timerCtx,
-		DiscordSession,
-		secrets.Channels.Deals,
-		1*time.Hour,
-	)
-	log.Println("EGS Free Games: Starting timer with interval of 1 Hour")
+	// Every instance serves the webserver routes and interactions, but only the leader runs the
+	// background jobs, so instances sharing a database do not post everything more than once
+	waitForJobs := leader.Run(jobsCtx, store, 15*time.Second, jobs.Start)
 
-	defer cancelTimers()
+	// The jobs stop and the leadership is released before the store and session are closed
+	defer func() {
+		cancelJobs()
+		waitForJobs()
+	}()
 
 	stop := make(chan os.Signal, 1)
 	signal.Notify(stop, os.Interrupt, syscall.SIGINT, syscall.SIGTERM)
 	<-stop
 
-	log.Println("Removing commands...")
+	log.Println("Gracefully shutting down.")
+
+	return nil
+}
 
-	for _, v := range registeredCommands {
-		err := DiscordSession.ApplicationCommandDelete(
-			DiscordSession.State.User.ID,
-			secrets.Discord.GuildID,
-			v.ID,
-		)
+// registerJobs registers the background jobs with the schedules from the config
+func registerJobs(jobs *scheduler.Scheduler, discord *discordgo.Session) error {
+	runs := []struct {
+		name string
+		run  func(ctx context.Context) error
+	}{
+		{appconfig.JobWowTokenPrices, func(context.Context) error {
+			return blizzardClient.FetchAllTokenPrices()
+		}},
+		{appconfig.JobFreeGames, func(ctx context.Context) error {
+			return freeGamesClient.AnnounceNewFreeGames(ctx, discord, dealsChannels)
+		}},
+		{appconfig.JobFreeGameReminders, func(ctx context.Context) error {
+			return freeGamesClient.SendReminders(ctx, discord, dealsChannels)
+		}},
+		{appconfig.JobFreeGameExpiry, func(ctx context.Context) error {
+			return freeGamesClient.ExpireAnnouncements(ctx, discord)
+		}},
+		{appconfig.JobFreeGameOutbox, func(ctx context.Context) error {
+			return freeGamesClient.SendOutbox(ctx, discord)
+		}},
+	}
+
+	for _, job := range runs {
+		schedule := config.Jobs[job.name]
+		err := jobs.Register(job.name, schedule.Schedule, schedule.ShouldRunOnStart(), job.run)
 		if err != nil {
-			log.Panicf("Cannot delete '%v' command: %v", v.Name, err)
+			return err
 		}
 	}
 
-	log.Println("Gracefully shutting down.")
-
 	return nil
 }
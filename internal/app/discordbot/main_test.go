@@ -0,0 +1,233 @@
+package discordbot
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/app/blizzard"
+	appconfig "github.com/aloop/discord-bot/internal/pkg/config"
+	appsecrets "github.com/aloop/discord-bot/internal/pkg/secrets"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+// setupWowTokenTest points the package globals at a fake database and Blizzard API
+func setupWowTokenTest(t *testing.T, fake *fakes.DB, api *fakes.BlizzardAPI) {
+	t.Helper()
+
+	prevConfig, prevSecrets, prevDB, prevClient := config, secrets, db, blizzardClient
+	t.Cleanup(func() {
+		config, secrets, db, blizzardClient = prevConfig, prevSecrets, prevDB, prevClient
+	})
+
+	config = &appconfig.Config{
+		HTTP: appconfig.HTTPConfig{
+			Host: "https://bot.example.com",
+		},
+		Blizzard: appconfig.BlizzardConfig{
+			AuthTokenUrl:      api.AuthTokenURL(),
+			TokenPriceUrls:    api.TokenPriceURLs("us"),
+			Regions:           []string{"us"},
+			AlertRearmPercent: 2,
+		},
+	}
+	secrets = &appsecrets.Secrets{
+		Blizzard: appsecrets.BlizzardSecrets{
+			ClientID:     api.ClientID,
+			ClientSecret: api.ClientSecret,
+		},
+	}
+	db = storage.NewPostgres(fake)
+	blizzardClient = blizzard.New(context.Background(), config, secrets, db)
+}
+
+func wowTokenPriceInteraction(
+	options ...*discordgo.ApplicationCommandInteractionDataOption,
+) *discordgo.InteractionCreate {
+	return &discordgo.InteractionCreate{
+		Interaction: &discordgo.Interaction{
+			Type: discordgo.InteractionApplicationCommand,
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "wowtoken",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{
+						Name:    "price",
+						Type:    discordgo.ApplicationCommandOptionSubCommand,
+						Options: options,
+					},
+				},
+			},
+		},
+	}
+}
+
+func timestamptz(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+func embedField(embed *discordgo.MessageEmbed, name string) *discordgo.MessageEmbedField {
+	for _, field := range embed.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+
+	return nil
+}
+
+func TestWowTokenPriceWithoutHistory(t *testing.T) {
+	fake := fakes.NewDB()
+	fake.SetRows("AddTokenPrice", database.WowTokenPrice{
+		ID:      1,
+		Updated: timestamptz(time.Now()),
+		Price:   250_000,
+		Region:  "us",
+	})
+
+	api := fakes.NewBlizzardAPI(t)
+	api.SetPrice("us", 250_000, time.Now())
+	setupWowTokenTest(t, fake, api)
+
+	data, err := handleWowToken(nil, wowTokenPriceInteraction())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	embed := data.Embeds[0]
+	if embed.Image != nil {
+		t.Errorf("expected no chart, got %s", embed.Image.URL)
+	}
+
+	if !strings.Contains(embed.Description, "Not enough price history") {
+		t.Errorf("expected a note about missing history, got %q", embed.Description)
+	}
+
+	current := embedField(embed, "Current Price")
+	if current == nil || !strings.Contains(current.Value, "250,000") {
+		t.Errorf("expected the fetched price to be shown, got %+v", current)
+	}
+
+	if n := len(fake.Calls("AddTokenPrice")); n != 1 {
+		t.Errorf("expected the fetched price to be stored once, stored %d times", n)
+	}
+}
+
+func TestWowTokenPriceWithoutHistoryOrAPI(t *testing.T) {
+	api := fakes.NewBlizzardAPI(t)
+	api.QueuePriceResponses(fakes.Response{Status: http.StatusServiceUnavailable})
+	setupWowTokenTest(t, fakes.NewDB(), api)
+
+	data, err := handleWowToken(nil, wowTokenPriceInteraction())
+	if err == nil {
+		t.Fatalf("expected an error, got %+v", data)
+	}
+
+	var userErr *userError
+	if errors.As(err, &userErr) {
+		t.Errorf("expected an internal error, got user error %q", userErr.message)
+	}
+}
+
+func TestWowTokenPriceHistoryUnavailable(t *testing.T) {
+	fake := fakes.NewDB()
+	fake.SetError("AddTokenPrice", errors.New("connection refused"))
+	fake.SetError("GetTokenPricesBetween", errors.New("connection refused"))
+
+	api := fakes.NewBlizzardAPI(t)
+	api.SetPrice("us", 300_000, time.Now())
+	setupWowTokenTest(t, fake, api)
+
+	data, err := handleWowToken(nil, wowTokenPriceInteraction())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	embed := data.Embeds[0]
+	if embed.Image != nil {
+		t.Errorf("expected no chart, got %s", embed.Image.URL)
+	}
+
+	current := embedField(embed, "Current Price")
+	if current == nil || !strings.Contains(current.Value, "300,000") {
+		t.Errorf("expected the fetched price to be shown, got %+v", current)
+	}
+}
+
+func TestWowTokenPriceWithHistory(t *testing.T) {
+	now := time.Now().UTC()
+
+	fake := fakes.NewDB()
+	fake.SetRows(
+		"GetLatestTokenPrice",
+		database.WowTokenPrice{
+			ID:      3,
+			Updated: timestamptz(now.Add(-time.Minute)),
+			Price:   260_000,
+			Region:  "us",
+		},
+	)
+	fake.SetRows(
+		"GetTokenPricesBetween",
+		database.GetTokenPricesBetweenRow{Price: 240_000, Updated: timestamptz(now.Add(-2 * time.Hour))},
+		database.GetTokenPricesBetweenRow{Price: 250_000, Updated: timestamptz(now.Add(-time.Hour))},
+		database.GetTokenPricesBetweenRow{Price: 260_000, Updated: timestamptz(now.Add(-time.Minute))},
+	)
+
+	api := fakes.NewBlizzardAPI(t)
+	setupWowTokenTest(t, fake, api)
+
+	data, err := handleWowToken(nil, wowTokenPriceInteraction(
+		&discordgo.ApplicationCommandInteractionDataOption{
+			Name:  "chart",
+			Type:  discordgo.ApplicationCommandOptionString,
+			Value: `{"period": 10, "unit": "days"}`,
+		},
+	))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if n := api.PriceRequests(); n != 0 {
+		t.Errorf("expected the recent stored price to be used, the API was called %d times", n)
+	}
+
+	embed := data.Embeds[0]
+	if embed.Image == nil {
+		t.Fatal("expected a chart")
+	}
+
+	want := "https://bot.example.com/wow-token/chart/us/days/10?"
+	if !strings.HasPrefix(embed.Image.URL, want) {
+		t.Errorf("expected chart URL to start with %s, got %s", want, embed.Image.URL)
+	}
+
+	change := embedField(embed, "10-day Change")
+	if change == nil || !strings.Contains(change.Value, "+20,000") {
+		t.Errorf("expected a change of +20,000 gold, got %+v", change)
+	}
+}
+
+func TestWowTokenPriceUntrackedRegion(t *testing.T) {
+	setupWowTokenTest(t, fakes.NewDB(), fakes.NewBlizzardAPI(t))
+
+	_, err := handleWowToken(nil, wowTokenPriceInteraction(
+		&discordgo.ApplicationCommandInteractionDataOption{
+			Name:  "region",
+			Type:  discordgo.ApplicationCommandOptionString,
+			Value: "eu",
+		},
+	))
+
+	var userErr *userError
+	if !errors.As(err, &userErr) {
+		t.Fatalf("expected a user error, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package discordbot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	// Discord requires a response to interactions within 3 seconds, so any handler taking longer
+	// than this is deferred and its response sent through the interaction webhook instead
+	interactionDeferAfter = 2500 * time.Millisecond
+
+	errorEmbedColor = 0xed4245
+)
+
+// commandHandler handles a slash command. The returned data is sent as the response, unless
+// an error is returned, in which case the user is shown an error message instead.
+type commandHandler func(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+) (*discordgo.InteractionResponseData, error)
+
+// userError is an error caused by the user's input, its message is shown to them as is
+type userError struct {
+	message string
+}
+
+func (e *userError) Error() string {
+	return e.message
+}
+
+func userErrorf(format string, a ...any) error {
+	return &userError{message: fmt.Sprintf(format, a...)}
+}
+
+// requirePermission returns a user error naming the permission unless the member running the
+// command has it. Discord hides commands from members without their default permissions, but
+// server admins can override that, so handlers check it as well.
+func requirePermission(i *discordgo.InteractionCreate, permission int64, name string) error {
+	if i.Member == nil || i.Member.Permissions&permission == 0 {
+		return userErrorf("You need the %s permission to use this command", name)
+	}
+
+	return nil
+}
+
+func ephemeralMessage(content string) *discordgo.InteractionResponseData {
+	return &discordgo.InteractionResponseData{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	}
+}
+
+func errorResponse(err error) *discordgo.InteractionResponseData {
+	description := "Something went wrong while running this command, please try again later"
+
+	var userErr *userError
+	if errors.As(err, &userErr) {
+		description = userErr.message
+	}
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{
+			{
+				Title:       "Sorry, that didn't work",
+				Description: description,
+				Color:       errorEmbedColor,
+			},
+		},
+		Flags: discordgo.MessageFlagsEphemeral,
+	}
+}
+
+// runCommand runs the handler of a command and sends its response. Handlers that take too long
+// are deferred, which shows the user a loading state until the response is ready.
+func runCommand(s *discordgo.Session, i *discordgo.InteractionCreate, cmd *botCommand) {
+	name := cmd.definition.Name
+	results := make(chan *discordgo.InteractionResponseData, 1)
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				log.Printf("Commands: /%s panicked\n%v\n%s", name, r, debug.Stack())
+				results <- errorResponse(fmt.Errorf("panic: %v", r))
+			}
+		}()
+
+		data, err := cmd.handler(s, i)
+		if err != nil {
+			var userErr *userError
+			if !errors.As(err, &userErr) {
+				log.Printf("Commands: /%s failed\n%v", name, err)
+			}
+
+			data = errorResponse(err)
+		}
+
+		results <- data
+	}()
+
+	var data *discordgo.InteractionResponseData
+
+	select {
+	case data = <-results:
+		err := respond(s, i, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		})
+		if err != nil {
+			log.Printf("Error while sending Discord Interaction Response\n%v\n", err)
+		}
+		return
+	case <-time.After(interactionDeferAfter):
+	}
+
+	// The visibility of the response is decided when deferring, and every response is ephemeral
+	err := respond(s, i, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+	if err != nil {
+		log.Printf("Error while deferring Discord Interaction Response\n%v\n", err)
+		return
+	}
+
+	data = <-results
+
+	_, err = s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{
+		Content: &data.Content,
+		Embeds:  &data.Embeds,
+	})
+	if err != nil {
+		log.Printf("Error while sending deferred Discord Interaction Response\n%v\n", err)
+	}
+}
@@ -0,0 +1,98 @@
+package discordbot
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/aloop/discord-bot/internal/pkg/scheduler"
+)
+
+const (
+	jobsHealthyEmbedColor = 0x57f287
+	// Discord rejects embed field values longer than this
+	maxEmbedFieldLength = 1024
+)
+
+var (
+	administratorPermission int64 = discordgo.PermissionAdministrator
+
+	botStatusCommand = &botCommand{
+		definition: &discordgo.ApplicationCommand{
+			Name:                     "botstatus",
+			Description:              "Shows the status of the background jobs of the bot",
+			DefaultMemberPermissions: &administratorPermission,
+			DMPermission:             &configInDMs,
+		},
+		handler: handleBotStatus,
+	}
+)
+
+func handleBotStatus(
+	s *discordgo.Session,
+	i *discordgo.InteractionCreate,
+) (*discordgo.InteractionResponseData, error) {
+	// The command can still be registered elsewhere from before it was limited to the guild
+	if i.GuildID == "" || i.GuildID != secrets.Discord.GuildID {
+		return nil, userErrorf("The bot status can only be seen from the server of the bot owner")
+	}
+
+	if err := requirePermission(i, discordgo.PermissionAdministrator, "Administrator"); err != nil {
+		return nil, err
+	}
+
+	statuses, err := jobs.Status(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	return botStatusMessage(statuses), nil
+}
+
+func botStatusMessage(statuses []scheduler.JobStatus) *discordgo.InteractionResponseData {
+	embed := &discordgo.MessageEmbed{
+		Title:  "Background jobs",
+		Color:  jobsHealthyEmbedColor,
+		Fields: make([]*discordgo.MessageEmbedField, 0, len(statuses)),
+	}
+
+	for _, status := range statuses {
+		lines := []string{fmt.Sprintf("Schedule: `%s`", status.Schedule)}
+
+		if run := status.LastRun; run == nil {
+			lines = append(lines, "Last run: Never")
+		} else {
+			duration := time.Duration(run.DurationMs) * time.Millisecond
+			lines = append(lines, fmt.Sprintf(
+				"Last run: <t:%d:R>, took %s",
+				run.Started.Unix(),
+				duration,
+			))
+
+			if run.Error != "" {
+				embed.Color = errorEmbedColor
+				lines = append(lines, fmt.Sprintf("Failed: %s", run.Error))
+			}
+		}
+
+		lines = append(lines, fmt.Sprintf("Next run: <t:%d:R>", status.NextRun.Unix()))
+
+		value := strings.Join(lines, "\n")
+		if len(value) > maxEmbedFieldLength {
+			value = strings.ToValidUTF8(value[:maxEmbedFieldLength-3], "") + "..."
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  status.Name,
+			Value: value,
+		})
+	}
+
+	return &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
+		Flags:  discordgo.MessageFlagsEphemeral,
+	}
+}
@@ -0,0 +1,128 @@
+package discordbot
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+
+	"github.com/aloop/discord-bot/internal/pkg/scheduler"
+	appsecrets "github.com/aloop/discord-bot/internal/pkg/secrets"
+)
+
+func TestHandleBotStatusRequiresAdministrator(t *testing.T) {
+	prevSecrets := secrets
+	t.Cleanup(func() { secrets = prevSecrets })
+
+	tests := []struct {
+		name         string
+		secretsGuild string
+		guild        string
+		permissions  int64
+	}{
+		{"not an administrator", "guild", "guild", discordgo.PermissionManageServer},
+		{"another guild", "guild", "other", discordgo.PermissionAdministrator},
+		{"without a secrets guild", "", "other", discordgo.PermissionAdministrator},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secrets = &appsecrets.Secrets{
+				Discord: appsecrets.DiscordSecrets{GuildID: tt.secretsGuild},
+			}
+
+			_, err := handleBotStatus(nil, &discordgo.InteractionCreate{
+				Interaction: &discordgo.Interaction{
+					GuildID: tt.guild,
+					Member:  &discordgo.Member{Permissions: tt.permissions},
+				},
+			})
+
+			var userErr *userError
+			if !errors.As(err, &userErr) {
+				t.Fatalf("expected a user error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCommandScopes(t *testing.T) {
+	names := func(cmds []*discordgo.ApplicationCommand) string {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, cmd.Name)
+		}
+
+		return strings.Join(names, ",")
+	}
+
+	tests := []struct {
+		name        string
+		guildID     string
+		expectGuild string
+	}{
+		{"with a guild", "guild", "botstatus"},
+		{"without a guild", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global, guild := commandScopes(tt.guildID)
+
+			if got := names(global); got != "wowtoken,freegames,config" {
+				t.Errorf("expected the public commands to be global, got %s", got)
+			}
+
+			if got := names(guild); got != tt.expectGuild {
+				t.Errorf("expected guild commands %q, got %q", tt.expectGuild, got)
+			}
+		})
+	}
+}
+
+func TestBotStatusMessage(t *testing.T) {
+	now := time.Now()
+
+	data := botStatusMessage([]scheduler.JobStatus{
+		{
+			Name:     "freeGames",
+			Schedule: "@every 1h",
+			NextRun:  now.Add(time.Hour),
+			LastRun:  &scheduler.JobRun{Started: now, DurationMs: 1500},
+		},
+		{
+			Name:     "wowTokenPrices",
+			Schedule: "*/5 * * * *",
+			NextRun:  now.Add(5 * time.Minute),
+			LastRun: &scheduler.JobRun{
+				Started: now,
+				Error:   strings.Repeat("token api is down ", 100),
+			},
+		},
+		{Name: "freeGameOutbox", Schedule: "@every 1m", NextRun: now.Add(time.Minute)},
+	})
+
+	embed := data.Embeds[0]
+	if embed.Color != errorEmbedColor {
+		t.Errorf("expected a failing job to color the status red, got %x", embed.Color)
+	}
+
+	if len(embed.Fields) != 3 || !strings.Contains(embed.Fields[0].Value, "took 1.5s") {
+		t.Fatalf("expected a field for each job, got %+v", embed.Fields)
+	}
+
+	if failed := embed.Fields[1].Value; len(failed) > maxEmbedFieldLength ||
+		!strings.Contains(failed, "Failed: token api is down") {
+		t.Errorf("expected the shortened error of the failed job, got %q", failed)
+	}
+
+	if !strings.Contains(embed.Fields[2].Value, "Last run: Never") {
+		t.Errorf("expected the job that never ran to say so, got %q", embed.Fields[2].Value)
+	}
+
+	if data.Flags != discordgo.MessageFlagsEphemeral {
+		t.Error("expected the status to only be shown to the admin")
+	}
+}
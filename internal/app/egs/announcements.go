@@ -0,0 +1,252 @@
+package egs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+const (
+	// Discord allows at most 10 embeds per message
+	MaxEmbedsPerMessage = 10
+	expiredEmbedColor   = 0x747f8d
+)
+
+// Discord is the part of the Discord session used to post and update free game announcements
+type Discord interface {
+	ChannelMessage(
+		channelID, messageID string,
+		options ...discordgo.RequestOption,
+	) (*discordgo.Message, error)
+	ChannelMessageSendComplex(
+		channelID string,
+		data *discordgo.MessageSend,
+		options ...discordgo.RequestOption,
+	) (*discordgo.Message, error)
+	ChannelMessageEditEmbeds(
+		channelID, messageID string,
+		embeds []*discordgo.MessageEmbed,
+		options ...discordgo.RequestOption,
+	) (*discordgo.Message, error)
+}
+
+// Channel is a channel free games are announced in
+type Channel struct {
+	ID string
+	// Role pinged by reminders, none when empty
+	ReminderRole string
+}
+
+// ChannelLister returns every channel free games should be announced in
+type ChannelLister func(ctx context.Context) ([]Channel, error)
+
+// AnnounceNewFreeGames queues the free games found since the last fetch for announcement in
+// every channel, then posts them
+func (egs *FreeGamesClient) AnnounceNewFreeGames(
+	ctx context.Context,
+	discord Discord,
+	channels ChannelLister,
+) error {
+	// The games are queued in the transaction that stores them, so the channels are needed
+	// first. Without them the games are left for the next fetch to find.
+	targets, err := channels(ctx)
+	if err != nil {
+		return fmt.Errorf(
+			"Free Games: Failed to get the channels to announce free games in:\n%w",
+			err,
+		)
+	}
+
+	_, _, fetchErr := egs.fetchNewFreeGames(ctx, targets)
+
+	return errors.Join(fetchErr, egs.SendOutbox(ctx, discord))
+}
+
+// sendFreeGames posts an embed for each game, split across as many messages as Discord needs.
+// It returns the message each game was posted in, which is nil for games that failed to post.
+func sendFreeGames(
+	discord Discord,
+	channel string,
+	message discordgo.MessageSend,
+	games FreeGames,
+) []*discordgo.Message {
+	posted := make([]*discordgo.Message, len(games))
+
+	for start := 0; start < len(games); start += MaxEmbedsPerMessage {
+		end := min(start+MaxEmbedsPerMessage, len(games))
+		message.Embeds = CreateDiscordMessageEmbeds(games[start:end])
+
+		sent, err := discord.ChannelMessageSendComplex(channel, &message)
+		if err != nil {
+			log.Printf("Free Games: Failed to send free games message\n%v", err)
+			continue
+		}
+
+		for i := start; i < end; i++ {
+			posted[i] = sent
+		}
+	}
+
+	return posted
+}
+
+// SendReminders posts a reminder for the games whose promotion ends within the configured
+// number of hours in every channel, pinging the channel's reminder role. Games are marked as
+// reminded in the same transaction that queues their reminders in the outbox, so a game is
+// reminded about once, and reminders that fail to post are retried. Nothing is posted when
+// reminders are disabled.
+func (egs *FreeGamesClient) SendReminders(
+	ctx context.Context,
+	discord Discord,
+	channels ChannelLister,
+) error {
+	if egs.config.FreeGames.ReminderHours <= 0 {
+		return nil
+	}
+
+	remindBefore := time.Duration(egs.config.FreeGames.ReminderHours) * time.Hour
+
+	rows, err := egs.db.GetFreeGamesEndingBefore(ctx, pgtype.Timestamptz{
+		Time:  time.Now().Add(remindBefore),
+		Valid: true,
+	})
+	if err != nil {
+		return fmt.Errorf(
+			"Free Games: Failed to get free games to remind about from DB:\n%w",
+			err,
+		)
+	}
+
+	if len(rows) == 0 {
+		return nil
+	}
+
+	targets, err := channels(ctx)
+	if err != nil {
+		return fmt.Errorf(
+			"Free Games: Failed to get the channels to send reminders in:\n%w",
+			err,
+		)
+	}
+
+	err = egs.db.InTx(ctx, func(tx storage.Store) error {
+		for _, row := range rows {
+			marked, err := tx.MarkFreeGameReminded(ctx, row.ID)
+			if err != nil {
+				return fmt.Errorf(
+					"Free Games: Failed to mark free game %d as reminded:\n%w",
+					row.ID,
+					err,
+				)
+			}
+
+			// Another instance reminded about the game since it was read
+			if marked == 0 {
+				continue
+			}
+
+			game := FreeGames{freeGameFromRow(row)}
+			if err := queueAnnouncements(ctx, tx, targets, game, outboxReminder); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return egs.SendOutbox(ctx, discord)
+}
+
+// ExpireAnnouncements edits the announcements of games whose promotion has ended to show that
+// they are no longer free
+func (egs *FreeGamesClient) ExpireAnnouncements(ctx context.Context, discord Discord) error {
+	rows, err := egs.db.GetExpiredFreeGameAnnouncements(ctx)
+	if err != nil {
+		return fmt.Errorf("Free Games: Failed to get expired announcements from DB:\n%w", err)
+	}
+
+	var errs []error
+	for _, row := range rows {
+		if err := expireAnnouncement(discord, row); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"Free Games: Failed to mark announcement %d as expired:\n%w",
+				row.ID,
+				err,
+			))
+			continue
+		}
+
+		if err := egs.db.MarkFreeGameAnnouncementExpired(ctx, row.ID); err != nil {
+			errs = append(errs, fmt.Errorf(
+				"Free Games: Failed to mark announcement %d as expired:\n%w",
+				row.ID,
+				err,
+			))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// expireAnnouncement updates the embed of the expired game in the announcement. Announcements
+// that were deleted are treated as expired, as there is nothing left to update.
+func expireAnnouncement(
+	discord Discord,
+	announcement database.GetExpiredFreeGameAnnouncementsRow,
+) error {
+	message, err := discord.ChannelMessage(announcement.ChannelID, announcement.MessageID)
+	if isNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, embed := range message.Embeds {
+		// Embeds of open ended giveaways have no end date, so the footer tells whether the
+		// embed was updated already
+		if embed.Title != announcement.Title || embed.Footer != nil {
+			continue
+		}
+
+		for _, field := range embed.Fields {
+			if field.Name == "Free Until" {
+				field.Name = "Was Free Until"
+			}
+		}
+
+		embed.Color = expiredEmbedColor
+		embed.Footer = &discordgo.MessageEmbedFooter{Text: "This giveaway has ended"}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+
+	_, err = discord.ChannelMessageEditEmbeds(announcement.ChannelID, announcement.MessageID, message.Embeds)
+	if isNotFound(err) {
+		return nil
+	}
+
+	return err
+}
+
+func isNotFound(err error) bool {
+	var restErr *discordgo.RESTError
+	return errors.As(err, &restErr) &&
+		restErr.Response != nil &&
+		restErr.Response.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,322 @@
+package egs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+// channels lists the given channels, each pinging a role named after the channel
+func channels(ids ...string) ChannelLister {
+	return func(context.Context) ([]Channel, error) {
+		channels := make([]Channel, 0, len(ids))
+		for _, id := range ids {
+			channels = append(channels, Channel{ID: id, ReminderRole: id + "-role"})
+		}
+
+		return channels, nil
+	}
+}
+
+func TestAnnounceNewFreeGamesQueuesAnnouncements(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(fakes.EGSGame{ID: "free", Title: "Free Game", Start: start, End: end})
+
+	db := fakes.NewDB()
+	game := storedGame(3, "free", "Free Game", StatusLive, start, end)
+	db.SetRows("AddFreeGame", game)
+	db.SetRows("ClaimFreeGameOutbox", make([]any, 1)...)
+	db.SetRows("GetPendingFreeGameOutbox",
+		pendingAnnouncement(1, "first", StatusLive, 0, game),
+		pendingAnnouncement(2, "second", StatusLive, 0, game),
+	)
+
+	discord := fakes.NewDiscord()
+	client := newTestClient(db, api)
+	err := client.AnnounceNewFreeGames(context.Background(), discord, channels("first", "second"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	queued := db.Calls("AddFreeGameOutbox")
+	if len(queued) != 2 || queued[0][1] != "first" || queued[1][1] != "second" {
+		t.Fatalf("expected the game to be queued for both channels, got %v", queued)
+	}
+
+	for _, call := range queued {
+		if call[0] != int64(3) || call[2] != StatusLive {
+			t.Errorf("expected game 3 to be queued as live, got %v", call)
+		}
+	}
+
+	// One transaction stores and queues the game, and one marks each announcement as sent
+	if db.Commits() != 3 || db.Rollbacks() != 0 {
+		t.Errorf("expected 3 commits, got %d commits and %d rollbacks", db.Commits(), db.Rollbacks())
+	}
+
+	sent := discord.Sent()
+	if len(sent) != 2 || sent[0].ChannelID != "first" || sent[1].ChannelID != "second" {
+		t.Fatalf("expected the game to be announced in both channels, sent %+v", sent)
+	}
+
+	marked := db.Calls("MarkFreeGameOutboxSent")
+	calls := db.Calls("AddFreeGameAnnouncement")
+	if len(marked) != 2 || len(calls) != 2 {
+		t.Fatalf("expected both announcements to be stored, got %v %v", marked, calls)
+	}
+
+	for i, message := range sent {
+		if marked[i][0] != int64(i+1) || marked[i][1] != message.ID {
+			t.Errorf("expected announcement %d to be sent as %s, got %v", i+1, message.ID, marked[i])
+		}
+
+		if calls[i][0] != int64(3) || calls[i][1] != message.ChannelID || calls[i][2] != message.ID {
+			t.Errorf("expected game 3 to be stored with message %s, got %v", message.ID, calls[i])
+		}
+	}
+}
+
+func TestAnnounceNewFreeGamesWithoutChannels(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(fakes.EGSGame{ID: "free", Title: "Free Game", Start: start, End: end})
+
+	db := fakes.NewDB()
+	db.SetRows("AddFreeGame", storedGame(3, "free", "Free Game", StatusLive, start, end))
+
+	discord := fakes.NewDiscord()
+	newTestClient(db, api).AnnounceNewFreeGames(context.Background(), discord, channels())
+
+	if sent := discord.Sent(); len(sent) != 0 {
+		t.Errorf("expected nothing to be sent, sent %+v", sent)
+	}
+
+	if n := len(db.Calls("AddFreeGame")); n != 1 {
+		t.Errorf("expected the game to be stored regardless, stored %d", n)
+	}
+}
+
+func TestAnnounceNewFreeGamesChannelsFailure(t *testing.T) {
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(fakes.EGSGame{
+		ID:    "free",
+		Title: "Free Game",
+		Start: time.Now().Add(-time.Hour),
+		End:   time.Now().Add(time.Hour),
+	})
+
+	db := fakes.NewDB()
+	failing := func(context.Context) ([]Channel, error) {
+		return nil, errors.New("database is down")
+	}
+
+	client := newTestClient(db, api)
+	err := client.AnnounceNewFreeGames(context.Background(), fakes.NewDiscord(), failing)
+	if err == nil {
+		t.Error("expected the channels error to be returned")
+	}
+
+	if n := len(db.Calls("AddFreeGame")); n != 0 {
+		t.Errorf("expected the game to be left for the next fetch, stored %d", n)
+	}
+}
+
+func TestSendFreeGamesSplitsMessages(t *testing.T) {
+	games := make(FreeGames, 0, 12)
+	for i := range 12 {
+		games = append(games, &FreeGame{Title: fmt.Sprintf("Game %d", i)})
+	}
+
+	discord := fakes.NewDiscord()
+	posted := sendFreeGames(discord, "deals", discordgo.MessageSend{}, games)
+
+	sent := discord.Sent()
+	if len(sent) != 2 || len(sent[0].Embeds) != MaxEmbedsPerMessage || len(sent[1].Embeds) != 2 {
+		t.Fatalf("expected the games to be split across 2 messages, sent %+v", sent)
+	}
+
+	if posted[0] != sent[0] || posted[9] != sent[0] || posted[10] != sent[1] {
+		t.Errorf("expected each game to be matched with the message it was posted in")
+	}
+}
+
+func TestSendReminders(t *testing.T) {
+	now := time.Now()
+	end := now.Add(12 * time.Hour)
+
+	tests := []struct {
+		name   string
+		marked int
+		queued int
+	}{
+		{"reminds every channel", 1, 4},
+		{"already reminded", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := fakes.NewDB()
+			db.SetRows("GetFreeGamesEndingBefore",
+				storedGame(1, "first", "First", StatusLive, now.Add(-time.Hour), end),
+				storedGame(2, "second", "Second", StatusLive, now.Add(-time.Hour), end),
+			)
+			db.SetRows("MarkFreeGameReminded", make([]any, tt.marked)...)
+
+			client := newTestClient(db, fakes.NewEGSAPI(t))
+			client.config.FreeGames.ReminderHours = 24
+
+			discord := fakes.NewDiscord()
+			err := client.SendReminders(context.Background(), discord, channels("first", "second"))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			before := db.Calls("GetFreeGamesEndingBefore")[0][0].(pgtype.Timestamptz).Time
+			if before.Before(now.Add(24*time.Hour)) || before.After(time.Now().Add(24*time.Hour)) {
+				t.Errorf("expected games ending within 24 hours to be selected, got %s", before)
+			}
+
+			if n := len(db.Calls("MarkFreeGameReminded")); n != 2 {
+				t.Errorf("expected both games to be marked as reminded, marked %d", n)
+			}
+
+			if db.Commits() != 1 || db.Rollbacks() != 0 {
+				t.Errorf("expected the reminders to be queued in a single transaction, got %d "+
+					"commits and %d rollbacks", db.Commits(), db.Rollbacks())
+			}
+
+			queued := db.Calls("AddFreeGameOutbox")
+			if len(queued) != tt.queued {
+				t.Fatalf("expected %d reminders to be queued, queued %v", tt.queued, queued)
+			}
+
+			for _, args := range queued {
+				channel, kind, role := args[1], args[2], args[3]
+				if kind != outboxReminder || role != fmt.Sprint(channel, "-role") {
+					t.Errorf("expected a reminder pinging the role of channel %s, got %v",
+						channel, args)
+				}
+			}
+		})
+	}
+}
+
+func TestSendRemindersWithoutChannels(t *testing.T) {
+	now := time.Now()
+
+	db := fakes.NewDB()
+	db.SetRows("GetFreeGamesEndingBefore",
+		storedGame(1, "free", "Free", StatusLive, now.Add(-time.Hour), now.Add(time.Hour)))
+
+	client := newTestClient(db, fakes.NewEGSAPI(t))
+	client.config.FreeGames.ReminderHours = 24
+
+	failing := func(context.Context) ([]Channel, error) {
+		return nil, errors.New("connection refused")
+	}
+	if err := client.SendReminders(context.Background(), fakes.NewDiscord(), failing); err == nil {
+		t.Error("expected the failure to list channels to be reported")
+	}
+
+	if n := len(db.Calls("MarkFreeGameReminded")); n != 0 {
+		t.Errorf("expected the game to be reminded about later, marked %d times", n)
+	}
+}
+
+func TestExpireAnnouncements(t *testing.T) {
+	now := time.Now()
+	start := now.Add(-7 * 24 * time.Hour)
+
+	discord := fakes.NewDiscord()
+	discord.AddMessage(&discordgo.Message{
+		ID:        "announcement",
+		ChannelID: "deals",
+		Embeds: CreateDiscordMessageEmbeds(FreeGames{
+			{Title: "Expired Game", Starts: start, Ends: now.Add(time.Hour)},
+			{Title: "Other Game", Starts: start, Ends: now.Add(time.Hour)},
+		}),
+	})
+
+	db := fakes.NewDB()
+	db.SetRows("GetExpiredFreeGameAnnouncements",
+		database.GetExpiredFreeGameAnnouncementsRow{
+			ID: 1, ChannelID: "deals", MessageID: "announcement", Title: "Expired Game",
+		},
+		database.GetExpiredFreeGameAnnouncementsRow{
+			ID: 2, ChannelID: "deals", MessageID: "deleted", Title: "Deleted Game",
+		},
+	)
+
+	newTestClient(db, fakes.NewEGSAPI(t)).ExpireAnnouncements(context.Background(), discord)
+
+	embeds := discord.Message("announcement").Embeds
+	if embedFieldNamed(embeds[0], "Was Free Until") == nil || embeds[0].Footer == nil {
+		t.Errorf("expected the announcement to show the game has expired, got %+v", embeds[0])
+	}
+
+	if embedFieldNamed(embeds[1], "Free Until") == nil || embeds[1].Footer != nil {
+		t.Errorf("expected other games in the announcement to be left alone, got %+v", embeds[1])
+	}
+
+	calls := db.Calls("MarkFreeGameAnnouncementExpired")
+	if len(calls) != 2 || calls[0][0] != int64(1) || calls[1][0] != int64(2) {
+		t.Errorf("expected both announcements to be marked as expired, got %v", calls)
+	}
+
+	if discord.Edits() != 1 {
+		t.Errorf("expected only the existing announcement to be edited, edited %d", discord.Edits())
+	}
+}
+
+func TestExpireAnnouncementsKeepsFailedGames(t *testing.T) {
+	db := fakes.NewDB()
+	db.SetRows("GetExpiredFreeGameAnnouncements",
+		database.GetExpiredFreeGameAnnouncementsRow{
+			ID: 1, ChannelID: "deals", MessageID: "announcement", Title: "Game",
+		})
+
+	client := newTestClient(db, fakes.NewEGSAPI(t))
+	err := client.ExpireAnnouncements(context.Background(), failingDiscord{fakes.NewDiscord()})
+	if err == nil {
+		t.Error("expected the failed announcement to be reported")
+	}
+
+	if calls := db.Calls("MarkFreeGameAnnouncementExpired"); len(calls) != 0 {
+		t.Errorf("expected the game to be retried later, got %v", calls)
+	}
+}
+
+// failingDiscord fails to fetch any message, as during a Discord outage
+type failingDiscord struct {
+	*fakes.Discord
+}
+
+func (failingDiscord) ChannelMessage(
+	string, string,
+	...discordgo.RequestOption,
+) (*discordgo.Message, error) {
+	return nil, errors.New("service unavailable")
+}
+
+func embedFieldNamed(embed *discordgo.MessageEmbed, name string) *discordgo.MessageEmbedField {
+	for _, field := range embed.Fields {
+		if field.Name == name {
+			return field
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,245 @@
+package egs
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aloop/discord-bot/internal/pkg/config"
+)
+
+type freeGames []*freeGame
+
+type freeGame struct {
+	Title         string      `json:"title"`
+	Description   string      `json:"description"`
+	StoreID       string      `json:"id"`
+	Price         price       `json:"price"`
+	UrlSlug       string      `json:"urlSlug"`
+	ProductSlug   string      `json:"productSlug"`
+	Images        []image     `json:"keyImages"`
+	CatalogNs     catalogs    `json:"catalogNs"`
+	OfferType     string      `json:"offerType"`
+	OfferMappings []pageMap   `json:"offerMappings"`
+	Promotions    promotional `json:"promotions"`
+	startTime     time.Time
+	endTime       time.Time
+}
+
+type catalogs struct {
+	Mappings []pageMap `json:"mappings"`
+}
+
+type pageMap struct {
+	PageSlug string `json:"pageSlug"`
+	PageType string `json:"pageType"`
+}
+
+type promotional struct {
+	PromotionalOffers         []promotions `json:"promotionalOffers"`
+	UpcomingPromotionalOffers []promotions `json:"upcomingPromotionalOffers"`
+}
+
+type promotions struct {
+	PromotionalOffers []promotion `json:"promotionalOffers"`
+}
+
+type promotion struct {
+	StartDate       time.Time        `json:"startDate"`
+	EndDate         time.Time        `json:"endDate"`
+	DiscountSetting *discountSetting `json:"discountSetting"`
+}
+
+// discountSetting describes the price during a promotion as a percentage of the original
+// price, so free promotions have a percentage of 0
+type discountSetting struct {
+	DiscountPercentage int `json:"discountPercentage"`
+}
+
+type price struct {
+	Total totalPrice `json:"totalPrice"`
+}
+
+type totalPrice struct {
+	DiscountPrice int `json:"discountPrice"`
+}
+
+type image struct {
+	Type string `json:"type"`
+	Url  string `json:"url"`
+}
+
+type freeGameAPIResponse struct {
+	Data struct {
+		Catalog struct {
+			SearchStore struct {
+				Elements freeGames `json:"elements"`
+			} `json:"searchStore"`
+		} `json:"Catalog"`
+	}
+}
+
+// epicSource finds free games through the Epic Games Store free games promotions API
+type epicSource struct {
+	config config.EpicGamesStoreConfig
+}
+
+func newEpicSource(config config.EpicGamesStoreConfig) *epicSource {
+	return &epicSource{config: config}
+}
+
+func (s *epicSource) FetchFreeGames(ctx context.Context) (FreeGames, FreeGames, error) {
+	var result freeGameAPIResponse
+	if err := fetchJSON(ctx, SourceEpic, s.config.FreeGamesApiUrl, &result); err != nil {
+		return nil, nil, err
+	}
+
+	games := result.Data.Catalog.SearchStore.Elements
+
+	currentGames := selectCurrentFreeGames(games)
+	current := make(FreeGames, 0, len(currentGames))
+	for _, game := range currentGames {
+		current = append(current, s.formatGame(game))
+	}
+
+	// Selecting upcoming games changes their dates, so this has to happen after the current
+	// games have been formatted
+	upcomingGames := selectUpcomingFreeGames(games)
+	upcoming := make(FreeGames, 0, len(upcomingGames))
+	for _, game := range upcomingGames {
+		upcoming = append(upcoming, s.formatGame(game))
+	}
+
+	return current, upcoming, nil
+}
+
+func (s *epicSource) formatGame(game *freeGame) *FreeGame {
+	gameUrl, err := url.JoinPath(s.config.ProductBaseUrl, game.getUrl())
+	if err != nil {
+		log.Printf("EGS Free Games: Failed to create url for game")
+	}
+
+	return &FreeGame{
+		Source:       SourceEpic,
+		StoreID:      game.StoreID,
+		Title:        game.Title,
+		Description:  game.Description,
+		Starts:       game.startTime,
+		Ends:         game.endTime,
+		URL:          gameUrl,
+		ThumbnailURL: game.getThumbnail(),
+	}
+}
+
+func (g *freeGame) catalog(key string) string {
+	for _, mapping := range g.CatalogNs.Mappings {
+		if mapping.PageType == key {
+			return mapping.PageSlug
+		}
+	}
+
+	return ""
+}
+
+func (g *freeGame) setDates() {
+	now := time.Now()
+	for _, offers := range g.Promotions.PromotionalOffers {
+		for _, offer := range offers.PromotionalOffers {
+			if offer.StartDate.Before(now) && offer.EndDate.After(now) {
+				g.startTime = offer.StartDate
+				g.endTime = offer.EndDate
+			}
+		}
+	}
+}
+
+// setUpcomingDates sets the dates of the earliest upcoming promotion making the game free, and
+// reports whether there is one
+func (g *freeGame) setUpcomingDates() bool {
+	now := time.Now()
+	found := false
+	for _, offers := range g.Promotions.UpcomingPromotionalOffers {
+		for _, offer := range offers.PromotionalOffers {
+			if offer.DiscountSetting == nil || offer.DiscountSetting.DiscountPercentage != 0 {
+				continue
+			}
+
+			if offer.StartDate.After(now) && (!found || offer.StartDate.Before(g.startTime)) {
+				g.startTime = offer.StartDate
+				g.endTime = offer.EndDate
+				found = true
+			}
+		}
+	}
+
+	return found
+}
+
+func (g *freeGame) hasCurrentPromotion() bool {
+	now := time.Now()
+	return g.startTime.Before(now) && g.endTime.After(now)
+}
+
+func (game *freeGame) getUrl() string {
+	slug := game.UrlSlug
+
+	if str := game.catalog("productHome"); str != "" {
+		slug = str
+	} else if game.ProductSlug != "" {
+		slug = game.ProductSlug
+	}
+
+	if index := strings.IndexByte(slug, '/'); index != -1 {
+		slug = slug[:index]
+	}
+
+	return slug
+}
+
+func (game *freeGame) getThumbnail() string {
+	url := ""
+	for _, img := range game.Images {
+		switch strings.ToLower(img.Type) {
+		case "thumbnail":
+			// Prefer thumbnail if available, so return immediately when found
+			return img.Url
+		case "offerimagewide":
+			url = img.Url
+		case "dieselstorefrontwide":
+			url = img.Url
+		}
+	}
+	return url
+}
+
+func selectCurrentFreeGames(games freeGames) freeGames {
+	filteredGames := make(freeGames, 0)
+
+	for _, game := range games {
+		game.setDates()
+		if game.Price.Total.DiscountPrice <= 0 && game.hasCurrentPromotion() {
+			filteredGames = append(filteredGames, game)
+		}
+	}
+
+	return filteredGames
+}
+
+func selectUpcomingFreeGames(games freeGames) freeGames {
+	filteredGames := make(freeGames, 0)
+
+	for _, game := range games {
+		if game.setUpcomingDates() {
+			filteredGames = append(filteredGames, game)
+		}
+	}
+
+	sort.SliceStable(filteredGames, func(i, j int) bool {
+		return filteredGames[i].startTime.Before(filteredGames[j].startTime)
+	})
+
+	return filteredGames
+}
@@ -0,0 +1,92 @@
+package egs
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strconv"
+
+	"github.com/aloop/discord-bot/internal/pkg/config"
+)
+
+type gogCatalogResponse struct {
+	Products []gogProduct `json:"products"`
+}
+
+type gogProduct struct {
+	ID              string    `json:"id"`
+	Slug            string    `json:"slug"`
+	Title           string    `json:"title"`
+	CoverHorizontal string    `json:"coverHorizontal"`
+	Price           *gogPrice `json:"price"`
+}
+
+type gogPrice struct {
+	BaseMoney  gogMoney `json:"baseMoney"`
+	FinalMoney gogMoney `json:"finalMoney"`
+}
+
+// gogMoney is an amount of money, which the API formats as a decimal string such as "9.99"
+type gogMoney struct {
+	Amount string `json:"amount"`
+}
+
+// gogSource finds games given away on GOG by searching its catalog for games discounted to a
+// price of 0. GOG neither says when a giveaway ends nor announces upcoming ones.
+type gogSource struct {
+	config config.GOGConfig
+}
+
+func newGOGSource(config config.GOGConfig) *gogSource {
+	return &gogSource{config: config}
+}
+
+func (s *gogSource) FetchFreeGames(ctx context.Context) (FreeGames, FreeGames, error) {
+	var result gogCatalogResponse
+	if err := fetchJSON(ctx, SourceGOG, s.config.CatalogApiUrl, &result); err != nil {
+		return nil, nil, err
+	}
+
+	current := make(FreeGames, 0)
+
+	for _, product := range result.Products {
+		if !product.isGivenAway() {
+			continue
+		}
+
+		gameUrl, err := url.JoinPath(s.config.ProductBaseUrl, product.Slug)
+		if err != nil {
+			log.Printf("GOG Free Games: Failed to create url for game %s\n%v", product.Title, err)
+		}
+
+		current = append(current, &FreeGame{
+			Source:       SourceGOG,
+			StoreID:      product.ID,
+			Title:        product.Title,
+			URL:          gameUrl,
+			ThumbnailURL: product.CoverHorizontal,
+		})
+	}
+
+	return current, FreeGames{}, nil
+}
+
+// isGivenAway reports whether the product is discounted to a price of 0, which leaves out games
+// that are always free
+func (p gogProduct) isGivenAway() bool {
+	if p.Price == nil {
+		return false
+	}
+
+	final, err := strconv.ParseFloat(p.Price.FinalMoney.Amount, 64)
+	if err != nil {
+		return false
+	}
+
+	base, err := strconv.ParseFloat(p.Price.BaseMoney.Amount, 64)
+	if err != nil {
+		return false
+	}
+
+	return final == 0 && base > 0
+}
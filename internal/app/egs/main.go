@@ -2,12 +2,12 @@ package egs
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"net/url"
-	"strings"
+	"sort"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -15,137 +15,87 @@ import (
 
 	"github.com/aloop/discord-bot/database"
 	"github.com/aloop/discord-bot/internal/pkg/config"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
 )
 
-var httpClient = &http.Client{Timeout: 30 * time.Second}
-
-type freeGames []*freeGame
-
-type freeGame struct {
-	Title         string      `json:"title"`
-	Description   string      `json:"description"`
-	StoreID       string      `json:"id"`
-	Price         price       `json:"price"`
-	UrlSlug       string      `json:"urlSlug"`
-	ProductSlug   string      `json:"productSlug"`
-	Images        []image     `json:"keyImages"`
-	CatalogNs     catalogs    `json:"catalogNs"`
-	OfferType     string      `json:"offerType"`
-	OfferMappings []pageMap   `json:"offerMappings"`
-	Promotions    promotional `json:"promotions"`
-	startTime     time.Time
-	endTime       time.Time
-}
-
-type catalogs struct {
-	Mappings []pageMap `json:"mappings"`
-}
+const embedTimeFormat = "Monday, January 02, 2006 at 03:04PM MST"
 
-type pageMap struct {
-	PageSlug string `json:"pageSlug"`
-	PageType string `json:"pageType"`
-}
-
-type promotional struct {
-	PromotionalOffers []promotions `json:"promotionalOffers"`
-}
-
-type promotions struct {
-	PromotionalOffers []promotion `json:"promotionalOffers"`
-}
+// Statuses of stored free games
+const (
+	StatusUpcoming = "upcoming"
+	StatusLive     = "live"
+)
 
-type promotion struct {
-	StartDate time.Time `json:"startDate"`
-	EndDate   time.Time `json:"endDate"`
-}
+// How long a game whose store does not say when the promotion ends is considered free after
+// the store last listed it. This should comfortably exceed the fetch interval, so a single
+// failed fetch does not end the promotion early.
+const openEndedGracePeriod = 6 * time.Hour
 
-type price struct {
-	Total totalPrice `json:"totalPrice"`
-}
-
-type totalPrice struct {
-	DiscountPrice int `json:"discountPrice"`
-}
+// How long ago an open-ended promotion has to have ended for the game being listed again to count
+// as a new giveaway. Shorter gaps are taken to be fetches that failed, such as during an outage.
+const openEndedRelistGap = 7 * 24 * time.Hour
 
-type image struct {
-	Type string `json:"type"`
-	Url  string `json:"url"`
-}
+var httpClient = &http.Client{Timeout: 30 * time.Second}
 
 type FreeGames []*FreeGame
 
 type FreeGame struct {
+	// ID of the stored game, 0 for games that are not stored
+	ID int64
+	// Key of the store the game is free at, such as SourceEpic
+	Source string
+	// ID of the game in its store
+	StoreID      string
 	Title        string
 	Description  string
 	URL          string
 	ThumbnailURL string
-	Starts       time.Time
-	Ends         time.Time
+	// When the promotion started. Stores that do not say leave it zero, and the game is dated to
+	// the day it was first seen, which keeps the date the same between fetches as it is part of
+	// the unique key of stored games. That is not enough for open-ended promotions, which would
+	// be stored again after a fetch on either side of midnight or a long outage, so they are
+	// stored once per game instead and renewed when the game is given away again.
+	Starts time.Time
+	Ends   time.Time
+	// Whether the store did not say when the promotion ends, in which case Ends is an estimate
+	// based on when the store last listed the game
+	OpenEnded bool
 }
 
-type freeGameAPIResponse struct {
-	Data struct {
-		Catalog struct {
-			SearchStore struct {
-				Elements freeGames `json:"elements"`
-			} `json:"searchStore"`
-		} `json:"Catalog"`
-	}
-}
-
-type EGSClient struct {
-	config *config.Config
-	db     *database.Queries
+// FreeGamesClient announces the games given away by the Epic Games Store and every other enabled
+// DealSource
+type FreeGamesClient struct {
+	config  *config.Config
+	db      storage.Store
+	sources []DealSource
 }
 
 func New(
 	config *config.Config,
-	db *database.Queries,
-) *EGSClient {
-	return &EGSClient{
-		config: config,
-		db:     db,
+	db storage.Store,
+) *FreeGamesClient {
+	sources := []DealSource{newEpicSource(config.EpicGamesStore)}
+
+	if config.GOG.Enabled {
+		sources = append(sources, newGOGSource(config.GOG))
 	}
-}
 
-func (egs *EGSClient) StartFreeGamesFetchInterval(
-	ctx context.Context,
-	discord *discordgo.Session,
-	channel string,
-	period time.Duration,
-) {
-	ticker := time.NewTicker(period)
-
-	go func() {
-		for {
-			select {
-			case <-ticker.C:
-				log.Println("EGS Free Games: Attempting to fetch latest free games")
-				newGames, err := egs.FetchNewFreeGames()
-				if err != nil {
-					log.Println(err)
-				}
+	if config.Steam.Enabled {
+		sources = append(sources, newSteamSource(config.Steam))
+	}
 
-				if len(newGames) > 0 {
-					embeds := createDiscordMessageEmbeds(newGames)
-					_, err := discord.ChannelMessageSendEmbeds(
-						channel,
-						embeds,
-					)
-					if err != nil {
-						log.Println(err)
-					}
-				}
-			case <-ctx.Done():
-				ticker.Stop()
-				log.Println("EGS Free Games: stopping fetch interval")
-				return
-			}
-		}
-	}()
+	return &FreeGamesClient{
+		config:  config,
+		db:      db,
+		sources: sources,
+	}
 }
 
-func createDiscordMessageEmbeds(games FreeGames) []*discordgo.MessageEmbed {
+// CreateDiscordMessageEmbeds creates an embed for each game. At most MaxEmbedsPerMessage of them
+// fit in a single message.
+func CreateDiscordMessageEmbeds(games FreeGames) []*discordgo.MessageEmbed {
+	now := time.Now()
+
 	embeds := make([]*discordgo.MessageEmbed, 0, len(games))
 	for _, game := range games {
 		newEmbed := &discordgo.MessageEmbed{
@@ -153,22 +103,42 @@ func createDiscordMessageEmbeds(games FreeGames) []*discordgo.MessageEmbed {
 			Fields: []*discordgo.MessageEmbedField{
 				{
 					Name:  "Free at",
-					Value: "Epic Games Store",
-				},
-				{
-					Name:  "Description",
-					Value: game.Description,
-				},
-				{
-					Name:  "Free Until",
-					Value: game.Ends.Local().Format("Monday, January 02, 2006 at 03:04PM MST"),
+					Value: SourceName(game.Source),
 				},
 			},
 		}
 
+		// Discord rejects embed fields without a value, and not every store has descriptions
+		if game.Description != "" {
+			newEmbed.Fields = append(newEmbed.Fields, &discordgo.MessageEmbedField{
+				Name:  "Description",
+				Value: game.Description,
+			})
+		}
+
+		if game.Starts.After(now) {
+			newEmbed.Fields = append(newEmbed.Fields, &discordgo.MessageEmbedField{
+				Name:  "Free From",
+				Value: game.Starts.Local().Format(embedTimeFormat),
+			})
+		}
+
+		// The end of open ended promotions is only an estimate, so it is not shown
+		if !game.OpenEnded {
+			untilField := "Free Until"
+			if game.Ends.Before(now) {
+				untilField = "Was Free Until"
+			}
+
+			newEmbed.Fields = append(newEmbed.Fields, &discordgo.MessageEmbedField{
+				Name:  untilField,
+				Value: game.Ends.Local().Format(embedTimeFormat),
+			})
+		}
+
 		if encodedGameUrl, err := url.Parse(game.URL); err != nil {
 			log.Printf(
-				"EGS Free Games: Failed to parse game url, omitting game url: %v",
+				"Free Games: Failed to parse game url, omitting game url: %v",
 				err,
 			)
 		} else {
@@ -179,7 +149,7 @@ func createDiscordMessageEmbeds(games FreeGames) []*discordgo.MessageEmbed {
 			encodedThumbnailUrl, err := url.Parse(game.ThumbnailURL)
 			if err != nil {
 				log.Printf(
-					"EGS Free Games: Failed to parse thumbnail image url, omitting thumbnail: %v",
+					"Free Games: Failed to parse thumbnail image url, omitting thumbnail: %v",
 					err,
 				)
 			} else {
@@ -195,152 +165,350 @@ func createDiscordMessageEmbeds(games FreeGames) []*discordgo.MessageEmbed {
 	return embeds
 }
 
-func (egs *EGSClient) FetchNewFreeGames() (FreeGames, error) {
-	res, err := httpClient.Get(egs.config.EpicGamesStore.FreeGamesApiUrl)
-	if err != nil {
-		return nil,
-			fmt.Errorf(
-				"failed to fetch latest free games from the Epic Games Store API:\n%w",
-				err,
-			)
-	}
+// fetchNewFreeGames fetches the free games of every source and stores the ones not seen before.
+// It returns the games that became free since the last fetch, including previously announced
+// upcoming games that went live, and the newly announced upcoming games. A source failing does
+// not keep the games of the other sources from being returned.
+//
+// The returned games are queued for announcement in every channel, in the same transaction that
+// stores them. Only games this fetch stored or marked live are returned, so a game is never
+// announced twice, even by two instances fetching at once.
+func (egs *FreeGamesClient) fetchNewFreeGames(
+	ctx context.Context,
+	channels []Channel,
+) (live FreeGames, upcoming FreeGames, err error) {
+	var current, announced FreeGames
+	var errs []error
+	for _, source := range egs.sources {
+		sourceCurrent, sourceUpcoming, err := source.FetchFreeGames(ctx)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
 
-	defer res.Body.Close()
+		current = append(current, sourceCurrent...)
+		announced = append(announced, sourceUpcoming...)
+	}
 
-	if res.StatusCode < 200 || res.StatusCode > 299 {
-		return nil,
-			fmt.Errorf(
-				"failed to fetch latest free games from the Epic Games Store API - API returned Status: %s",
-				res.Status,
+	err = egs.db.InTx(ctx, func(tx storage.Store) error {
+		storedGames, err := tx.GetUnexpiredFreeGames(ctx)
+		if err != nil {
+			return fmt.Errorf(
+				"Free Games: Failed to fetch current free games from DB:\n%w",
+				err,
 			)
-	}
+		}
 
-	var result freeGameAPIResponse
+		stored := make(map[freeGameKey]*database.EgsFreeGame, len(storedGames))
+		for i, game := range storedGames {
+			stored[freeGameKey{game.Source, game.StoreID, game.Title}] = &storedGames[i]
+		}
 
-	if err := json.NewDecoder(res.Body).Decode(&result); err != nil {
-		return nil,
-			fmt.Errorf("EGS API: Failed to parse json response:\n%+v", err)
-	}
+		live, err = storeCurrentGames(ctx, tx, stored, current)
+		if err != nil {
+			return err
+		}
 
-	newFreeGames := selectCurrentFreeGames(result.Data.Catalog.SearchStore.Elements)
+		upcoming, err = storeUpcomingGames(ctx, tx, stored, announced)
+		if err != nil {
+			return err
+		}
 
-	formattedFreeGames := make(FreeGames, 0, len(newFreeGames))
+		if err := queueAnnouncements(ctx, tx, channels, live, StatusLive); err != nil {
+			return err
+		}
 
-	currentFreeGames, err := egs.db.GetCurrentFreeGames(context.Background())
+		return queueAnnouncements(ctx, tx, channels, upcoming, StatusUpcoming)
+	})
 	if err != nil {
-		return nil,
-			fmt.Errorf("EGS Free Games: Failed to fetch current free games from DB")
+		return nil, nil, errors.Join(append(errs, err)...)
 	}
 
-OUTER:
-	for _, game := range newFreeGames {
-		for _, currentGame := range currentFreeGames {
-			if currentGame.StoreID == game.StoreID && currentGame.Title == game.Title {
-				continue OUTER
+	return live, upcoming, errors.Join(errs...)
+}
+
+// freeGameKey identifies the promotions of a game, as the same game can be free in more than
+// one store
+type freeGameKey struct {
+	source  string
+	storeID string
+	title   string
+}
+
+func (game *FreeGame) key() freeGameKey {
+	return freeGameKey{game.Source, game.StoreID, game.Title}
+}
+
+// storeCurrentGames stores the games that became free, and returns the ones to announce
+func storeCurrentGames(
+	ctx context.Context,
+	tx storage.Store,
+	stored map[freeGameKey]*database.EgsFreeGame,
+	games FreeGames,
+) (FreeGames, error) {
+	now := time.Now()
+	live := make(FreeGames, 0, len(games))
+
+	for _, game := range games {
+		if game.Starts.IsZero() {
+			game.Starts = now.Truncate(24 * time.Hour)
+		}
+
+		if game.Ends.IsZero() {
+			game.OpenEnded = true
+			game.Ends = now.Add(openEndedGracePeriod)
+		}
+
+		storedGame, ok := stored[game.key()]
+		if !ok {
+			added, err := storeGame(ctx, tx, game, StatusLive)
+			if err != nil {
+				return nil, err
+			}
+
+			if !added && game.OpenEnded {
+				added, err = renewOpenEndedGame(ctx, tx, game, now)
+				if err != nil {
+					return nil, err
+				}
 			}
+
+			if added {
+				live = append(live, game)
+			}
+			continue
 		}
 
-		gameUrl, err := url.JoinPath(egs.config.EpicGamesStore.ProductBaseUrl, game.getUrl())
-		if err != nil {
-			log.Printf("EGS Free Games: Failed to create url for game")
+		if storedGame.OpenEnded {
+			err := tx.ExtendFreeGame(ctx, database.ExtendFreeGameParams{
+				ID:      storedGame.ID,
+				EndDate: pgtype.Timestamptz{Time: game.Ends, Valid: true},
+			})
+			if err != nil {
+				return nil, fmt.Errorf(
+					"Free Games: Failed to extend free game %d:\n%w",
+					storedGame.ID,
+					err,
+				)
+			}
 		}
 
-		formattedGame := &FreeGame{
-			Title:        game.Title,
-			Description:  game.Description,
-			Starts:       game.startTime,
-			Ends:         game.endTime,
-			URL:          gameUrl,
-			ThumbnailURL: game.getThumbnail(),
+		if storedGame.Status != StatusUpcoming {
+			continue
 		}
-		formattedFreeGames = append(formattedFreeGames, formattedGame)
-
-		// Store new free games in the database
-		_, err = egs.db.AddFreeGame(context.Background(), database.AddFreeGameParams{
-			Title:        game.Title,
-			Description:  game.Description,
-			StartDate:    pgtype.Timestamptz{Time: game.startTime, Valid: true},
-			EndDate:      pgtype.Timestamptz{Time: game.endTime, Valid: true},
-			Url:          gameUrl,
-			ThumbnailUrl: formattedGame.ThumbnailURL,
-			StoreID:      game.StoreID,
-		})
+
+		// Only the fetch that marks the game as live announces it, so it is never posted twice
+		marked, err := tx.MarkFreeGameLive(ctx, storedGame.ID)
 		if err != nil {
-			log.Printf("EGS Free Games: Failed to add free game to DB\n%+v", err)
+			return nil, fmt.Errorf(
+				"Free Games: Failed to mark upcoming free game as live:\n%w",
+				err,
+			)
+		}
+
+		if marked > 0 {
+			game.ID = storedGame.ID
+			live = append(live, game)
 		}
 	}
 
-	return formattedFreeGames, nil
+	return live, nil
 }
 
-func (g *freeGame) catalog(key string) string {
-	for _, mapping := range g.CatalogNs.Mappings {
-		if mapping.PageType == key {
-			return mapping.PageSlug
+// storeUpcomingGames stores the newly announced upcoming games, and returns the ones to announce
+func storeUpcomingGames(
+	ctx context.Context,
+	tx storage.Store,
+	stored map[freeGameKey]*database.EgsFreeGame,
+	games FreeGames,
+) (FreeGames, error) {
+	upcoming := make(FreeGames, 0, len(games))
+
+	for _, game := range games {
+		if _, ok := stored[game.key()]; ok {
+			continue
 		}
-	}
 
-	return ""
-}
+		added, err := storeGame(ctx, tx, game, StatusUpcoming)
+		if err != nil {
+			return nil, err
+		}
 
-func (g *freeGame) setDates() {
-	now := time.Now()
-	for _, offers := range g.Promotions.PromotionalOffers {
-		for _, offer := range offers.PromotionalOffers {
-			if offer.StartDate.Before(now) && offer.EndDate.After(now) {
-				g.startTime = offer.StartDate
-				g.endTime = offer.EndDate
-			}
+		if added {
+			upcoming = append(upcoming, game)
 		}
 	}
+
+	return upcoming, nil
 }
 
-func (g *freeGame) hasCurrentPromotion() bool {
-	now := time.Now()
-	return g.startTime.Before(now) && g.endTime.After(now)
+// storeGame stores a newly seen free game and sets its ID. It reports whether the game was
+// added, which it is not when the same promotion was stored already, such as by another
+// instance of the bot.
+func storeGame(ctx context.Context, tx storage.Store, game *FreeGame, status string) (bool, error) {
+	stored, err := tx.AddFreeGame(ctx, database.AddFreeGameParams{
+		Title:        game.Title,
+		Description:  game.Description,
+		StartDate:    pgtype.Timestamptz{Time: game.Starts, Valid: true},
+		EndDate:      pgtype.Timestamptz{Time: game.Ends, Valid: true},
+		Url:          game.URL,
+		ThumbnailUrl: game.ThumbnailURL,
+		StoreID:      game.StoreID,
+		Status:       status,
+		Source:       game.Source,
+		OpenEnded:    game.OpenEnded,
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("Free Games: Failed to add free game to DB:\n%w", err)
+	}
+
+	game.ID = stored.ID
+
+	return true, nil
 }
 
-func (game *freeGame) getUrl() string {
-	slug := game.UrlSlug
+// renewOpenEndedGame handles an open-ended game that was not added as the game is stored already,
+// either by another instance or for an earlier promotion. The stored promotion is extended, unless
+// it ended long enough ago for this to be a new giveaway, in which case it is renewed and the game
+// is reported so it is announced again.
+func renewOpenEndedGame(
+	ctx context.Context,
+	tx storage.Store,
+	game *FreeGame,
+	now time.Time,
+) (bool, error) {
+	storedGame, err := tx.GetOpenEndedFreeGame(ctx, database.GetOpenEndedFreeGameParams{
+		Source:  game.Source,
+		StoreID: game.StoreID,
+	})
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("Free Games: Failed to fetch open ended free game:\n%w", err)
+	}
 
-	if str := game.catalog("productHome"); str != "" {
-		slug = str
-	} else if game.ProductSlug != "" {
-		slug = game.ProductSlug
+	if storedGame.EndDate.Time.After(now.Add(-openEndedRelistGap)) {
+		err := tx.ExtendFreeGame(ctx, database.ExtendFreeGameParams{
+			ID:      storedGame.ID,
+			EndDate: pgtype.Timestamptz{Time: game.Ends, Valid: true},
+		})
+		if err != nil {
+			return false, fmt.Errorf(
+				"Free Games: Failed to extend free game %d:\n%w",
+				storedGame.ID,
+				err,
+			)
+		}
+
+		return false, nil
 	}
 
-	if index := strings.IndexByte(slug, '/'); index != -1 {
-		slug = slug[:index]
+	err = tx.RenewFreeGame(ctx, database.RenewFreeGameParams{
+		ID:        storedGame.ID,
+		StartDate: pgtype.Timestamptz{Time: game.Starts, Valid: true},
+		EndDate:   pgtype.Timestamptz{Time: game.Ends, Valid: true},
+	})
+	if err != nil {
+		return false, fmt.Errorf(
+			"Free Games: Failed to renew free game %d:\n%w",
+			storedGame.ID,
+			err,
+		)
 	}
 
-	return slug
+	game.ID = storedGame.ID
+
+	return true, nil
 }
 
-func (game *freeGame) getThumbnail() string {
-	url := ""
-	for _, img := range game.Images {
-		switch strings.ToLower(img.Type) {
-		case "thumbnail":
-			// Prefer thumbnail if available, so return immediately when found
-			return img.Url
-		case "offerimagewide":
-			url = img.Url
-		case "dieselstorefrontwide":
-			url = img.Url
+// FetchUpcomingFreeGames fetches the games that will be free in a future promotion, soonest
+// first. Sources that fail are skipped, unless every source fails.
+func (egs *FreeGamesClient) FetchUpcomingFreeGames() (FreeGames, error) {
+	upcoming := make(FreeGames, 0)
+
+	var errs []error
+	for _, source := range egs.sources {
+		_, games, err := source.FetchFreeGames(context.Background())
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
+
+		upcoming = append(upcoming, games...)
+	}
+
+	if len(errs) == len(egs.sources) {
+		return nil, errors.Join(errs...)
+	}
+
+	for _, err := range errs {
+		log.Printf("Free Games: Failed to fetch upcoming free games\n%v", err)
+	}
+
+	sort.SliceStable(upcoming, func(i, j int) bool {
+		return upcoming[i].Starts.Before(upcoming[j].Starts)
+	})
+
+	return upcoming, nil
+}
+
+// CurrentFreeGames returns the stored games that are free right now
+func (egs *FreeGamesClient) CurrentFreeGames(ctx context.Context) (FreeGames, error) {
+	rows, err := egs.db.GetCurrentFreeGames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current free games from DB: %w", err)
 	}
-	return url
+
+	games := make(FreeGames, 0, len(rows))
+	for _, row := range rows {
+		games = append(games, freeGameFromRow(row))
+	}
+
+	return games, nil
 }
 
-func selectCurrentFreeGames(games freeGames) freeGames {
-	filteredGames := make(freeGames, 0)
+// PastFreeGames returns a page of the stored games whose promotion has ended, most recent
+// first, along with the number of pages. Pages start at 1.
+func (egs *FreeGamesClient) PastFreeGames(
+	ctx context.Context,
+	page int,
+	pageSize int,
+) (FreeGames, int, error) {
+	rows, err := egs.db.GetAllFreeGames(ctx)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get free games from DB: %w", err)
+	}
 
-	for _, game := range games {
-		game.setDates()
-		if game.Price.Total.DiscountPrice <= 0 && game.hasCurrentPromotion() {
-			filteredGames = append(filteredGames, game)
+	now := time.Now()
+	past := make(FreeGames, 0, len(rows))
+	for _, row := range rows {
+		if row.EndDate.Time.Before(now) {
+			past = append(past, freeGameFromRow(row))
 		}
 	}
 
-	return filteredGames
+	pages := (len(past) + pageSize - 1) / pageSize
+	start := (page - 1) * pageSize
+	if page < 1 || start >= len(past) {
+		return FreeGames{}, pages, nil
+	}
+
+	return past[start:min(start+pageSize, len(past))], pages, nil
+}
+
+func freeGameFromRow(row database.EgsFreeGame) *FreeGame {
+	return &FreeGame{
+		ID:           row.ID,
+		Source:       row.Source,
+		StoreID:      row.StoreID,
+		Title:        row.Title,
+		Description:  row.Description,
+		URL:          row.Url,
+		ThumbnailURL: row.ThumbnailUrl,
+		Starts:       row.StartDate.Time,
+		Ends:         row.EndDate.Time,
+		OpenEnded:    row.OpenEnded,
+	}
 }
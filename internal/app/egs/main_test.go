@@ -0,0 +1,395 @@
+package egs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/pkg/config"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+func newTestClient(db *fakes.DB, api *fakes.EGSAPI) *FreeGamesClient {
+	return New(
+		&config.Config{
+			EpicGamesStore: config.EpicGamesStoreConfig{
+				ProductBaseUrl:  api.ProductBaseURL(),
+				FreeGamesApiUrl: api.FreeGamesURL(),
+			},
+		},
+		storage.NewPostgres(db),
+	)
+}
+
+func storedGame(
+	id int64,
+	storeID, title, status string,
+	start, end time.Time,
+) database.EgsFreeGame {
+	return database.EgsFreeGame{
+		ID:        id,
+		StoreID:   storeID,
+		Title:     title,
+		StartDate: pgtype.Timestamptz{Time: start, Valid: true},
+		EndDate:   pgtype.Timestamptz{Time: end, Valid: true},
+		Status:    status,
+		Source:    SourceEpic,
+	}
+}
+
+func TestFetchNewFreeGames(t *testing.T) {
+	now := time.Now()
+	start := now.Add(-24 * time.Hour).Truncate(time.Second)
+	end := now.Add(6 * 24 * time.Hour).Truncate(time.Second)
+
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(
+		fakes.EGSGame{
+			ID:            "free",
+			Title:         "Free Game",
+			Description:   "Free this week",
+			ProductSlug:   "free-game",
+			ThumbnailURL:  "https://cdn.example.com/free.png",
+			OriginalPrice: 1999,
+			Start:         start,
+			End:           end,
+		},
+		fakes.EGSGame{
+			ID:            "discounted",
+			Title:         "Discounted Game",
+			ProductSlug:   "discounted-game",
+			OriginalPrice: 1999,
+			DiscountPrice: 999,
+			Start:         start,
+			End:           end,
+		},
+		fakes.EGSGame{
+			ID:          "expired",
+			Title:       "Expired Game",
+			ProductSlug: "expired-game",
+			Start:       now.Add(-14 * 24 * time.Hour),
+			End:         now.Add(-7 * 24 * time.Hour),
+		},
+		fakes.EGSGame{
+			ID:          "upcoming",
+			Title:       "Upcoming Game",
+			ProductSlug: "upcoming-game",
+			Start:       now.Add(6 * 24 * time.Hour),
+			End:         now.Add(13 * 24 * time.Hour),
+			Upcoming:    true,
+		},
+		fakes.EGSGame{
+			ID:          "no-promotion",
+			Title:       "Always Free Game",
+			ProductSlug: "always-free-game",
+		},
+	)
+
+	db := fakes.NewDB()
+	db.SetRows("AddFreeGame", storedGame(1, "free", "Free Game", StatusLive, start, end))
+
+	games, upcoming, err := newTestClient(db, api).fetchNewFreeGames(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(games) != 1 {
+		t.Fatalf("expected 1 new free game, got %d", len(games))
+	}
+
+	if len(upcoming) != 1 || upcoming[0].Title != "Upcoming Game" {
+		t.Fatalf("expected 1 upcoming free game, got %+v", upcoming)
+	}
+
+	game := games[0]
+	if game.Title != "Free Game" || game.Description != "Free this week" {
+		t.Errorf("unexpected game %+v", game)
+	}
+
+	if want := api.ProductBaseURL() + "free-game"; game.URL != want {
+		t.Errorf("expected url %q, got %q", want, game.URL)
+	}
+
+	if game.ThumbnailURL != "https://cdn.example.com/free.png" {
+		t.Errorf("unexpected thumbnail url %q", game.ThumbnailURL)
+	}
+
+	if !game.Starts.Equal(start) || !game.Ends.Equal(end) {
+		t.Errorf("expected the game to be free from %s to %s, got %s to %s",
+			start, end, game.Starts, game.Ends)
+	}
+
+	calls := db.Calls("AddFreeGame")
+	if len(calls) != 2 {
+		t.Fatalf("expected the free and upcoming games to be stored, stored %d", len(calls))
+	}
+
+	for i, want := range [][2]string{{"free", StatusLive}, {"upcoming", StatusUpcoming}} {
+		if storeID, status := calls[i][0], calls[i][7]; storeID != want[0] || status != want[1] {
+			t.Errorf("expected %q to be stored as %s, stored %v as %v",
+				want[0], want[1], storeID, status)
+		}
+
+		if source := calls[i][8]; source != SourceEpic {
+			t.Errorf("expected %q to be stored as an Epic game, stored as %v", want[0], source)
+		}
+	}
+}
+
+func TestFetchNewFreeGamesSkipsKnownGames(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(
+		fakes.EGSGame{ID: "known", Title: "Known Game", ProductSlug: "known", Start: start, End: end},
+		fakes.EGSGame{ID: "new", Title: "New Game", ProductSlug: "new", Start: start, End: end},
+	)
+
+	db := fakes.NewDB()
+	db.SetRows("GetUnexpiredFreeGames", storedGame(1, "known", "Known Game", StatusLive, start, end))
+	db.SetRows("AddFreeGame", storedGame(2, "new", "New Game", StatusLive, start, end))
+
+	games, _, err := newTestClient(db, api).fetchNewFreeGames(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(games) != 1 || games[0].Title != "New Game" {
+		t.Fatalf("expected only the new game, got %+v", games)
+	}
+
+	if n := len(db.Calls("AddFreeGame")); n != 1 {
+		t.Errorf("expected 1 game to be stored, stored %d", n)
+	}
+}
+
+func TestFetchNewFreeGamesWithoutGames(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"no elements", `{"data":{"Catalog":{"searchStore":{"elements":[]}}}}`},
+		{"null data", `{"data":null}`},
+		{"empty object", `{}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := fakes.NewEGSAPI(t)
+			api.QueueResponses(fakes.Response{Body: tt.body})
+
+			db := fakes.NewDB()
+
+			games, _, err := newTestClient(db, api).fetchNewFreeGames(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if len(games) != 0 {
+				t.Errorf("expected no games, got %d", len(games))
+			}
+		})
+	}
+}
+
+func TestFetchNewFreeGamesErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		response fakes.Response
+	}{
+		{"server error", fakes.Response{Status: http.StatusInternalServerError}},
+		{"rate limited", fakes.Response{Status: http.StatusTooManyRequests}},
+		{"truncated body", fakes.Response{Body: `{"data":{"Catalog":`}},
+		{"html", fakes.Response{Body: `<html>Access Denied</html>`}},
+		{"invalid dates", fakes.Response{Body: `{"data":{"Catalog":{"searchStore":{"elements":[
+			{"promotions":{"promotionalOffers":[{"promotionalOffers":[{"startDate":"soon"}]}]}}
+		]}}}}`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := fakes.NewEGSAPI(t)
+			api.QueueResponses(tt.response)
+
+			db := fakes.NewDB()
+
+			games, _, err := newTestClient(db, api).fetchNewFreeGames(context.Background(), nil)
+			if err == nil {
+				t.Fatalf("expected an error, got %d games", len(games))
+			}
+
+			if n := len(db.Calls("AddFreeGame")); n != 0 {
+				t.Errorf("expected nothing to be stored, stored %d games", n)
+			}
+		})
+	}
+}
+
+func TestFetchNewFreeGamesDatabaseError(t *testing.T) {
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(fakes.EGSGame{
+		ID:    "free",
+		Title: "Free Game",
+		Start: time.Now().Add(-time.Hour),
+		End:   time.Now().Add(time.Hour),
+	})
+
+	db := fakes.NewDB()
+	db.SetError("GetUnexpiredFreeGames", errors.New("connection refused"))
+
+	if _, _, err := newTestClient(db, api).fetchNewFreeGames(context.Background(), nil); err == nil {
+		t.Fatal("expected an error")
+	}
+
+	if n := len(db.Calls("AddFreeGame")); n != 0 {
+		t.Errorf("expected nothing to be stored, stored %d games", n)
+	}
+}
+
+func TestFetchNewFreeGamesAnnouncesUpcomingGamesOnce(t *testing.T) {
+	now := time.Now()
+	start := now.Add(7 * 24 * time.Hour)
+	end := now.Add(14 * 24 * time.Hour)
+
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(fakes.EGSGame{
+		ID:            "upcoming",
+		Title:         "Upcoming Game",
+		ProductSlug:   "upcoming-game",
+		OriginalPrice: 1999,
+		Start:         start,
+		End:           end,
+		Upcoming:      true,
+	})
+
+	db := fakes.NewDB()
+	db.SetRows("GetUnexpiredFreeGames",
+		storedGame(1, "upcoming", "Upcoming Game", StatusUpcoming, start, end))
+
+	live, upcoming, err := newTestClient(db, api).fetchNewFreeGames(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(live) != 0 || len(upcoming) != 0 {
+		t.Errorf("expected an announced upcoming game not to be announced again, got %+v %+v",
+			live, upcoming)
+	}
+
+	if n := len(db.Calls("AddFreeGame")); n != 0 {
+		t.Errorf("expected nothing to be stored, stored %d games", n)
+	}
+}
+
+func TestFetchNewFreeGamesAnnouncesUpcomingGamesGoingLive(t *testing.T) {
+	now := time.Now()
+	start := now.Add(-time.Hour)
+	end := now.Add(7 * 24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		status    string
+		marked    int
+		announced bool
+	}{
+		{"upcoming game goes live", StatusUpcoming, 1, true},
+		{"marked live by another fetch", StatusUpcoming, 0, false},
+		{"already live", StatusLive, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			api := fakes.NewEGSAPI(t)
+			api.SetGames(fakes.EGSGame{
+				ID:          "game",
+				Title:       "Game",
+				ProductSlug: "game",
+				Start:       start,
+				End:         end,
+			})
+
+			db := fakes.NewDB()
+			db.SetRows("GetUnexpiredFreeGames", storedGame(7, "game", "Game", tt.status, start, end))
+			db.SetRows("MarkFreeGameLive", make([]any, tt.marked)...)
+
+			live, _, err := newTestClient(db, api).fetchNewFreeGames(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if announced := len(live) == 1; announced != tt.announced {
+				t.Errorf("expected announced to be %v, got %+v", tt.announced, live)
+			}
+
+			calls := db.Calls("MarkFreeGameLive")
+			if tt.status == StatusUpcoming && (len(calls) != 1 || calls[0][0] != int64(7)) {
+				t.Errorf("expected the stored game to be marked live, got %v", calls)
+			}
+
+			if n := len(db.Calls("AddFreeGame")); n != 0 {
+				t.Errorf("expected nothing to be stored, stored %d games", n)
+			}
+		})
+	}
+}
+
+func TestFetchNewFreeGamesDoesNotAnnounceUnstoredGames(t *testing.T) {
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(fakes.EGSGame{
+		ID:    "free",
+		Title: "Free Game",
+		Start: time.Now().Add(-time.Hour),
+		End:   time.Now().Add(time.Hour),
+	})
+
+	db := fakes.NewDB()
+	db.SetError("AddFreeGame", errors.New("connection refused"))
+
+	live, _, err := newTestClient(db, api).fetchNewFreeGames(context.Background(), nil)
+	if err == nil {
+		t.Error("expected an error")
+	}
+
+	if len(live) != 0 {
+		t.Errorf("expected games that failed to be stored not to be announced, got %+v", live)
+	}
+
+	if db.Commits() != 0 || db.Rollbacks() != 1 {
+		t.Errorf("expected the transaction to be rolled back, got %d commits and %d rollbacks",
+			db.Commits(), db.Rollbacks())
+	}
+}
+
+func TestFetchNewFreeGamesSkipsGamesStoredConcurrently(t *testing.T) {
+	api := fakes.NewEGSAPI(t)
+	api.SetGames(fakes.EGSGame{
+		ID:    "free",
+		Title: "Free Game",
+		Start: time.Now().Add(-time.Hour),
+		End:   time.Now().Add(time.Hour),
+	})
+
+	// Without any rows, the insert hits the unique key like a game stored by another instance
+	db := fakes.NewDB()
+
+	live, _, err := newTestClient(db, api).fetchNewFreeGames(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(live) != 0 {
+		t.Errorf("expected a game stored by another instance not to be announced, got %+v", live)
+	}
+
+	if n := len(db.Calls("AddFreeGame")); n != 1 || db.Commits() != 1 {
+		t.Errorf("expected a single insert in a committed transaction, got %d inserts and %d commits",
+			n, db.Commits())
+	}
+}
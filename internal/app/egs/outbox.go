@@ -0,0 +1,241 @@
+package egs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+const (
+	// Failed announcements are retried after a delay that doubles with every attempt, up to
+	// maxOutboxRetryDelay, and are given up on after maxOutboxAttempts
+	outboxRetryDelay    = time.Minute
+	maxOutboxRetryDelay = time.Hour
+	maxOutboxAttempts   = 10
+
+	// Announcements are claimed for this long while they are being posted, after which they are
+	// due again in case the claim was lost along with the bot posting them
+	outboxClaimDuration = 10 * time.Minute
+
+	// Outbox kind of the reminders posted before a promotion ends, next to the kinds StatusLive
+	// and StatusUpcoming of announcements
+	outboxReminder = "reminder"
+)
+
+// queueAnnouncements adds the games to the outbox of every channel. The kind is the status the
+// games are announced with, StatusLive or StatusUpcoming, or outboxReminder for reminders, which
+// ping the channel's reminder role.
+func queueAnnouncements(
+	ctx context.Context,
+	tx storage.Store,
+	channels []Channel,
+	games FreeGames,
+	kind string,
+) error {
+	for _, channel := range channels {
+		for _, game := range games {
+			queued := database.AddFreeGameOutboxParams{
+				FreeGameID: game.ID,
+				ChannelID:  channel.ID,
+				Kind:       kind,
+			}
+			if kind == outboxReminder {
+				queued.PingRoleID = channel.ReminderRole
+			}
+
+			if err := tx.AddFreeGameOutbox(ctx, queued); err != nil {
+				return fmt.Errorf(
+					"Free Games: Failed to queue the announcement of free game %d:\n%w",
+					game.ID,
+					err,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
+// outboxBatch is the pending announcements of a kind that are posted together in a channel
+type outboxBatch struct {
+	channel  string
+	kind     string
+	pingRole string
+	rows     []database.GetPendingFreeGameOutboxRow
+}
+
+// SendOutbox posts the pending announcements that are due, grouping the games announced in a
+// channel into as few messages as possible. Each announcement is claimed before it is posted, so
+// calls running at the same time never post it twice. Announcements that fail to post are
+// retried by a later call, unless their promotion has ended by then.
+func (egs *FreeGamesClient) SendOutbox(ctx context.Context, discord Discord) error {
+	rows, err := egs.db.GetPendingFreeGameOutbox(ctx, maxOutboxAttempts)
+	if err != nil {
+		return fmt.Errorf("Free Games: Failed to get pending announcements from DB:\n%w", err)
+	}
+
+	var errs []error
+	var batches []*outboxBatch
+	byTarget := make(map[[3]string]*outboxBatch)
+	for _, row := range rows {
+		claimed, err := egs.db.ClaimFreeGameOutbox(ctx, database.ClaimFreeGameOutboxParams{
+			ID: row.ID,
+			NextAttempt: pgtype.Timestamptz{
+				Time:  time.Now().Add(outboxClaimDuration),
+				Valid: true,
+			},
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf(
+				"Free Games: Failed to claim announcement %d:\n%w",
+				row.ID,
+				err,
+			))
+			continue
+		}
+
+		// Another call claimed or posted the announcement since it was read
+		if claimed == 0 {
+			continue
+		}
+
+		target := [3]string{row.ChannelID, row.Kind, row.PingRoleID}
+
+		batch, ok := byTarget[target]
+		if !ok {
+			batch = &outboxBatch{
+				channel:  row.ChannelID,
+				kind:     row.Kind,
+				pingRole: row.PingRoleID,
+			}
+			byTarget[target] = batch
+			batches = append(batches, batch)
+		}
+
+		batch.rows = append(batch.rows, row)
+	}
+
+	for _, batch := range batches {
+		message := discordgo.MessageSend{}
+		switch batch.kind {
+		case StatusUpcoming:
+			message.Content = "Coming soon for free"
+		case outboxReminder:
+			message.Content = "Last chance to claim these free games"
+			message.AllowedMentions = &discordgo.MessageAllowedMentions{}
+
+			if batch.pingRole != "" {
+				message.Content = fmt.Sprintf("<@&%s> %s", batch.pingRole, message.Content)
+				message.AllowedMentions.Roles = []string{batch.pingRole}
+			}
+		}
+
+		games := make(FreeGames, 0, len(batch.rows))
+		for _, row := range batch.rows {
+			games = append(games, freeGameFromRow(row.EgsFreeGame))
+		}
+
+		for i, posted := range sendFreeGames(discord, batch.channel, message, games) {
+			if posted == nil {
+				errs = append(errs, egs.retryAnnouncement(ctx, batch.rows[i]))
+				continue
+			}
+
+			errs = append(errs, egs.markAnnounced(ctx, batch.rows[i], posted.ID))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// markAnnounced marks the announcement as sent and stores the message it was posted in, so the
+// announcement can be marked as expired once the promotion ends. Reminders are only marked sent.
+func (egs *FreeGamesClient) markAnnounced(
+	ctx context.Context,
+	row database.GetPendingFreeGameOutboxRow,
+	messageID string,
+) error {
+	err := egs.db.InTx(ctx, func(tx storage.Store) error {
+		err := tx.MarkFreeGameOutboxSent(ctx, database.MarkFreeGameOutboxSentParams{
+			ID:        row.ID,
+			MessageID: messageID,
+		})
+		if err != nil || row.Kind == outboxReminder {
+			return err
+		}
+
+		return tx.AddFreeGameAnnouncement(ctx, database.AddFreeGameAnnouncementParams{
+			FreeGameID: row.EgsFreeGame.ID,
+			ChannelID:  row.ChannelID,
+			MessageID:  messageID,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf(
+			"Free Games: Failed to store the announcement of free game %d:\n%w",
+			row.EgsFreeGame.ID,
+			err,
+		)
+	}
+
+	return nil
+}
+
+// retryAnnouncement schedules the next attempt at posting an announcement that failed to post.
+// The returned error reports the failure, as the reason was logged when posting.
+func (egs *FreeGamesClient) retryAnnouncement(
+	ctx context.Context,
+	row database.GetPendingFreeGameOutboxRow,
+) error {
+	err := egs.db.MarkFreeGameOutboxFailed(ctx, database.MarkFreeGameOutboxFailedParams{
+		ID: row.ID,
+		NextAttempt: pgtype.Timestamptz{
+			Time:  time.Now().Add(outboxBackoff(row.Attempts)),
+			Valid: true,
+		},
+	})
+	if err != nil {
+		return fmt.Errorf(
+			"Free Games: Failed to schedule a retry of announcement %d:\n%w",
+			row.ID,
+			err,
+		)
+	}
+
+	if row.Attempts+1 >= maxOutboxAttempts {
+		return fmt.Errorf(
+			"Free Games: Giving up on announcing free game %d in channel %s after %d attempts",
+			row.EgsFreeGame.ID,
+			row.ChannelID,
+			maxOutboxAttempts,
+		)
+	}
+
+	return fmt.Errorf(
+		"Free Games: Failed to announce free game %d in channel %s, retrying later",
+		row.EgsFreeGame.ID,
+		row.ChannelID,
+	)
+}
+
+// outboxBackoff returns how long to wait before retrying an announcement that failed to post
+// after the given number of earlier attempts
+func outboxBackoff(attempts int32) time.Duration {
+	if attempts < 0 {
+		attempts = 0
+	}
+
+	// Shifting any further could overflow, and is well past the maximum delay anyway
+	if attempts > 20 {
+		return maxOutboxRetryDelay
+	}
+
+	return min(outboxRetryDelay<<attempts, maxOutboxRetryDelay)
+}
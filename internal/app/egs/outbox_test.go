@@ -0,0 +1,188 @@
+package egs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+// pendingAnnouncement is an outbox row of GetPendingFreeGameOutbox for the stored game
+func pendingAnnouncement(
+	id int64,
+	channel, kind string,
+	attempts int32,
+	game database.EgsFreeGame,
+) database.GetPendingFreeGameOutboxRow {
+	return database.GetPendingFreeGameOutboxRow{
+		ID:          id,
+		ChannelID:   channel,
+		Kind:        kind,
+		Attempts:    attempts,
+		EgsFreeGame: game,
+	}
+}
+
+func TestSendOutboxGroupsAnnouncements(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	db := fakes.NewDB()
+	db.SetRows("ClaimFreeGameOutbox", make([]any, 1)...)
+	db.SetRows("GetPendingFreeGameOutbox",
+		pendingAnnouncement(1, "deals", StatusLive, 0,
+			storedGame(1, "first", "First", StatusLive, start, end)),
+		pendingAnnouncement(2, "deals", StatusUpcoming, 0,
+			storedGame(2, "soon", "Soon", StatusUpcoming, end, end.Add(time.Hour))),
+		pendingAnnouncement(3, "deals", StatusLive, 0,
+			storedGame(3, "second", "Second", StatusLive, start, end)),
+	)
+
+	discord := fakes.NewDiscord()
+	client := newTestClient(db, fakes.NewEGSAPI(t))
+	if err := client.SendOutbox(context.Background(), discord); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := discord.Sent()
+	if len(sent) != 2 {
+		t.Fatalf("expected live and upcoming games to be posted separately, sent %+v", sent)
+	}
+
+	if len(sent[0].Embeds) != 2 || sent[0].Content != "" {
+		t.Errorf("expected both live games in the first message, got %+v", sent[0])
+	}
+
+	if len(sent[1].Embeds) != 1 || sent[1].Content != "Coming soon for free" {
+		t.Errorf("expected the upcoming game in the second message, got %+v", sent[1])
+	}
+
+	marked := db.Calls("MarkFreeGameOutboxSent")
+	if len(marked) != 3 || marked[0][1] != sent[0].ID || marked[1][1] != sent[0].ID ||
+		marked[2][1] != sent[1].ID {
+		t.Errorf("expected each announcement to be marked with its message, got %v", marked)
+	}
+}
+
+func TestSendOutboxReminders(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	reminder := pendingAnnouncement(4, "deals", outboxReminder, 0,
+		storedGame(1, "free", "Free Game", StatusLive, start, end))
+	reminder.PingRoleID = "role"
+
+	db := fakes.NewDB()
+	db.SetRows("ClaimFreeGameOutbox", make([]any, 1)...)
+	db.SetRows("GetPendingFreeGameOutbox", reminder)
+
+	discord := fakes.NewDiscord()
+	client := newTestClient(db, fakes.NewEGSAPI(t))
+	if err := client.SendOutbox(context.Background(), discord); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sent := discord.Sent()
+	if len(sent) != 1 || !strings.HasPrefix(sent[0].Content, "<@&role> Last chance") ||
+		len(sent[0].MentionRoles) != 1 || sent[0].MentionRoles[0] != "role" {
+		t.Fatalf("expected a reminder pinging the role, sent %+v", sent)
+	}
+
+	if marked := db.Calls("MarkFreeGameOutboxSent"); len(marked) != 1 {
+		t.Errorf("expected the reminder to be marked sent, got %v", marked)
+	}
+
+	if calls := db.Calls("AddFreeGameAnnouncement"); len(calls) != 0 {
+		t.Errorf("expected the reminder not to be stored as an announcement, got %v", calls)
+	}
+}
+
+func TestSendOutboxSkipsClaimedAnnouncements(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	db := fakes.NewDB()
+	db.SetRows("GetPendingFreeGameOutbox", pendingAnnouncement(7, "deals", StatusLive, 0,
+		storedGame(1, "free", "Free Game", StatusLive, start, end)))
+
+	discord := fakes.NewDiscord()
+	client := newTestClient(db, fakes.NewEGSAPI(t))
+	if err := client.SendOutbox(context.Background(), discord); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sent := discord.Sent(); len(sent) != 0 {
+		t.Errorf("expected an announcement claimed by another call not to be posted, sent %+v", sent)
+	}
+
+	claims := db.Calls("ClaimFreeGameOutbox")
+	if len(claims) != 1 || claims[0][0] != int64(7) {
+		t.Fatalf("expected the announcement to be claimed, got %v", claims)
+	}
+
+	until := claims[0][1].(pgtype.Timestamptz).Time
+	if until.Before(time.Now().Add(outboxClaimDuration - time.Minute)) {
+		t.Errorf("expected the announcement to be claimed for a while, claimed until %s", until)
+	}
+}
+
+func TestSendOutboxRetriesFailedAnnouncements(t *testing.T) {
+	start := time.Now().Add(-time.Hour)
+	end := time.Now().Add(time.Hour)
+
+	db := fakes.NewDB()
+	db.SetRows("ClaimFreeGameOutbox", make([]any, 1)...)
+	db.SetRows("GetPendingFreeGameOutbox", pendingAnnouncement(7, "deals", StatusLive, 2,
+		storedGame(1, "free", "Free Game", StatusLive, start, end)))
+
+	discord := fakes.NewDiscord()
+	discord.SetSendError(errors.New("service unavailable"))
+
+	before := time.Now()
+	client := newTestClient(db, fakes.NewEGSAPI(t))
+	if err := client.SendOutbox(context.Background(), discord); err == nil {
+		t.Error("expected the failed announcement to be reported")
+	}
+
+	if calls := db.Calls("MarkFreeGameOutboxSent"); len(calls) != 0 {
+		t.Errorf("expected the announcement to stay pending, got %v", calls)
+	}
+
+	if calls := db.Calls("AddFreeGameAnnouncement"); len(calls) != 0 {
+		t.Errorf("expected no announcement to be stored, got %v", calls)
+	}
+
+	failed := db.Calls("MarkFreeGameOutboxFailed")
+	if len(failed) != 1 || failed[0][0] != int64(7) {
+		t.Fatalf("expected the announcement to be retried, got %v", failed)
+	}
+
+	next := failed[0][1].(pgtype.Timestamptz).Time
+	if next.Before(before.Add(4*time.Minute)) || next.After(time.Now().Add(4*time.Minute)) {
+		t.Errorf("expected the third attempt to be retried in 4 minutes, got %s", next)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{3, 8 * time.Minute},
+		{6, time.Hour},
+		{63, time.Hour},
+	}
+
+	for _, test := range tests {
+		if got := outboxBackoff(test.attempts); got != test.want {
+			t.Errorf("outboxBackoff(%d) = %s, want %s", test.attempts, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package egs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Keys of the stores free games are stored under
+const (
+	SourceEpic  = "epic"
+	SourceGOG   = "gog"
+	SourceSteam = "steam"
+)
+
+var sourceNames = map[string]string{
+	SourceEpic:  "Epic Games Store",
+	SourceGOG:   "GOG",
+	SourceSteam: "Steam",
+}
+
+// DealSource is a store giving away games
+type DealSource interface {
+	// FetchFreeGames fetches the games that are free right now, and the games announced to
+	// become free later, with Source set to the key of the store. Games whose store does not say
+	// when the promotion starts or ends have a zero Starts or Ends.
+	FetchFreeGames(ctx context.Context) (current FreeGames, upcoming FreeGames, err error)
+}
+
+// SourceName returns the name of the store a free game is from
+func SourceName(source string) string {
+	if name, ok := sourceNames[source]; ok {
+		return name
+	}
+
+	return source
+}
+
+// fetchJSON decodes the JSON response of a store API into v
+func fetchJSON(ctx context.Context, source string, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request to the %s API:\n%w", SourceName(source), err)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf(
+			"failed to fetch latest free games from the %s API:\n%w",
+			SourceName(source),
+			err,
+		)
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf(
+			"failed to fetch latest free games from the %s API - API returned Status: %s",
+			SourceName(source),
+			res.Status,
+		)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(v); err != nil {
+		return fmt.Errorf("%s API: Failed to parse json response:\n%+v", SourceName(source), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package egs
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/internal/pkg/config"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+func newMultiSourceClient(
+	db *fakes.DB,
+	epic *fakes.EGSAPI,
+	gog *fakes.GOGAPI,
+	steam *fakes.SteamAPI,
+) *FreeGamesClient {
+	return New(
+		&config.Config{
+			EpicGamesStore: config.EpicGamesStoreConfig{
+				ProductBaseUrl:  epic.ProductBaseURL(),
+				FreeGamesApiUrl: epic.FreeGamesURL(),
+			},
+			GOG: config.GOGConfig{
+				Enabled:        true,
+				ProductBaseUrl: gog.ProductBaseURL(),
+				CatalogApiUrl:  gog.CatalogURL(),
+			},
+			Steam: config.SteamConfig{
+				Enabled:        true,
+				AppBaseUrl:     steam.AppBaseURL(),
+				FeaturedApiUrl: steam.FeaturedURL(),
+			},
+		},
+		storage.NewPostgres(db),
+	)
+}
+
+func TestGOGSourceFetchFreeGames(t *testing.T) {
+	api := fakes.NewGOGAPI(t)
+	api.SetProducts(
+		fakes.GOGProduct{
+			ID:        "1",
+			Title:     "Given Away",
+			Slug:      "given_away",
+			CoverURL:  "https://images.example.com/given_away.png",
+			BasePrice: 999,
+		},
+		fakes.GOGProduct{ID: "2", Title: "Always Free", Slug: "always_free"},
+		fakes.GOGProduct{
+			ID:           "3",
+			Title:        "Discounted",
+			Slug:         "discounted",
+			BasePrice:    999,
+			DiscountedTo: 499,
+		},
+	)
+
+	source := newGOGSource(config.GOGConfig{
+		ProductBaseUrl: api.ProductBaseURL(),
+		CatalogApiUrl:  api.CatalogURL(),
+	})
+
+	current, upcoming, err := source.FetchFreeGames(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(current) != 1 || len(upcoming) != 0 {
+		t.Fatalf("expected only the given away game, got %+v %+v", current, upcoming)
+	}
+
+	game := current[0]
+	if game.Source != SourceGOG || game.StoreID != "1" || game.Title != "Given Away" ||
+		game.URL != api.ProductBaseURL()+"given_away" ||
+		game.ThumbnailURL != "https://images.example.com/given_away.png" {
+		t.Errorf("unexpected game %+v", game)
+	}
+
+	if !game.Ends.IsZero() {
+		t.Errorf("expected the end of a GOG giveaway to be unknown, got %s", game.Ends)
+	}
+}
+
+func TestSteamSourceFetchFreeGames(t *testing.T) {
+	now := time.Now()
+	expires := now.Add(48 * time.Hour).Truncate(time.Second)
+
+	api := fakes.NewSteamAPI(t)
+	api.SetItems(
+		fakes.SteamItem{ID: 10, Name: "Free Weekend", OriginalPrice: 1999, DiscountPercent: 100,
+			Expires: expires},
+		fakes.SteamItem{ID: 20, Name: "No Expiration", OriginalPrice: 999, DiscountPercent: 100},
+		fakes.SteamItem{ID: 10, Name: "Free Weekend", OriginalPrice: 1999, DiscountPercent: 100,
+			Expires: expires},
+		fakes.SteamItem{ID: 30, Name: "Half Off", OriginalPrice: 1999, DiscountPercent: 50},
+		fakes.SteamItem{ID: 40, Name: "Ended", OriginalPrice: 1999, DiscountPercent: 100,
+			Expires: now.Add(-time.Hour)},
+	)
+
+	source := newSteamSource(config.SteamConfig{
+		AppBaseUrl:     api.AppBaseURL(),
+		FeaturedApiUrl: api.FeaturedURL(),
+	})
+
+	current, _, err := source.FetchFreeGames(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(current) != 2 {
+		t.Fatalf("expected each free game once, got %+v", current)
+	}
+
+	if game := current[0]; game.Source != SourceSteam || game.StoreID != "10" ||
+		game.URL != api.AppBaseURL()+"10" || !game.Ends.Equal(expires) {
+		t.Errorf("unexpected game %+v", game)
+	}
+
+	if !current[1].Ends.IsZero() {
+		t.Errorf("expected a discount without expiration to be open ended, got %+v", current[1])
+	}
+}
+
+func TestFetchNewFreeGamesOpenEnded(t *testing.T) {
+	now := time.Now()
+
+	gog := fakes.NewGOGAPI(t)
+	gog.SetProducts(
+		fakes.GOGProduct{ID: "known", Title: "Known", Slug: "known", BasePrice: 999},
+		fakes.GOGProduct{ID: "new", Title: "New", Slug: "new", BasePrice: 999},
+	)
+
+	db := fakes.NewDB()
+	known := storedGame(4, "known", "Known", StatusLive, now.Add(-time.Hour), now.Add(time.Hour))
+	known.Source, known.OpenEnded = SourceGOG, true
+	db.SetRows("GetUnexpiredFreeGames", known)
+	db.SetRows("AddFreeGame", storedGame(5, "new", "New", StatusLive, now, now.Add(time.Hour)))
+
+	client := newMultiSourceClient(db, fakes.NewEGSAPI(t), gog, fakes.NewSteamAPI(t))
+
+	live, _, err := client.fetchNewFreeGames(context.Background(), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(live) != 1 || live[0].Title != "New" || !live[0].OpenEnded || live[0].ID != 5 {
+		t.Fatalf("expected only the new game to be announced, got %+v", live)
+	}
+
+	calls := db.Calls("AddFreeGame")
+	if len(calls) != 1 || calls[0][8] != SourceGOG || calls[0][9] != true {
+		t.Fatalf("expected the new game to be stored as an open ended GOG game, got %v", calls)
+	}
+
+	extends := db.Calls("ExtendFreeGame")
+	if len(extends) != 1 || extends[0][0] != int64(4) {
+		t.Fatalf("expected the known game to be extended, got %v", extends)
+	}
+
+	for _, end := range []any{calls[0][6], extends[0][1]} {
+		ends := end.(pgtype.Timestamptz).Time.Add(-openEndedGracePeriod)
+		if ends.Before(now) || ends.After(time.Now()) {
+			t.Errorf("expected the game to be free for the grace period, got %s", ends)
+		}
+	}
+}
+
+func TestFetchNewFreeGamesOpenEndedStoredAlready(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		ended    time.Time
+		announce bool
+	}{
+		// Stored by another instance, or before fetches failed for a while
+		{"still free", now.Add(time.Hour), false},
+		{"after an outage", now.Add(-24 * time.Hour), false},
+		{"given away again", now.Add(-openEndedRelistGap - time.Hour), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gog := fakes.NewGOGAPI(t)
+			gog.SetProducts(fakes.GOGProduct{ID: "game", Title: "Game", Slug: "game", BasePrice: 999})
+
+			db := fakes.NewDB()
+			game := storedGame(4, "game", "Game", StatusLive, now.Add(-30*24*time.Hour), tt.ended)
+			game.Source, game.OpenEnded = SourceGOG, true
+			db.SetRows("GetOpenEndedFreeGame", game)
+
+			client := newMultiSourceClient(db, fakes.NewEGSAPI(t), gog, fakes.NewSteamAPI(t))
+
+			live, _, err := client.fetchNewFreeGames(context.Background(), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			renews := db.Calls("RenewFreeGame")
+			extends := db.Calls("ExtendFreeGame")
+
+			if !tt.announce {
+				if len(live) != 0 || len(renews) != 0 || len(extends) != 1 {
+					t.Errorf("expected the stored game to be extended only, got %+v %v", live, renews)
+				}
+				return
+			}
+
+			if len(live) != 1 || live[0].ID != 4 || len(renews) != 1 || len(extends) != 0 {
+				t.Fatalf("expected the stored game to be renewed and announced, got %+v", live)
+			}
+
+			starts := renews[0][1].(pgtype.Timestamptz).Time
+			if !starts.Equal(now.Truncate(24 * time.Hour)) {
+				t.Errorf("expected the renewed game to be dated to today, got %s", starts)
+			}
+		})
+	}
+}
+
+func TestFetchNewFreeGamesSourceFailure(t *testing.T) {
+	epic := fakes.NewEGSAPI(t)
+	epic.QueueResponses(fakes.Response{Status: http.StatusInternalServerError})
+
+	steam := fakes.NewSteamAPI(t)
+	steam.SetItems(fakes.SteamItem{
+		ID:              10,
+		Name:            "Free Game",
+		OriginalPrice:   999,
+		DiscountPercent: 100,
+		Expires:         time.Now().Add(time.Hour),
+	})
+
+	db := fakes.NewDB()
+	db.SetRows("AddFreeGame",
+		storedGame(1, "10", "Free Game", StatusLive, time.Now(), time.Now().Add(time.Hour)))
+
+	client := newMultiSourceClient(db, epic, fakes.NewGOGAPI(t), steam)
+	live, _, err := client.fetchNewFreeGames(context.Background(), nil)
+	if err == nil {
+		t.Error("expected the Epic Games Store error to be returned")
+	}
+
+	if len(live) != 1 || live[0].Source != SourceSteam {
+		t.Errorf("expected the Steam game to be returned regardless, got %+v", live)
+	}
+}
+
+func TestCreateDiscordMessageEmbedsForOpenEndedGames(t *testing.T) {
+	embeds := CreateDiscordMessageEmbeds(FreeGames{{
+		Source:    SourceGOG,
+		Title:     "Given Away",
+		Starts:    time.Now().Add(-time.Hour),
+		Ends:      time.Now().Add(openEndedGracePeriod),
+		OpenEnded: true,
+	}})
+
+	fields := embeds[0].Fields
+	if len(fields) != 1 || fields[0].Name != "Free at" || fields[0].Value != "GOG" {
+		t.Errorf("expected only the store to be shown, got %+v", fields)
+	}
+}
@@ -0,0 +1,83 @@
+package egs
+
+import (
+	"context"
+	"log"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/aloop/discord-bot/internal/pkg/config"
+)
+
+type steamFeaturedResponse struct {
+	Specials struct {
+		Items []steamItem `json:"items"`
+	} `json:"specials"`
+}
+
+type steamItem struct {
+	ID              int64  `json:"id"`
+	Name            string `json:"name"`
+	DiscountPercent int    `json:"discount_percent"`
+	FinalPrice      int    `json:"final_price"`
+	HeaderImage     string `json:"header_image"`
+	// Unix time the discount ends at, 0 when Steam does not say
+	DiscountExpiration int64 `json:"discount_expiration"`
+}
+
+// steamSource finds games discounted by 100% among the specials featured on the Steam store.
+// Steam does not announce upcoming discounts.
+type steamSource struct {
+	config config.SteamConfig
+}
+
+func newSteamSource(config config.SteamConfig) *steamSource {
+	return &steamSource{config: config}
+}
+
+func (s *steamSource) FetchFreeGames(ctx context.Context) (FreeGames, FreeGames, error) {
+	var result steamFeaturedResponse
+	if err := fetchJSON(ctx, SourceSteam, s.config.FeaturedApiUrl, &result); err != nil {
+		return nil, nil, err
+	}
+
+	now := time.Now()
+	current := make(FreeGames, 0)
+	// The same app can be featured more than once
+	seen := make(map[int64]bool)
+
+	for _, item := range result.Specials.Items {
+		if item.DiscountPercent != 100 || item.FinalPrice != 0 || seen[item.ID] {
+			continue
+		}
+
+		seen[item.ID] = true
+
+		var ends time.Time
+		if item.DiscountExpiration > 0 {
+			ends = time.Unix(item.DiscountExpiration, 0)
+			if ends.Before(now) {
+				continue
+			}
+		}
+
+		storeID := strconv.FormatInt(item.ID, 10)
+
+		gameUrl, err := url.JoinPath(s.config.AppBaseUrl, storeID)
+		if err != nil {
+			log.Printf("Steam Free Games: Failed to create url for game %s\n%v", item.Name, err)
+		}
+
+		current = append(current, &FreeGame{
+			Source:       SourceSteam,
+			StoreID:      storeID,
+			Title:        item.Name,
+			URL:          gameUrl,
+			ThumbnailURL: item.HeaderImage,
+			Ends:         ends,
+		})
+	}
+
+	return current, FreeGames{}, nil
+}
@@ -0,0 +1,245 @@
+package webserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aloop/discord-bot/internal/app/blizzard"
+	"github.com/aloop/discord-bot/internal/pkg/scheduler"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+const (
+	defaultHistoryPeriod = 48 * time.Hour
+	minHistoryResolution = time.Minute
+	maxHistoryBuckets    = 10_000
+)
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+type tokenPriceResponse struct {
+	Region  string    `json:"region"`
+	Price   int64     `json:"price"`
+	Updated time.Time `json:"updated"`
+}
+
+type tokenHistoryResponse struct {
+	Region     string                      `json:"region"`
+	Since      time.Time                   `json:"since"`
+	Until      time.Time                   `json:"until"`
+	Resolution string                      `json:"resolution,omitempty"`
+	Stats      *blizzard.TokenPriceStats   `json:"stats"`
+	Prices     []blizzard.TokenPriceBucket `json:"prices"`
+}
+
+type jobsResponse struct {
+	Jobs []scheduler.JobStatus `json:"jobs"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed while writing JSON response\n%v\n", err)
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, apiError{Error: message})
+}
+
+// requestRegion returns the region given in the query string, or the default region
+func (h *handlerData) requestRegion(req *http.Request) (string, bool) {
+	region := req.URL.Query().Get("region")
+	if region == "" {
+		region = h.blizzard.DefaultRegion()
+	}
+
+	return region, h.blizzard.HasRegion(region)
+}
+
+func (h *handlerData) handleLatestTokenPriceRequest(w http.ResponseWriter, req *http.Request) {
+	region, ok := h.requestRegion(req)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "region is not being tracked")
+		return
+	}
+
+	price, err := h.blizzard.GetLatestTokenPrice(region)
+	if errors.Is(err, storage.ErrNotFound) {
+		writeJSONError(w, http.StatusNotFound, "no token prices have been recorded yet")
+		return
+	} else if err != nil {
+		log.Printf("failed to get latest token price for API request: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	setCacheHeaders(w, price.Updated)
+	writeJSON(w, http.StatusOK, tokenPriceResponse{
+		Region:  region,
+		Price:   price.Price,
+		Updated: price.Updated.UTC(),
+	})
+}
+
+func (h *handlerData) handleTokenHistoryRequest(w http.ResponseWriter, req *http.Request) {
+	region, ok := h.requestRegion(req)
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, "region is not being tracked")
+		return
+	}
+
+	query := req.URL.Query()
+
+	until := time.Now().UTC()
+	if v := query.Get("until"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "until: "+err.Error())
+			return
+		}
+		until = t
+	}
+
+	since := until.Add(-defaultHistoryPeriod)
+	if v := query.Get("since"); v != "" {
+		t, err := parseTimeParam(v)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "since: "+err.Error())
+			return
+		}
+		since = t
+	}
+
+	if !since.Before(until) {
+		writeJSONError(w, http.StatusBadRequest, "since must be before until")
+		return
+	}
+
+	var resolution time.Duration
+	if v := query.Get("resolution"); v != "" {
+		d, err := parseResolution(v)
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "resolution: "+err.Error())
+			return
+		}
+
+		if d < minHistoryResolution {
+			writeJSONError(w, http.StatusBadRequest, "resolution must be at least 1 minute")
+			return
+		}
+
+		if until.Sub(since)/d > maxHistoryBuckets {
+			writeJSONError(
+				w,
+				http.StatusBadRequest,
+				fmt.Sprintf("resolution would produce more than %d buckets", maxHistoryBuckets),
+			)
+			return
+		}
+
+		resolution = d
+	}
+
+	prices, err := h.blizzard.GetTokenPriceHistory(region, since, until)
+	if err != nil {
+		log.Printf("failed to get token price history for API request: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	if len(prices) > 0 {
+		setCacheHeaders(w, prices[len(prices)-1].Updated)
+	}
+
+	resp := tokenHistoryResponse{
+		Region: region,
+		Since:  since.UTC(),
+		Until:  until.UTC(),
+		Prices: blizzard.BucketTokenPrices(prices, since, resolution),
+	}
+
+	if resolution > 0 {
+		resp.Resolution = resolution.String()
+	}
+
+	if stats, ok := blizzard.ComputeTokenPriceStats(prices); ok {
+		resp.Stats = &stats
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// parseTimeParam accepts either an RFC 3339 timestamp or seconds since the unix epoch
+func parseTimeParam(v string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(seconds, 0).UTC(), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("must be an RFC 3339 timestamp or unix time in seconds")
+	}
+
+	return t.UTC(), nil
+}
+
+// parseResolution parses a Go duration, additionally allowing whole days such as "7d"
+func parseResolution(v string) (time.Duration, error) {
+	if days, found := strings.CutSuffix(v, "d"); found {
+		n, err := strconv.ParseInt(days, 10, 64)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf(`invalid number of days "%s"`, v)
+		}
+
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf(`invalid duration "%s"`, v)
+	}
+
+	return d, nil
+}
+
+func (h *handlerData) handleTokenExportRequest(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set(
+		"Content-Disposition",
+		fmt.Sprintf(`attachment; filename="wow-token-prices-%s.csv"`, time.Now().UTC().Format("2006-01-02")),
+	)
+	w.Header().Set("Cache-Control", "no-store")
+
+	// The status has already been sent once rows are being written, so errors can only be logged
+	if err := h.blizzard.ExportTokenPricesCSV(req.Context(), w); err != nil {
+		log.Printf("failed while exporting token prices: %v", err)
+	}
+}
+
+func (h *handlerData) handleJobsRequest(w http.ResponseWriter, req *http.Request) {
+	statuses, err := h.jobs.Status(req.Context())
+	if err != nil {
+		log.Printf("failed to get job statuses for API request: %v", err)
+		writeJSONError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	for i := range statuses {
+		statuses[i].NextRun = statuses[i].NextRun.UTC()
+		if run := statuses[i].LastRun; run != nil {
+			run.Started = run.Started.UTC()
+		}
+	}
+
+	writeJSON(w, http.StatusOK, jobsResponse{Jobs: statuses})
+}
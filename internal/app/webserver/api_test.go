@@ -0,0 +1,143 @@
+package webserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/app/blizzard"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+func historyRequest(h *handlerData, query string) *httptest.ResponseRecorder {
+	res := httptest.NewRecorder()
+	h.handleTokenHistoryRequest(
+		res,
+		httptest.NewRequest(http.MethodGet, "/api/wow-token/history?"+query, nil),
+	)
+
+	return res
+}
+
+func TestHandleTokenHistoryRequestRejectsInvalidQueries(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"since after until", "since=2024-06-02T00:00:00Z&until=2024-06-01T00:00:00Z"},
+		{"since equal to until", "since=1717200000&until=1717200000"},
+		{"invalid since", "since=yesterday"},
+		{"invalid resolution", "resolution=often"},
+		{"resolution under a minute", "resolution=30s"},
+		{"zero days", "resolution=0d"},
+		{
+			"too many buckets",
+			"since=2024-01-01T00:00:00Z&until=2024-06-01T00:00:00Z&resolution=1m",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := fakes.NewDB()
+			res := historyRequest(newTestHandler(db), tt.query)
+
+			if res.Code != http.StatusBadRequest {
+				t.Errorf("expected a bad request, got %d: %s", res.Code, res.Body)
+			}
+
+			var body apiError
+			if err := json.NewDecoder(res.Body).Decode(&body); err != nil || body.Error == "" {
+				t.Errorf("expected a JSON error, got %v", err)
+			}
+
+			if n := len(db.Calls("GetTokenPricesBetween")); n != 0 {
+				t.Errorf("expected no prices to be fetched, fetched %d times", n)
+			}
+		})
+	}
+}
+
+func TestHandleTokenHistoryRequestUnknownRegion(t *testing.T) {
+	res := historyRequest(newTestHandler(fakes.NewDB()), "region=eu")
+	if res.Code != http.StatusNotFound {
+		t.Errorf("expected an untracked region not to be found, got %d", res.Code)
+	}
+}
+
+func TestHandleTokenHistoryRequestBuckets(t *testing.T) {
+	since := time.Date(2024, 6, 1, 0, 30, 0, 0, time.UTC)
+
+	db := fakes.NewDB()
+	row := func(price int64, minutes int) database.GetTokenPricesBetweenRow {
+		updated := since.Add(time.Duration(minutes) * time.Minute)
+		return database.GetTokenPricesBetweenRow{
+			Price:   price,
+			Updated: pgtype.Timestamptz{Time: updated, Valid: true},
+		}
+	}
+	db.SetRows("GetTokenPricesBetween", row(100, 10), row(300, 50), row(200, 70), row(400, 170))
+
+	res := historyRequest(
+		newTestHandler(db),
+		"since=2024-06-01T00:30:00Z&until=2024-06-01T03:30:00Z&resolution=1h",
+	)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected the history, got %d: %s", res.Code, res.Body)
+	}
+
+	var body tokenHistoryResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	// Buckets start at since rather than on the hour, and hours without prices are left out
+	expected := []blizzard.TokenPriceBucket{
+		{Updated: since, Price: 300, Min: 100, Max: 300, Avg: 200, Samples: 2},
+		{Updated: since.Add(time.Hour), Price: 200, Min: 200, Max: 200, Avg: 200, Samples: 1},
+		{Updated: since.Add(2 * time.Hour), Price: 400, Min: 400, Max: 400, Avg: 400, Samples: 1},
+	}
+
+	if len(body.Prices) != len(expected) {
+		t.Fatalf("expected %d buckets, got %+v", len(expected), body.Prices)
+	}
+
+	for i, bucket := range body.Prices {
+		if !bucket.Updated.Equal(expected[i].Updated) {
+			t.Errorf("expected bucket %d to start at %s, got %s",
+				i, expected[i].Updated, bucket.Updated)
+		}
+
+		bucket.Updated = expected[i].Updated
+		if bucket != expected[i] {
+			t.Errorf("expected bucket %d to be %+v, got %+v", i, expected[i], bucket)
+		}
+	}
+
+	if body.Resolution != "1h0m0s" || body.Stats == nil {
+		t.Errorf("expected the resolution and stats, got %+v", body)
+	}
+}
+
+func TestHandleTokenHistoryRequestDays(t *testing.T) {
+	res := historyRequest(
+		newTestHandler(fakes.NewDB()),
+		"since=2024-01-01T00:00:00Z&until=2024-06-01T00:00:00Z&resolution=7d",
+	)
+	if res.Code != http.StatusOK {
+		t.Fatalf("expected the history, got %d: %s", res.Code, res.Body)
+	}
+
+	var body tokenHistoryResponse
+	if err := json.NewDecoder(res.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	if body.Resolution != "168h0m0s" || len(body.Prices) != 0 || body.Stats != nil {
+		t.Errorf("expected a week of resolution without any prices, got %+v", body)
+	}
+}
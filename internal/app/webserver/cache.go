@@ -0,0 +1,224 @@
+package webserver
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// How long rendered charts are kept on disk, well past the time until the next token price
+// update after which a new chart is rendered anyway
+const chartCacheMaxAge = 2 * time.Hour
+
+type renderedChart struct {
+	data       []byte
+	lastUpdate time.Time
+}
+
+// chartCache holds rendered charts in memory, and optionally on disk. Keys include the time of
+// the latest token price, so entries never need to be invalidated, they just stop being
+// requested and fall out of the cache.
+type chartCache struct {
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	size    int
+	dir     string
+	group   singleflight.Group
+}
+
+type chartCacheEntry struct {
+	key   string
+	chart renderedChart
+}
+
+func newChartCache(size int, dir string) *chartCache {
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			log.Printf("Chart Cache: Failed to create cache directory, disabling disk cache\n%v", err)
+			dir = ""
+		}
+	}
+
+	return &chartCache{
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		size:    size,
+		dir:     dir,
+	}
+}
+
+// chartCacheKey identifies a rendered chart. The key is hashed, so it can be used as an ETag and
+// as a file name.
+func chartCacheKey(parts ...any) string {
+	hash := sha256.Sum256([]byte(fmt.Sprint(parts...)))
+
+	return hex.EncodeToString(hash[:16])
+}
+
+// get returns the chart for the key, rendering it at most once no matter how many requests
+// for it arrive at the same time
+func (c *chartCache) get(
+	key string,
+	render func() (*bytes.Buffer, time.Time, error),
+) (renderedChart, error) {
+	if chart, ok := c.fromMemory(key); ok {
+		return chart, nil
+	}
+
+	v, err, _ := c.group.Do(key, func() (any, error) {
+		if chart, ok := c.fromMemory(key); ok {
+			return chart, nil
+		}
+
+		if chart, ok := c.fromDisk(key); ok {
+			c.store(key, chart)
+			return chart, nil
+		}
+
+		buffer, lastUpdate, err := render()
+		if err != nil {
+			return renderedChart{}, err
+		}
+
+		chart := renderedChart{data: buffer.Bytes(), lastUpdate: lastUpdate}
+		c.store(key, chart)
+		c.toDisk(key, chart)
+
+		return chart, nil
+	})
+
+	return v.(renderedChart), err
+}
+
+func (c *chartCache) fromMemory(key string) (renderedChart, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return renderedChart{}, false
+	}
+
+	c.order.MoveToFront(element)
+
+	return element.Value.(*chartCacheEntry).chart, true
+}
+
+func (c *chartCache) store(key string, chart renderedChart) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*chartCacheEntry).chart = chart
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&chartCacheEntry{key: key, chart: chart})
+
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*chartCacheEntry).key)
+	}
+}
+
+func (c *chartCache) path(key string) string {
+	return filepath.Join(c.dir, key+".chart")
+}
+
+func (c *chartCache) fromDisk(key string) (renderedChart, bool) {
+	if c.dir == "" {
+		return renderedChart{}, false
+	}
+
+	file, err := os.ReadFile(c.path(key))
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Chart Cache: Failed to read cached chart\n%v", err)
+		}
+		return renderedChart{}, false
+	}
+
+	// The first line holds the time of the last price in the chart
+	header, data, found := bytes.Cut(file, []byte("\n"))
+	if !found {
+		return renderedChart{}, false
+	}
+
+	lastUpdate, err := time.Parse(time.RFC3339Nano, string(header))
+	if err != nil {
+		return renderedChart{}, false
+	}
+
+	return renderedChart{data: data, lastUpdate: lastUpdate}, true
+}
+
+func (c *chartCache) toDisk(key string, chart renderedChart) {
+	if c.dir == "" {
+		return
+	}
+
+	file, err := os.CreateTemp(c.dir, "tmp-*")
+	if err != nil {
+		log.Printf("Chart Cache: Failed to write cached chart\n%v", err)
+		return
+	}
+
+	_, err = fmt.Fprintf(file, "%s\n", chart.lastUpdate.UTC().Format(time.RFC3339Nano))
+	if err == nil {
+		_, err = file.Write(chart.data)
+	}
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	// Renaming makes sure other instances sharing the directory never read a partial file
+	if err == nil {
+		err = os.Rename(file.Name(), c.path(key))
+	}
+
+	if err != nil {
+		log.Printf("Chart Cache: Failed to write cached chart\n%v", err)
+		os.Remove(file.Name())
+		return
+	}
+
+	c.pruneDisk()
+}
+
+// pruneDisk removes charts that have not been written in a while
+func (c *chartCache) pruneDisk() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		log.Printf("Chart Cache: Failed to list cached charts\n%v", err)
+		return
+	}
+
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".chart") {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || time.Since(info.ModTime()) < chartCacheMaxAge {
+			continue
+		}
+
+		if err := os.Remove(filepath.Join(c.dir, entry.Name())); err != nil && !os.IsNotExist(err) {
+			log.Printf("Chart Cache: Failed to remove stale chart\n%v", err)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package webserver
+
+import (
+	"bytes"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingRender returns a render function producing data, counting how often it is called
+func countingRender(data string, renders *atomic.Int32) func() (*bytes.Buffer, time.Time, error) {
+	return func() (*bytes.Buffer, time.Time, error) {
+		renders.Add(1)
+		return bytes.NewBufferString(data), time.Unix(1_700_000_000, 0), nil
+	}
+}
+
+func TestChartCacheRendersOnce(t *testing.T) {
+	cache := newChartCache(8, "")
+
+	var renders atomic.Int32
+	release := make(chan struct{})
+	render := func() (*bytes.Buffer, time.Time, error) {
+		renders.Add(1)
+		<-release
+		return bytes.NewBufferString("chart"), time.Now(), nil
+	}
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if chart, err := cache.get("key", render); err != nil || string(chart.data) != "chart" {
+				t.Errorf("unexpected chart %q: %v", chart.data, err)
+			}
+		}()
+	}
+
+	// Let the requests pile up on the render in progress
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if n := renders.Load(); n != 1 {
+		t.Errorf("expected concurrent requests to render once, rendered %d times", n)
+	}
+}
+
+func TestChartCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newChartCache(2, "")
+
+	var renders atomic.Int32
+	for _, key := range []string{"a", "b", "a", "c"} {
+		if _, err := cache.get(key, countingRender(key, &renders)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if n := renders.Load(); n != 3 {
+		t.Fatalf("expected a cached chart not to be rendered again, rendered %d times", n)
+	}
+
+	if _, ok := cache.fromMemory("b"); ok {
+		t.Error("expected the least recently used chart to be evicted")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.fromMemory(key); !ok {
+			t.Errorf("expected chart %s to be kept", key)
+		}
+	}
+}
+
+func TestChartCacheDisk(t *testing.T) {
+	dir := t.TempDir()
+
+	var renders atomic.Int32
+	if _, err := newChartCache(8, dir).get("key", countingRender("chart", &renders)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another instance sharing the directory, or this one after a restart
+	chart, err := newChartCache(8, dir).get("key", countingRender("other", &renders))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if renders.Load() != 1 || string(chart.data) != "chart" {
+		t.Errorf("expected the chart to be read from disk, got %q after %d renders",
+			chart.data, renders.Load())
+	}
+
+	if !chart.lastUpdate.Equal(time.Unix(1_700_000_000, 0)) {
+		t.Errorf("expected the time of the last price to be kept, got %s", chart.lastUpdate)
+	}
+}
+
+func TestChartCacheDoesNotCacheErrors(t *testing.T) {
+	cache := newChartCache(8, "")
+
+	failure := errors.New("not enough prices")
+	_, err := cache.get("key", func() (*bytes.Buffer, time.Time, error) {
+		return nil, time.Time{}, failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the render error, got %v", err)
+	}
+
+	if _, ok := cache.fromMemory("key"); ok {
+		t.Error("expected a failed render not to be cached")
+	}
+}
@@ -1,25 +1,40 @@
 package webserver
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"runtime/debug"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/aloop/discord-bot/internal/app/blizzard"
 	"github.com/aloop/discord-bot/internal/pkg/config"
+	"github.com/aloop/discord-bot/internal/pkg/scheduler"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
 )
 
 type handlerData struct {
 	blizzard *blizzard.BlizzardClient
+	jobs     *scheduler.Scheduler
+	charts   *chartCache
 }
 
-func Run(ctx context.Context, b *blizzard.BlizzardClient, c *config.Config) error {
+// Run starts the HTTP server. Any additional routes are registered on the same mux, keyed by
+// their pattern.
+func Run(
+	ctx context.Context,
+	b *blizzard.BlizzardClient,
+	jobs *scheduler.Scheduler,
+	c *config.Config,
+	routes map[string]http.Handler,
+) error {
 	var (
 		listener     net.Listener
 		err          error
@@ -29,12 +44,25 @@ func Run(ctx context.Context, b *blizzard.BlizzardClient, c *config.Config) erro
 
 	h := &handlerData{
 		blizzard: b,
+		jobs:     jobs,
+		charts:   newChartCache(c.HTTP.ChartCacheSize, c.HTTP.ChartCacheDir),
 	}
 
 	mux := http.NewServeMux()
 
+	mux.HandleFunc("GET /wow-token/chart/{region}/{unit}/{period}", h.handleChartRequest)
+	// Charts linked before prices were tracked per region
 	mux.HandleFunc("GET /wow-token/chart/{unit}/{period}", h.handleChartRequest)
 
+	mux.HandleFunc("GET /api/wow-token/latest", h.handleLatestTokenPriceRequest)
+	mux.HandleFunc("GET /api/wow-token/history", h.handleTokenHistoryRequest)
+	mux.HandleFunc("GET /api/wow-token/export.csv", h.handleTokenExportRequest)
+	mux.HandleFunc("GET /api/jobs", h.handleJobsRequest)
+
+	for pattern, handler := range routes {
+		mux.Handle(pattern, handler)
+	}
+
 	if strings.HasPrefix(c.HTTP.ListenHost, "unix:") {
 		isUnixSocket = true
 		socketPath = strings.TrimPrefix(c.HTTP.ListenHost, "unix:")
@@ -53,7 +81,7 @@ func Run(ctx context.Context, b *blizzard.BlizzardClient, c *config.Config) erro
 
 	log.Println("Starting HTTP server")
 	go func() {
-		if err := http.Serve(listener, mux); err != nil {
+		if err := http.Serve(listener, recoverPanics(mux)); err != nil {
 			log.Panicf(
 				"Failed to start HTTP server on %s:%d\n\n%v",
 				c.HTTP.ListenHost,
@@ -72,6 +100,35 @@ func Run(ctx context.Context, b *blizzard.BlizzardClient, c *config.Config) erro
 	return nil
 }
 
+// recoverPanics keeps a panicking handler from taking down the process, logging the panic and
+// responding with an internal server error instead
+func recoverPanics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			err := recover()
+			if err == nil {
+				return
+			}
+
+			// Used by the standard library to abort a response on purpose
+			if err == http.ErrAbortHandler {
+				panic(err)
+			}
+
+			log.Printf(
+				"Recovered from panic while handling %s %s\n%v\n%s",
+				req.Method,
+				req.URL.Path,
+				err,
+				debug.Stack(),
+			)
+			http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		}()
+
+		next.ServeHTTP(w, req)
+	})
+}
+
 func createUnixSocketListener(socketPath string, permissionsStr string) (net.Listener, error) {
 	// Attempt to remove the socket if it already exists
 	if err := os.Remove(socketPath); err != nil && !os.IsNotExist(err) {
@@ -99,9 +156,31 @@ func createUnixSocketListener(socketPath string, permissionsStr string) (net.Lis
 	return listener, nil
 }
 
+// setCacheHeaders allows responses derived from the token price history to be cached until
+// the next token price update is expected
+func setCacheHeaders(w http.ResponseWriter, lastUpdate time.Time) {
+	nextUpdate := lastUpdate.UTC().Add(time.Duration(blizzard.WowTokenGracePeriod) * time.Minute)
+	secondsUntilUpdate := max(int64(time.Until(nextUpdate).Seconds()), 0)
+
+	w.Header().Set("Vary", "accept")
+	w.Header().
+		Set("Cache-Control", fmt.Sprintf("public, max-age=%d", secondsUntilUpdate))
+	w.Header().Set("Last-Modified", lastUpdate.UTC().Format(http.TimeFormat))
+}
+
 func (h *handlerData) handleChartRequest(w http.ResponseWriter, req *http.Request) {
+	region := req.PathValue("region")
+	if region == "" {
+		region = h.blizzard.DefaultRegion()
+	}
+
+	if !h.blizzard.HasRegion(region) {
+		http.NotFound(w, req)
+		return
+	}
+
 	unit := req.PathValue("unit")
-	periodStr := req.PathValue("period")
+	periodStr, format := chartFormat(req)
 
 	period, err := strconv.ParseInt(periodStr, 10, 64)
 	if err != nil {
@@ -130,26 +209,184 @@ func (h *handlerData) handleChartRequest(w http.ResponseWriter, req *http.Reques
 		return
 	}
 
-	chart, lastUpdate, err := h.blizzard.GeneratePriceChart(unit, int(period))
+	overlays, err := blizzard.ParseChartOverlays(req.URL.Query())
+	if err != nil {
+		http.Error(w, "400 Bad Request - "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
-	nextUpdate := lastUpdate.UTC().Add(time.Duration(blizzard.WowTokenGracePeriod) * time.Minute)
+	render := func() (*bytes.Buffer, time.Time, error) {
+		return h.blizzard.GeneratePriceChart(region, unit, int(period), overlays, format)
+	}
 
+	// Charts only change when a new price is stored, so the time of the latest price decides
+	// whether a cached chart can be used
+	latest, err := h.blizzard.GetLatestTokenPrice(region)
 	if err != nil {
-		log.Printf("failed to generate price chart for request: %v", err)
-		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("failed to get latest token price for chart request: %v", err)
+		}
+
+		chart, lastUpdate, err := render()
+		if err != nil {
+			writePlaceholderChart(w, format, err)
+			return
+		}
+
+		writeChart(w, format, chart.Bytes(), lastUpdate)
 		return
 	}
 
-	secondsUntilUpdate := int64(time.Until(nextUpdate.UTC()).Seconds())
+	key := chartCacheKey(
+		region,
+		unit,
+		period,
+		format,
+		overlays.Query().Encode(),
+		latest.Updated.UnixNano(),
+	)
+	etag := `"` + key + `"`
 
-	w.Header().Set("Content-Type", "image/png")
-	w.Header().Set("Vary", "accept")
-	w.Header().
-		Set("Cache-Control", fmt.Sprintf("public, max-age=%d", secondsUntilUpdate))
-	w.Header().Set("Last-Modified", lastUpdate.UTC().Format(http.TimeFormat))
+	if notModified(req, etag, latest.Updated) {
+		w.Header().Set("ETag", etag)
+		setCacheHeaders(w, latest.Updated)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	chart, err := h.charts.get(key, render)
+	if err != nil {
+		writePlaceholderChart(w, format, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	writeChart(w, format, chart.data, latest.Updated)
+}
+
+func writeChart(w http.ResponseWriter, format blizzard.ChartFormat, data []byte, lastUpdate time.Time) {
+	w.Header().Set("Content-Type", format.ContentType())
+	setCacheHeaders(w, lastUpdate)
 	w.WriteHeader(http.StatusOK)
-	_, err = chart.WriteTo(w)
+	_, err := w.Write(data)
 	if err != nil {
 		log.Printf("Failed while outputting WoW token graph image\n%v\n", err)
 	}
 }
+
+// writePlaceholderChart responds with an image explaining why the chart is missing. It is sent
+// with a 200 status, as Discord shows a broken image for anything else, but is never cached.
+func writePlaceholderChart(w http.ResponseWriter, format blizzard.ChartFormat, err error) {
+	message := "WoW token chart unavailable, try again later"
+	if errors.Is(err, blizzard.ErrNotEnoughPriceHistory) {
+		message = "Not enough WoW token price history for this chart yet"
+	} else {
+		log.Printf("failed to generate price chart for request: %v", err)
+	}
+
+	placeholder, err := blizzard.RenderPlaceholderChart(message, format)
+	if err != nil {
+		log.Printf("failed to render placeholder chart: %v", err)
+		http.Error(w, "500 Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if _, err := placeholder.WriteTo(w); err != nil {
+		log.Printf("Failed while outputting placeholder chart image\n%v\n", err)
+	}
+}
+
+// notModified checks the conditional headers of a request. If-None-Match takes precedence over
+// If-Modified-Since, as required by RFC 9110.
+func notModified(req *http.Request, etag string, lastModified time.Time) bool {
+	if match := req.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	since, err := http.ParseTime(req.Header.Get("If-Modified-Since"))
+	if err != nil {
+		return false
+	}
+
+	// HTTP dates only have a precision of one second
+	return !lastModified.Truncate(time.Second).After(since)
+}
+
+// chartFormat picks the image format for a chart request. A ".svg" or ".png" suffix on the period
+// takes precedence, otherwise SVG is only served to clients that explicitly prefer it, as Discord
+// embeds need PNG images. The period is returned without its suffix.
+func chartFormat(req *http.Request) (string, blizzard.ChartFormat) {
+	period := req.PathValue("period")
+
+	if p, found := strings.CutSuffix(period, ".svg"); found {
+		return p, blizzard.ChartFormatSVG
+	}
+
+	if p, found := strings.CutSuffix(period, ".png"); found {
+		return p, blizzard.ChartFormatPNG
+	}
+
+	svg, explicitSVG := acceptQuality(req.Header.Values("Accept"), "image/svg+xml")
+	png, explicitPNG := acceptQuality(req.Header.Values("Accept"), "image/png")
+
+	if explicitSVG && svg > 0 && (svg > png || !explicitPNG) {
+		return period, blizzard.ChartFormatSVG
+	}
+
+	return period, blizzard.ChartFormatPNG
+}
+
+// acceptQuality returns the quality value given to a media type by the Accept header, and whether
+// the media type was listed explicitly rather than matched by a wildcard
+func acceptQuality(accept []string, mediaType string) (quality float64, explicit bool) {
+	category, _, _ := strings.Cut(mediaType, "/")
+	specificity := -1
+
+	for _, header := range accept {
+		for _, entry := range strings.Split(header, ",") {
+			params := strings.Split(entry, ";")
+			mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+
+			var s int
+			switch mediaRange {
+			case mediaType:
+				s = 2
+			case category + "/*":
+				s = 1
+			case "*/*":
+				s = 0
+			default:
+				continue
+			}
+
+			if s <= specificity {
+				continue
+			}
+
+			q := 1.0
+			for _, param := range params[1:] {
+				name, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if name == "q" {
+					if v, err := strconv.ParseFloat(value, 64); err == nil {
+						q = v
+					}
+				}
+			}
+
+			specificity = s
+			quality = q
+		}
+	}
+
+	return quality, specificity == 2
+}
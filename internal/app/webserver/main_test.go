@@ -0,0 +1,205 @@
+package webserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/app/blizzard"
+	"github.com/aloop/discord-bot/internal/pkg/config"
+	"github.com/aloop/discord-bot/internal/pkg/secrets"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+func newTestHandler(db *fakes.DB) *handlerData {
+	return &handlerData{
+		blizzard: blizzard.New(
+			context.Background(),
+			&config.Config{Blizzard: config.BlizzardConfig{Regions: []string{"us"}}},
+			&secrets.Secrets{},
+			storage.NewPostgres(db),
+		),
+		charts: newChartCache(8, ""),
+	}
+}
+
+// setTokenPrices stores prices an hour apart, the newest one at updated
+func setTokenPrices(db *fakes.DB, updated time.Time, prices ...int64) {
+	db.SetRows("GetLatestTokenPrice", database.WowTokenPrice{
+		ID:      1,
+		Updated: pgtype.Timestamptz{Time: updated, Valid: true},
+		Price:   prices[len(prices)-1],
+		Region:  "us",
+	})
+
+	// Newest first, as the query orders them
+	rows := make([]any, 0, len(prices))
+	for i := len(prices) - 1; i >= 0; i-- {
+		rows = append(rows, database.GetAllTokenPricesSinceRow{
+			Price: prices[i],
+			Updated: pgtype.Timestamptz{
+				Time:  updated.Add(-time.Duration(len(prices)-1-i) * time.Hour),
+				Valid: true,
+			},
+		})
+	}
+	db.SetRows("GetAllTokenPricesSince", rows...)
+}
+
+func chartRequest(period string, header http.Header) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/wow-token/chart/us/hours/"+period, nil)
+	req.SetPathValue("region", "us")
+	req.SetPathValue("unit", "hours")
+	req.SetPathValue("period", period)
+
+	for name, values := range header {
+		req.Header[name] = values
+	}
+
+	return req
+}
+
+func TestHandleChartRequestCaches(t *testing.T) {
+	db := fakes.NewDB()
+	setTokenPrices(db, time.Now().Add(-time.Minute), 250_000, 260_000, 255_000)
+	h := newTestHandler(db)
+
+	first := httptest.NewRecorder()
+	h.handleChartRequest(first, chartRequest("24", nil))
+
+	second := httptest.NewRecorder()
+	h.handleChartRequest(second, chartRequest("24", nil))
+
+	if first.Code != http.StatusOK || second.Code != http.StatusOK {
+		t.Fatalf("expected both charts to be served, got %d and %d", first.Code, second.Code)
+	}
+
+	if n := len(db.Calls("GetAllTokenPricesSince")); n != 1 {
+		t.Errorf("expected the chart to be rendered once, rendered %d times", n)
+	}
+
+	etag := first.Header().Get("ETag")
+	if etag == "" || second.Header().Get("ETag") != etag {
+		t.Fatalf("expected both responses to have the same ETag, got %q and %q",
+			etag, second.Header().Get("ETag"))
+	}
+
+	revalidated := httptest.NewRecorder()
+	h.handleChartRequest(revalidated, chartRequest("24", http.Header{"If-None-Match": {etag}}))
+
+	if revalidated.Code != http.StatusNotModified || revalidated.Body.Len() != 0 {
+		t.Errorf("expected a matching ETag to be not modified, got %d with %d bytes",
+			revalidated.Code, revalidated.Body.Len())
+	}
+
+	if revalidated.Header().Get("ETag") != etag {
+		t.Errorf("expected the not modified response to repeat the ETag")
+	}
+}
+
+func TestNotModified(t *testing.T) {
+	lastModified := time.Date(2024, 6, 1, 12, 0, 0, 500_000_000, time.UTC)
+	before := lastModified.Add(-time.Hour).Format(http.TimeFormat)
+	at := lastModified.Format(http.TimeFormat)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		expect bool
+	}{
+		{"no conditional headers", http.Header{}, false},
+		{"matching etag", http.Header{"If-None-Match": {`"abc"`}}, true},
+		{"matching weak etag in a list", http.Header{"If-None-Match": {`"x", W/"abc"`}}, true},
+		{"any etag", http.Header{"If-None-Match": {"*"}}, true},
+		{"other etag", http.Header{"If-None-Match": {`"other"`}}, false},
+		{"modified since", http.Header{"If-Modified-Since": {before}}, false},
+		{"not modified since", http.Header{"If-Modified-Since": {at}}, true},
+		{"invalid date", http.Header{"If-Modified-Since": {"yesterday"}}, false},
+		{
+			"etag takes precedence over the date",
+			http.Header{"If-None-Match": {`"other"`}, "If-Modified-Since": {at}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header = tt.header
+
+			if got := notModified(req, `"abc"`, lastModified); got != tt.expect {
+				t.Errorf("expected %t, got %t", tt.expect, got)
+			}
+		})
+	}
+}
+
+func TestAcceptQuality(t *testing.T) {
+	tests := []struct {
+		name           string
+		accept         []string
+		expectQuality  float64
+		expectExplicit bool
+	}{
+		{"no header", nil, 0, false},
+		{"listed", []string{"image/svg+xml"}, 1, true},
+		{"listed with quality", []string{"image/png, image/svg+xml;q=0.5"}, 0.5, true},
+		{"any image", []string{"image/*;q=0.8"}, 0.8, false},
+		{"anything", []string{"*/*"}, 1, false},
+		{"most specific wins", []string{"image/svg+xml;q=0.2, image/*, */*"}, 0.2, true},
+		{"across headers", []string{"text/html", "IMAGE/SVG+XML;q=0.9"}, 0.9, true},
+		{"not listed", []string{"text/html, image/png"}, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quality, explicit := acceptQuality(tt.accept, "image/svg+xml")
+			if quality != tt.expectQuality || explicit != tt.expectExplicit {
+				t.Errorf("expected %v %t, got %v %t",
+					tt.expectQuality, tt.expectExplicit, quality, explicit)
+			}
+		})
+	}
+}
+
+func TestChartFormat(t *testing.T) {
+	tests := []struct {
+		name         string
+		period       string
+		accept       string
+		expectPeriod string
+		expectFormat blizzard.ChartFormat
+	}{
+		{"no header", "24", "", "24", blizzard.ChartFormatPNG},
+		// Discord sends wildcards, and needs PNG images for its embeds
+		{"anything", "24", "*/*", "24", blizzard.ChartFormatPNG},
+		{"any image", "24", "image/*", "24", blizzard.ChartFormatPNG},
+		{"svg", "24", "image/svg+xml", "24", blizzard.ChartFormatSVG},
+		{"svg among wildcards", "24", "image/svg+xml, */*;q=0.8", "24", blizzard.ChartFormatSVG},
+		{"png preferred", "24", "image/png, image/svg+xml;q=0.5", "24", blizzard.ChartFormatPNG},
+		{"svg refused", "24", "image/svg+xml;q=0", "24", blizzard.ChartFormatPNG},
+		{"svg suffix", "24.svg", "image/png", "24", blizzard.ChartFormatSVG},
+		{"png suffix", "24.png", "image/svg+xml", "24", blizzard.ChartFormatPNG},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			header := http.Header{}
+			if tt.accept != "" {
+				header.Set("Accept", tt.accept)
+			}
+
+			period, format := chartFormat(chartRequest(tt.period, header))
+			if period != tt.expectPeriod || format != tt.expectFormat {
+				t.Errorf("expected %s as %v, got %s as %v",
+					tt.expectPeriod, tt.expectFormat, period, format)
+			}
+		})
+	}
+}
@@ -7,12 +7,25 @@ import (
 	"io/fs"
 	"log"
 	"os"
+
+	"github.com/aloop/discord-bot/internal/pkg/scheduler"
 )
 
 type Config struct {
+	Discord        DiscordConfig        `json:"discord"`
 	HTTP           HTTPConfig           `json:"http"`
 	Blizzard       BlizzardConfig       `json:"blizzard"`
+	FreeGames      FreeGamesConfig      `json:"freeGames"`
 	EpicGamesStore EpicGamesStoreConfig `json:"epicGamesStore"`
+	GOG            GOGConfig            `json:"gog"`
+	Steam          SteamConfig          `json:"steam"`
+	// Schedules of the background jobs, keyed by job name
+	Jobs map[string]JobConfig `json:"jobs"`
+}
+
+type DiscordConfig struct {
+	// How interactions are received, either "gateway" or "http"
+	Interactions string `json:"interactions"`
 }
 
 type HTTPConfig struct {
@@ -20,34 +33,141 @@ type HTTPConfig struct {
 	SocketPermissions string `json:"socketPermissions"`
 	ListenHost        string `json:"listenHost"`
 	ListenPort        int    `json:"listenPort"`
+	// Number of rendered charts kept in memory
+	ChartCacheSize int `json:"chartCacheSize"`
+	// Directory to additionally keep rendered charts in, disabled when empty
+	ChartCacheDir string `json:"chartCacheDir"`
 }
 
 type BlizzardConfig struct {
-	AuthTokenUrl  string `json:"authTokenUrl"`
+	AuthTokenUrl string `json:"authTokenUrl"`
+	// Per-region overrides for AuthTokenUrl, mainly needed for the CN region
+	AuthTokenUrls  map[string]string `json:"authTokenUrls"`
+	TokenPriceUrls map[string]string `json:"tokenPriceUrls"`
+	// Regions to track token prices for. The first region is used as the default.
+	Regions []string `json:"regions"`
+	// Deprecated: replaced by TokenPriceUrls, only read to keep older configs working
 	TokenPriceUrl string `json:"tokenPriceUrl"`
+	// How far, in percent of the threshold, the price has to move back before an alert re-arms
+	AlertRearmPercent int64 `json:"alertRearmPercent"`
+	// Maximum number of price alerts a single user may have
+	MaxAlertsPerUser int `json:"maxAlertsPerUser"`
+}
+
+// Settings shared by the free games of every store
+type FreeGamesConfig struct {
+	// Hours before a free game's promotion ends to post a reminder, disabled when 0
+	ReminderHours int `json:"reminderHours"`
 }
 
 type EpicGamesStoreConfig struct {
 	ProductBaseUrl  string `json:"productBaseUrl"`
 	FreeGamesApiUrl string `json:"freeGamesApiUrl"`
+	// Deprecated: replaced by FreeGames.ReminderHours, only read to keep older configs working
+	ReminderHours *int `json:"reminderHours"`
+}
+
+type GOGConfig struct {
+	// Whether games given away on GOG are announced
+	Enabled        bool   `json:"enabled"`
+	ProductBaseUrl string `json:"productBaseUrl"`
+	// Catalog search listing the games discounted to a price of 0
+	CatalogApiUrl string `json:"catalogApiUrl"`
+}
+
+type SteamConfig struct {
+	// Whether games discounted by 100% on Steam are announced
+	Enabled    bool   `json:"enabled"`
+	AppBaseUrl string `json:"appBaseUrl"`
+	// Featured categories endpoint of the store API, whose specials are searched for free games
+	FeaturedApiUrl string `json:"featuredApiUrl"`
+}
+
+type JobConfig struct {
+	// A cron expression such as "0 * * * *", or an interval such as "@every 5m"
+	Schedule string `json:"schedule"`
+	// Whether the job also runs as soon as the bot starts running jobs, which is left unset to
+	// keep the default of the job
+	RunOnStart *bool `json:"runOnStart"`
+}
+
+// ShouldRunOnStart reports whether the job runs as soon as the bot starts running jobs
+func (job JobConfig) ShouldRunOnStart() bool {
+	return job.RunOnStart != nil && *job.RunOnStart
+}
+
+const (
+	InteractionsGateway string = "gateway"
+	InteractionsHTTP    string = "http"
+)
+
+// Names of the background jobs
+const (
+	JobWowTokenPrices    string = "wowTokenPrices"
+	JobFreeGames         string = "freeGames"
+	JobFreeGameReminders string = "freeGameReminders"
+	JobFreeGameExpiry    string = "freeGameExpiry"
+	JobFreeGameOutbox    string = "freeGameOutbox"
+)
+
+// defaultJobs returns the schedules of the background jobs, which jobs given in the config are
+// merged with
+func defaultJobs() map[string]JobConfig {
+	runOnStart := true
+
+	return map[string]JobConfig{
+		JobWowTokenPrices:    {Schedule: "@every 5m", RunOnStart: &runOnStart},
+		JobFreeGames:         {Schedule: "@every 1h", RunOnStart: &runOnStart},
+		JobFreeGameReminders: {Schedule: "@every 10m"},
+		JobFreeGameExpiry:    {Schedule: "@every 10m"},
+		JobFreeGameOutbox:    {Schedule: "@every 1m"},
+	}
 }
 
 func New(path string) *Config {
 	config := &Config{
+		Discord: DiscordConfig{
+			Interactions: InteractionsGateway,
+		},
 		HTTP: HTTPConfig{
 			Host:              "http://localhost",
 			ListenHost:        "127.0.0.1",
 			ListenPort:        5000,
 			SocketPermissions: "0666", // User: rw, Group: rw, Other: rw
+			ChartCacheSize:    128,
 		},
 		Blizzard: BlizzardConfig{
-			AuthTokenUrl:  "https://us.battle.net/oauth/token?grant_type=client_credentials",
-			TokenPriceUrl: "https://us.api.blizzard.com/data/wow/token/index?namespace=dynamic-us",
+			AuthTokenUrl: "https://us.battle.net/oauth/token?grant_type=client_credentials",
+			AuthTokenUrls: map[string]string{
+				"cn": "https://oauth.battlenet.com.cn/token?grant_type=client_credentials",
+			},
+			TokenPriceUrls: map[string]string{
+				"us": "https://us.api.blizzard.com/data/wow/token/index?namespace=dynamic-us",
+				"eu": "https://eu.api.blizzard.com/data/wow/token/index?namespace=dynamic-eu",
+				"kr": "https://kr.api.blizzard.com/data/wow/token/index?namespace=dynamic-kr",
+				"tw": "https://tw.api.blizzard.com/data/wow/token/index?namespace=dynamic-tw",
+				"cn": "https://gateway.battlenet.com.cn/data/wow/token/index?namespace=dynamic-cn",
+			},
+			Regions:           []string{"us"},
+			AlertRearmPercent: 2,
+			MaxAlertsPerUser:  10,
+		},
+		FreeGames: FreeGamesConfig{
+			ReminderHours: 24,
 		},
 		EpicGamesStore: EpicGamesStoreConfig{
 			ProductBaseUrl:  "https://www.epicgames.com/store/en-US/product/",
 			FreeGamesApiUrl: "https://store-site-backend-static.ak.epicgames.com/freeGamesPromotions?locale=en-US&country=US&allowCountries=US",
 		},
+		GOG: GOGConfig{
+			ProductBaseUrl: "https://www.gog.com/en/game/",
+			CatalogApiUrl:  "https://catalog.gog.com/v1/catalog?limit=48&price=between:0,0&discounted=eq:true&productType=in:game,pack&countryCode=US&locale=en-US&currencyCode=USD",
+		},
+		Steam: SteamConfig{
+			AppBaseUrl:     "https://store.steampowered.com/app/",
+			FeaturedApiUrl: "https://store.steampowered.com/api/featuredcategories?cc=US&l=english",
+		},
+		Jobs: defaultJobs(),
 	}
 
 	config.Load(path)
@@ -74,6 +194,11 @@ func (config *Config) Load(path string) {
 }
 
 func (config *Config) ValidateConfig() {
+	if config.Discord.Interactions != InteractionsGateway &&
+		config.Discord.Interactions != InteractionsHTTP {
+		log.Fatal(`Config: Discord interactions must be one of "gateway" or "http"! Exiting...`)
+	}
+
 	if config.HTTP.Host == "" {
 		log.Fatal("Config: HTTP host not set! Exiting...")
 	}
@@ -90,12 +215,36 @@ func (config *Config) ValidateConfig() {
 		log.Fatal("Config: HTTP listen port not set! Exiting...")
 	}
 
+	if config.HTTP.ChartCacheSize < 1 {
+		log.Fatal("Config: HTTP chart cache size must be at least 1! Exiting...")
+	}
+
 	if config.Blizzard.AuthTokenUrl == "" {
 		log.Fatal("Config: Blizzard auth token url not set! Exiting...")
 	}
 
-	if config.Blizzard.TokenPriceUrl == "" {
-		log.Fatal("Config: Blizzard token price url not set! Exiting...")
+	if len(config.Blizzard.Regions) == 0 {
+		log.Fatal("Config: No Blizzard regions set! Exiting...")
+	}
+
+	if config.Blizzard.TokenPriceUrl != "" {
+		defaultRegion := config.Blizzard.Regions[0]
+		log.Printf(
+			"Config: Blizzard \"tokenPriceUrl\" is deprecated, using it as the token price url "+
+				"of the default region \"%s\". Move it to \"tokenPriceUrls\" instead.",
+			defaultRegion,
+		)
+
+		if config.Blizzard.TokenPriceUrls == nil {
+			config.Blizzard.TokenPriceUrls = make(map[string]string)
+		}
+		config.Blizzard.TokenPriceUrls[defaultRegion] = config.Blizzard.TokenPriceUrl
+	}
+
+	for _, region := range config.Blizzard.Regions {
+		if config.Blizzard.TokenPriceUrls[region] == "" {
+			log.Fatalf("Config: Blizzard token price url for region \"%s\" not set! Exiting...", region)
+		}
 	}
 
 	if config.EpicGamesStore.ProductBaseUrl == "" {
@@ -105,4 +254,45 @@ func (config *Config) ValidateConfig() {
 	if config.EpicGamesStore.FreeGamesApiUrl == "" {
 		log.Fatal("Config: Epic Games Store free games api url not set! Exiting...")
 	}
+
+	if config.EpicGamesStore.ReminderHours != nil {
+		log.Print(
+			"Config: Epic Games Store \"reminderHours\" is deprecated, as it applies to every " +
+				"store. Move it to \"freeGames\" instead.",
+		)
+		config.FreeGames.ReminderHours = *config.EpicGamesStore.ReminderHours
+	}
+
+	if config.FreeGames.ReminderHours < 0 {
+		log.Fatal("Config: Free game reminder hours cannot be negative! Exiting...")
+	}
+
+	if config.GOG.Enabled && (config.GOG.ProductBaseUrl == "" || config.GOG.CatalogApiUrl == "") {
+		log.Fatal("Config: GOG product base url or catalog api url not set! Exiting...")
+	}
+
+	if config.Steam.Enabled && (config.Steam.AppBaseUrl == "" || config.Steam.FeaturedApiUrl == "") {
+		log.Fatal("Config: Steam app base url or featured api url not set! Exiting...")
+	}
+
+	defaults := defaultJobs()
+	for name, job := range config.Jobs {
+		defaultJob, ok := defaults[name]
+		if !ok {
+			log.Fatalf("Config: Unknown job \"%s\"! Exiting...", name)
+		}
+
+		// A job given in the config replaces its default, so whatever it leaves out is restored
+		if job.Schedule == "" {
+			job.Schedule = defaultJob.Schedule
+		}
+		if job.RunOnStart == nil {
+			job.RunOnStart = defaultJob.RunOnStart
+		}
+		config.Jobs[name] = job
+
+		if _, err := scheduler.Parse(job.Schedule); err != nil {
+			log.Fatalf("Config: Invalid schedule for job \"%s\": %v! Exiting...", name, err)
+		}
+	}
 }
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	return path
+}
+
+func TestDeprecatedTokenPriceUrl(t *testing.T) {
+	config := New(writeConfig(t,
+		`{"blizzard": {"regions": ["eu", "us"], "tokenPriceUrl": "https://example.com/eu"}}`))
+
+	if got := config.Blizzard.TokenPriceUrls["eu"]; got != "https://example.com/eu" {
+		t.Errorf("token price url of the default region = %q, want the deprecated url", got)
+	}
+
+	if got := config.Blizzard.TokenPriceUrls["us"]; got == "https://example.com/eu" {
+		t.Errorf("token price url of other regions was replaced")
+	}
+}
+
+func TestDeprecatedReminderHours(t *testing.T) {
+	if got := New(writeConfig(t, `{}`)).FreeGames.ReminderHours; got != 24 {
+		t.Errorf("default reminder hours = %d, want 24", got)
+	}
+
+	// Reminders are disabled with 0, which has to be told apart from the key being left out
+	config := New(writeConfig(t, `{"epicGamesStore": {"reminderHours": 0}}`))
+	if got := config.FreeGames.ReminderHours; got != 0 {
+		t.Errorf("reminder hours = %d, want the deprecated setting of 0", got)
+	}
+}
+
+func TestJobsKeepDefaults(t *testing.T) {
+	config := New(writeConfig(t, `{"jobs": {
+		"wowTokenPrices": {"schedule": "@every 10m"},
+		"freeGames": {"runOnStart": false},
+		"freeGameOutbox": {"schedule": "@every 5m", "runOnStart": true}
+	}}`))
+
+	tests := []struct {
+		name             string
+		expectSchedule   string
+		expectRunOnStart bool
+	}{
+		{JobWowTokenPrices, "@every 10m", true},
+		{JobFreeGames, "@every 1h", false},
+		{JobFreeGameReminders, "@every 10m", false},
+		{JobFreeGameOutbox, "@every 5m", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job := config.Jobs[tt.name]
+			if job.Schedule != tt.expectSchedule || job.ShouldRunOnStart() != tt.expectRunOnStart {
+				t.Errorf("job = %q, run on start %t, want %q, run on start %t",
+					job.Schedule, job.ShouldRunOnStart(), tt.expectSchedule, tt.expectRunOnStart)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Package leader elects a single instance of the bot to run the background jobs, so replicas
+// sharing a database do not fetch and post everything more than once
+package leader
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+// Leases hands out the leadership, such as storage.DB
+type Leases interface {
+	TryAcquireLeadership(ctx context.Context) (storage.Lease, error)
+}
+
+// Run campaigns for the leadership in the background until ctx is done, trying again every
+// period while another instance leads. Whenever this instance becomes the leader, lead is called
+// with a context that is cancelled once the leadership is lost, which is checked every period.
+// lead should start its work in the background and return a function waiting for the work to
+// stop once its context is cancelled.
+//
+// The returned function waits for Run to stop once ctx is done, which is after the work of lead
+// stopped and the lease was released.
+func Run(
+	ctx context.Context,
+	leases Leases,
+	period time.Duration,
+	lead func(ctx context.Context) (wait func()),
+) (wait func()) {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for {
+			lease, err := leases.TryAcquireLeadership(ctx)
+			if err != nil {
+				log.Printf("Leader Election: Failed to acquire the leadership\n%v", err)
+			} else if lease != nil {
+				log.Println("Leader Election: This instance is now the leader")
+				hold(ctx, lease, period, lead)
+				log.Println("Leader Election: This instance is no longer the leader")
+			}
+
+			select {
+			case <-time.After(period):
+			case <-ctx.Done():
+				log.Println("Leader Election: stopping")
+				return
+			}
+		}
+	}()
+
+	return func() { <-done }
+}
+
+// hold runs lead for as long as the lease is held
+func hold(
+	ctx context.Context,
+	lease storage.Lease,
+	period time.Duration,
+	lead func(ctx context.Context) (wait func()),
+) {
+	defer lease.Release()
+
+	leaderCtx, stopLeading := context.WithCancel(ctx)
+	wait := lead(leaderCtx)
+
+	// The work stops before the lease is released, so it does not overlap with the next leader
+	// once this instance gives up the leadership. A lost lease can only be noticed at the next
+	// Check though, until which the next leader may already be running the same jobs. Jobs that
+	// post anything claim their rows first, so the overlap does not post twice.
+	defer func() {
+		stopLeading()
+		wait()
+	}()
+
+	ticker := time.NewTicker(period)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := lease.Check(ctx); err != nil {
+				log.Printf("Leader Election: Lost the leadership\n%v", err)
+				return
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
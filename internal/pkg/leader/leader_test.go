@@ -0,0 +1,226 @@
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+const testPeriod = 5 * time.Millisecond
+
+// fakeLeases hands out the leadership once another instance stopped leading after the given
+// number of attempts
+type fakeLeases struct {
+	mu       sync.Mutex
+	attempts int
+	busy     int
+	leases   []*fakeLease
+}
+
+func (l *fakeLeases) TryAcquireLeadership(context.Context) (storage.Lease, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.attempts++
+	if l.attempts <= l.busy {
+		return nil, nil
+	}
+
+	lease := &fakeLease{}
+	l.leases = append(l.leases, lease)
+
+	return lease, nil
+}
+
+func (l *fakeLeases) lease(i int) *fakeLease {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.leases[i]
+}
+
+type fakeLease struct {
+	mu       sync.Mutex
+	lost     bool
+	released bool
+}
+
+func (l *fakeLease) Check(context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.lost {
+		return errors.New("connection dropped")
+	}
+
+	return nil
+}
+
+func (l *fakeLease) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.released = true
+}
+
+func (l *fakeLease) isReleased() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.released
+}
+
+func (l *fakeLease) lose() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.lost = true
+}
+
+// leading returns a lead function that sends each context it leads with
+func leading() (func(ctx context.Context) func(), chan context.Context) {
+	led := make(chan context.Context, 10)
+	return func(ctx context.Context) func() {
+		led <- ctx
+		return func() {}
+	}, led
+}
+
+func waitFor(t *testing.T, led chan context.Context) context.Context {
+	t.Helper()
+
+	select {
+	case ctx := <-led:
+		return ctx
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting to become the leader")
+		return nil
+	}
+}
+
+func waitForDone(t *testing.T, ctx context.Context) {
+	t.Helper()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the leadership to end")
+	}
+}
+
+func TestRunFailsOver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	leases := &fakeLeases{busy: 2}
+	lead, led := leading()
+	Run(ctx, leases, testPeriod, lead)
+
+	first := waitFor(t, led)
+	leases.mu.Lock()
+	if leases.attempts != 3 {
+		t.Errorf("expected to lead after the other instance did, took %d attempts", leases.attempts)
+	}
+	leases.mu.Unlock()
+
+	leases.lease(0).lose()
+	waitForDone(t, first)
+
+	second := waitFor(t, led)
+	if !leases.lease(0).isReleased() {
+		t.Error("expected the lost lease to be released")
+	}
+
+	if second.Err() != nil {
+		t.Error("expected to lead again with a new lease")
+	}
+}
+
+func TestRunStopsLeading(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	leases := &fakeLeases{}
+	lead, led := leading()
+	Run(ctx, leases, testPeriod, lead)
+
+	leaderCtx := waitFor(t, led)
+	cancel()
+	waitForDone(t, leaderCtx)
+
+	deadline := time.Now().Add(time.Second)
+	for !leases.lease(0).isReleased() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the lease to be released once stopped")
+		}
+		time.Sleep(testPeriod)
+	}
+}
+
+func TestRunWaitsForWorkBeforeReleasing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	leases := &fakeLeases{}
+	led := make(chan context.Context, 1)
+	stopped := make(chan struct{})
+	Run(ctx, leases, testPeriod, func(ctx context.Context) func() {
+		led <- ctx
+		return func() { <-stopped }
+	})
+
+	leaderCtx := waitFor(t, led)
+	cancel()
+	waitForDone(t, leaderCtx)
+
+	time.Sleep(10 * testPeriod)
+	if leases.lease(0).isReleased() {
+		t.Fatal("expected the lease to be held until the work stopped")
+	}
+
+	close(stopped)
+
+	deadline := time.Now().Add(time.Second)
+	for !leases.lease(0).isReleased() {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the lease to be released once the work stopped")
+		}
+		time.Sleep(testPeriod)
+	}
+}
+
+func TestRunWaitsForLeadBeforeReturning(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	leases := &fakeLeases{}
+	led := make(chan context.Context, 1)
+	var mu sync.Mutex
+	stopped := false
+	wait := Run(ctx, leases, testPeriod, func(ctx context.Context) func() {
+		led <- ctx
+		return func() {
+			<-ctx.Done()
+			time.Sleep(10 * testPeriod)
+
+			mu.Lock()
+			defer mu.Unlock()
+			stopped = true
+		}
+	})
+
+	waitFor(t, led)
+	cancel()
+	wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !stopped {
+		t.Error("expected the work to have stopped once Run returned")
+	}
+
+	if !leases.lease(0).isReleased() {
+		t.Error("expected the lease to be released once Run returned")
+	}
+}
@@ -0,0 +1,172 @@
+package migrations
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Arbitrary key for the advisory lock held while migrating, so multiple instances starting at
+// the same time don't apply the same migrations concurrently
+const lockKey int64 = 7_349_201_553
+
+// Postgres migrations live in sql/, SQLite migrations in sqlite/
+//
+//go:embed sql/*.sql sqlite/*.sql
+var files embed.FS
+
+type migration struct {
+	version int64
+	name    string
+	sql     string
+}
+
+// load reads the embedded migrations in dir, which are named "<version>_<name>.sql"
+func load(dir string) ([]migration, error) {
+	entries, err := fs.ReadDir(files, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]migration, 0, len(entries))
+	seen := make(map[int64]string, len(entries))
+
+	for _, entry := range entries {
+		name := strings.TrimSuffix(entry.Name(), ".sql")
+
+		versionStr, _, found := strings.Cut(name, "_")
+		if !found {
+			return nil, fmt.Errorf(`migration "%s" is not named "<version>_<name>.sql"`, entry.Name())
+		}
+
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf(`migration "%s" has an invalid version: %w`, entry.Name(), err)
+		}
+
+		if other, ok := seen[version]; ok {
+			return nil, fmt.Errorf(
+				`migrations "%s" and "%s" share version %d`,
+				other,
+				entry.Name(),
+				version,
+			)
+		}
+		seen[version] = entry.Name()
+
+		contents, err := files.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{
+			version: version,
+			name:    name,
+			sql:     string(contents),
+		})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// Run applies every migration that has not yet been recorded in the schema_migrations table.
+// Each migration runs in its own transaction.
+func Run(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := load("sql")
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Use a fresh context, as the lock must be released even if ctx was cancelled
+		if _, err := conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey); err != nil {
+			log.Printf("Migrations: Failed to release migration lock\n%v", err)
+		}
+	}()
+
+	_, err = conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    BIGINT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %w", err)
+	}
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		log.Printf("Migrations: Applying %s", m.name)
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx, m.sql); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to apply migration %s: %w", m.name, err)
+		}
+
+		_, err = tx.Exec(
+			ctx,
+			"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+			m.version,
+			m.name,
+		)
+		if err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to record migration %s: %w", m.name, err)
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit migration %s: %w", m.name, err)
+		}
+	}
+
+	return nil
+}
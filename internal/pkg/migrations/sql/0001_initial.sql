@@ -13,4 +13,4 @@ CREATE TABLE IF NOT EXISTS egs_free_games (
     thumbnail_url TEXT NOT NULL,
     start_date    TIMESTAMP WITH TIME ZONE NOT NULL,
     end_date      TIMESTAMP WITH TIME ZONE NOT NULL
-);
\ No newline at end of file
+);
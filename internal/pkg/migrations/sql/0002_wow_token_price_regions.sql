@@ -0,0 +1,5 @@
+ALTER TABLE wow_token_prices ADD COLUMN IF NOT EXISTS region TEXT NOT NULL DEFAULT 'us';
+ALTER TABLE wow_token_prices DROP CONSTRAINT IF EXISTS wow_token_prices_updated_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS wow_token_prices_region_updated_idx
+    ON wow_token_prices (region, updated);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS wow_token_alerts (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    TEXT    NOT NULL,
+    guild_id   TEXT    NOT NULL,
+    region     TEXT    NOT NULL,
+    direction  TEXT    NOT NULL,
+    threshold  BIGINT  NOT NULL,
+    -- Set once the alert fires, cleared when the price moves back past the threshold
+    triggered  BOOLEAN NOT NULL DEFAULT FALSE,
+    created    TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS wow_token_alerts_region_idx ON wow_token_alerts (region);
@@ -0,0 +1,27 @@
+CREATE TABLE IF NOT EXISTS guild_settings (
+    guild_id          TEXT PRIMARY KEY,
+    deals_channel_id  TEXT NOT NULL DEFAULT '',
+    alerts_channel_id TEXT NOT NULL DEFAULT '',
+    region            TEXT NOT NULL DEFAULT '',
+    locale            TEXT NOT NULL DEFAULT '',
+    ping_role_id      TEXT NOT NULL DEFAULT '',
+    updated           TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+-- Free games are announced in every configured deals channel, so announcements move out of
+-- egs_free_games into a table of their own
+CREATE TABLE IF NOT EXISTS egs_free_game_announcements (
+    id           BIGSERIAL PRIMARY KEY,
+    free_game_id BIGINT  NOT NULL REFERENCES egs_free_games (id) ON DELETE CASCADE,
+    channel_id   TEXT    NOT NULL,
+    message_id   TEXT    NOT NULL,
+    expired      BOOLEAN NOT NULL DEFAULT FALSE
+);
+
+INSERT INTO egs_free_game_announcements (free_game_id, channel_id, message_id, expired)
+SELECT id, channel_id, message_id, expired FROM egs_free_games WHERE message_id <> '';
+
+ALTER TABLE egs_free_games
+    DROP COLUMN IF EXISTS channel_id,
+    DROP COLUMN IF EXISTS message_id,
+    DROP COLUMN IF EXISTS expired;
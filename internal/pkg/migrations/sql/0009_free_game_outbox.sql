@@ -0,0 +1,19 @@
+-- Announcements waiting to be posted. Rows are queued along with the games they announce, and
+-- are retried with a backoff until Discord accepts them.
+CREATE TABLE IF NOT EXISTS egs_free_game_outbox (
+    id           BIGSERIAL PRIMARY KEY,
+    free_game_id BIGINT                   NOT NULL REFERENCES egs_free_games (id) ON DELETE CASCADE,
+    channel_id   TEXT                     NOT NULL,
+    -- Whether the game is announced as "live" or "upcoming", or is a "reminder" of its end
+    kind         TEXT                     NOT NULL,
+    -- Pinged by reminders when set
+    ping_role_id TEXT                     NOT NULL DEFAULT '',
+    message_id   TEXT                     NOT NULL DEFAULT '',
+    sent         BOOLEAN                  NOT NULL DEFAULT FALSE,
+    attempts     INTEGER                  NOT NULL DEFAULT 0,
+    next_attempt TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    created      TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS egs_free_game_outbox_pending_idx
+    ON egs_free_game_outbox (next_attempt) WHERE NOT sent;
@@ -0,0 +1,37 @@
+-- Timestamps are stored as unix milliseconds, as SQLite has no timestamp type that sorts and
+-- compares reliably across time zones
+
+CREATE TABLE IF NOT EXISTS wow_token_prices (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    updated    INTEGER NOT NULL,
+    price      INTEGER NOT NULL,
+    region     TEXT    NOT NULL DEFAULT 'us'
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS wow_token_prices_region_updated_idx
+    ON wow_token_prices (region, updated);
+
+CREATE TABLE IF NOT EXISTS egs_free_games (
+    id            INTEGER PRIMARY KEY AUTOINCREMENT,
+    store_id      TEXT    NOT NULL,
+    title         TEXT    NOT NULL,
+    description   TEXT    NOT NULL,
+    url           TEXT    NOT NULL,
+    thumbnail_url TEXT    NOT NULL,
+    start_date    INTEGER NOT NULL,
+    end_date      INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS wow_token_alerts (
+    id         INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id    TEXT    NOT NULL,
+    guild_id   TEXT    NOT NULL,
+    region     TEXT    NOT NULL,
+    direction  TEXT    NOT NULL,
+    threshold  INTEGER NOT NULL,
+    -- Set once the alert fires, cleared when the price moves back past the threshold
+    triggered  BOOLEAN NOT NULL DEFAULT FALSE,
+    created    INTEGER NOT NULL DEFAULT (CAST(unixepoch('subsec') * 1000 AS INTEGER))
+);
+
+CREATE INDEX IF NOT EXISTS wow_token_alerts_region_idx ON wow_token_alerts (region);
@@ -0,0 +1,3 @@
+-- Games are stored as "upcoming" when their promotion is announced, and become "live" once the
+-- promotion starts. Games stored before this migration were all live.
+ALTER TABLE egs_free_games ADD COLUMN status TEXT NOT NULL DEFAULT 'live';
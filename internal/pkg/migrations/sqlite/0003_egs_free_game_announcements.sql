@@ -0,0 +1,6 @@
+-- The message each game was announced in, so it can be marked as expired once the promotion
+-- ends. Games announced before this migration have no message and are never edited.
+ALTER TABLE egs_free_games ADD COLUMN channel_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE egs_free_games ADD COLUMN message_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE egs_free_games ADD COLUMN reminded BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE egs_free_games ADD COLUMN expired BOOLEAN NOT NULL DEFAULT FALSE;
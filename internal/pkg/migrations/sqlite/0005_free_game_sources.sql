@@ -0,0 +1,5 @@
+-- Free games come from several stores, games stored before this migration are all from Epic
+ALTER TABLE egs_free_games ADD COLUMN source TEXT NOT NULL DEFAULT 'epic';
+-- Whether the store did not say when the promotion ends, in which case end_date is pushed
+-- forward for as long as the store keeps listing the game
+ALTER TABLE egs_free_games ADD COLUMN open_ended BOOLEAN NOT NULL DEFAULT FALSE;
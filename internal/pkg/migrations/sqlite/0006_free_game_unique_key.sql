@@ -0,0 +1,21 @@
+-- Games could be stored more than once before this migration, so the announcements of every
+-- copy move to the first one before the other copies are removed
+UPDATE egs_free_game_announcements SET free_game_id = (
+    SELECT MIN(copy.id)
+    FROM egs_free_games game
+    JOIN egs_free_games copy
+        ON copy.source = game.source
+        AND copy.store_id = game.store_id
+        AND copy.start_date = game.start_date
+    WHERE game.id = egs_free_game_announcements.free_game_id
+);
+
+DELETE FROM egs_free_games
+WHERE id NOT IN (SELECT MIN(id) FROM egs_free_games GROUP BY source, store_id, start_date);
+
+CREATE UNIQUE INDEX IF NOT EXISTS egs_free_games_source_store_id_start_date_key
+    ON egs_free_games (source, store_id, start_date);
+
+-- Open-ended promotions are stored once per game instead, see FreeGame.Starts
+CREATE UNIQUE INDEX IF NOT EXISTS egs_free_games_open_ended_key
+    ON egs_free_games (source, store_id) WHERE open_ended;
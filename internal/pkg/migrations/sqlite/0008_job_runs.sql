@@ -0,0 +1,8 @@
+-- The latest run of each background job
+CREATE TABLE IF NOT EXISTS job_runs (
+    job         TEXT PRIMARY KEY,
+    started     INTEGER NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    -- Empty when the run succeeded
+    error       TEXT    NOT NULL DEFAULT ''
+);
@@ -0,0 +1,208 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule decides when a job runs
+type Schedule interface {
+	// Next returns the first time after t the job should run, or the zero time if it never runs
+	// again
+	Next(t time.Time) time.Time
+}
+
+// interval runs a job a fixed duration after its previous run
+type interval time.Duration
+
+func (i interval) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(i))
+}
+
+// Parse parses a schedule, which is either "@every" followed by an interval such as
+// "@every 5m", one of "@hourly", "@daily" or "@weekly", or a cron expression. Cron expressions
+// have five fields for the minute, hour, day of month, month and day of week, such as
+// "*/15 * * * *", each a comma separated list of values, ranges such as "1-5" or "*", optionally
+// followed by a step such as "/2". Times are in the local time zone.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	if every, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(strings.TrimSpace(every))
+		if err != nil {
+			return nil, fmt.Errorf("invalid interval in schedule %q: %w", spec, err)
+		}
+
+		if d < time.Second {
+			return nil, fmt.Errorf("interval in schedule %q is shorter than a second", spec)
+		}
+
+		return interval(d), nil
+	}
+
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	}
+
+	schedule, err := parseCron(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %w", spec, err)
+	}
+
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("schedule %q never runs", spec)
+	}
+
+	return schedule, nil
+}
+
+// cron is a parsed cron expression, with a bit set in each field for every value it matches
+type cron struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	// Whether either day field is "*", in which case a day has to match both fields, rather than
+	// either of them
+	anyDay bool
+}
+
+func parseCron(spec string) (*cron, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields, got %d", len(fields))
+	}
+
+	var c cron
+	var err error
+	bounds := []struct {
+		bits     *uint64
+		min, max int
+	}{
+		{&c.minute, 0, 59},
+		{&c.hour, 0, 23},
+		{&c.dayOfMonth, 1, 31},
+		{&c.month, 1, 12},
+		// Both 0 and 7 are Sunday
+		{&c.dayOfWeek, 0, 7},
+	}
+
+	for i, field := range fields {
+		*bounds[i].bits, err = parseField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if c.dayOfWeek&(1<<7) != 0 {
+		c.dayOfWeek = c.dayOfWeek&^(1<<7) | 1
+	}
+
+	c.anyDay = strings.HasPrefix(fields[2], "*") || strings.HasPrefix(fields[4], "*")
+
+	return &c, nil
+}
+
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(field, ",") {
+		values, stepValue, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepValue)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+		}
+
+		low, high := min, max
+		if values != "*" {
+			first, last, isRange := strings.Cut(values, "-")
+
+			var err error
+			low, err = strconv.Atoi(first)
+			if err != nil {
+				return 0, fmt.Errorf("invalid value in %q", part)
+			}
+
+			switch {
+			case isRange:
+				high, err = strconv.Atoi(last)
+				if err != nil {
+					return 0, fmt.Errorf("invalid value in %q", part)
+				}
+			case !hasStep:
+				high = low
+			}
+		}
+
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is outside of %d-%d", part, min, max)
+		}
+
+		for value := low; value <= high; value += step {
+			bits |= 1 << value
+		}
+	}
+
+	if bits == 0 {
+		return 0, errors.New("empty field")
+	}
+
+	return bits, nil
+}
+
+func (c *cron) Next(t time.Time) time.Time {
+	// Time zone offsets are whole minutes, so truncating works the same in every zone
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	// Every combination of days repeats within a few years, so a schedule that has not run by
+	// then never will, such as one only running on the 30th of February
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<t.Month()) == 0:
+			t = later(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location()))
+		case !c.matchesDay(t):
+			t = later(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location()))
+		case c.hour&(1<<t.Hour()) == 0:
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
+
+// later returns next, unless a daylight saving time change made it fall before t, in which case
+// it moves on an hour from t instead
+func later(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+
+	return t.Add(time.Hour)
+}
+
+func (c *cron) matchesDay(t time.Time) bool {
+	dayOfMonth := c.dayOfMonth&(1<<t.Day()) != 0
+	dayOfWeek := c.dayOfWeek&(1<<t.Weekday()) != 0
+
+	if c.anyDay {
+		return dayOfMonth && dayOfWeek
+	}
+
+	return dayOfMonth || dayOfWeek
+}
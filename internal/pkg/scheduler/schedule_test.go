@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	at := func(value string) time.Time {
+		t.Helper()
+
+		parsed, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return parsed
+	}
+
+	// A Wednesday
+	now := at("2024-05-15 10:17")
+
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"@every 5m", now.Add(5 * time.Minute)},
+		{"@hourly", at("2024-05-15 11:00")},
+		{"@daily", at("2024-05-16 00:00")},
+		{"@weekly", at("2024-05-19 00:00")},
+		{"* * * * *", at("2024-05-15 10:18")},
+		{"*/15 * * * *", at("2024-05-15 10:30")},
+		{"5,40 9-11 * * *", at("2024-05-15 10:40")},
+		{"0 8 * * 1-5", at("2024-05-16 08:00")},
+		{"30 12 1 * *", at("2024-06-01 12:30")},
+		{"0 0 * 2 *", at("2025-02-01 00:00")},
+		{"0 0 29 2 *", at("2028-02-29 00:00")},
+		// Sunday can be written as 7
+		{"0 9 * * 7", at("2024-05-19 09:00")},
+		// Either day field matches when both are restricted
+		{"0 0 20 * 5", at("2024-05-17 00:00")},
+	}
+
+	for _, test := range tests {
+		schedule, err := Parse(test.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) failed: %v", test.spec, err)
+			continue
+		}
+
+		if got := schedule.Next(now); !got.Equal(test.want) {
+			t.Errorf("Parse(%q).Next() = %s, want %s", test.spec, got, test.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"@every",
+		"@every soon",
+		"@every 1ms",
+		"@monthly",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+	} {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("expected Parse(%q) to fail", spec)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+// Package scheduler runs the background jobs of the bot on their configured schedules, and
+// records the outcome of their latest run
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+)
+
+// recordTimeout bounds recording a run, which also happens after the scheduler was stopped
+const recordTimeout = 5 * time.Second
+
+// Scheduler runs jobs on their schedules, never running a job while its previous run is still
+// going
+type Scheduler struct {
+	db   storage.JobRuns
+	jobs []*job
+}
+
+type job struct {
+	name string
+	// The schedule as configured, shown in the status of the job
+	spec       string
+	schedule   Schedule
+	runOnStart bool
+	run        func(ctx context.Context) error
+}
+
+// JobStatus is the schedule and latest run of a job
+type JobStatus struct {
+	Name     string    `json:"name"`
+	Schedule string    `json:"schedule"`
+	NextRun  time.Time `json:"nextRun"`
+	// Nil when the job has not run yet
+	LastRun *JobRun `json:"lastRun"`
+}
+
+// JobRun is the outcome of a run of a job
+type JobRun struct {
+	Started    time.Time `json:"started"`
+	DurationMs int64     `json:"durationMs"`
+	Failed     bool      `json:"failed"`
+	// Empty when the run succeeded. Left out of the JSON, as errors can mention the database or
+	// the channels of any guild.
+	Error string `json:"-"`
+}
+
+func New(db storage.JobRuns) *Scheduler {
+	return &Scheduler{db: db}
+}
+
+// Register adds a job running on the given schedule, see Parse. When runOnStart is set, the job
+// also runs as soon as the scheduler starts.
+func (s *Scheduler) Register(
+	name, spec string,
+	runOnStart bool,
+	run func(ctx context.Context) error,
+) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+
+	s.jobs = append(s.jobs, &job{
+		name:       name,
+		spec:       spec,
+		schedule:   schedule,
+		runOnStart: runOnStart,
+		run:        run,
+	})
+
+	return nil
+}
+
+// Start runs every registered job on its schedule in the background until ctx is done. The
+// returned function waits for the jobs to stop, including runs that are still going.
+func (s *Scheduler) Start(ctx context.Context) (wait func()) {
+	var running sync.WaitGroup
+	for _, job := range s.jobs {
+		log.Printf("Scheduler: Starting job %s with schedule %q", job.name, job.spec)
+
+		running.Add(1)
+		go func() {
+			defer running.Done()
+			s.loop(ctx, job)
+		}()
+	}
+
+	return running.Wait
+}
+
+func (s *Scheduler) loop(ctx context.Context, job *job) {
+	if job.runOnStart {
+		s.runJob(ctx, job)
+	}
+
+	for {
+		next := job.schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Printf("Scheduler: Job %s is not scheduled to run again", job.name)
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+
+		select {
+		case <-timer.C:
+			s.runJob(ctx, job)
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("Scheduler: stopping job %s", job.name)
+			return
+		}
+	}
+}
+
+// runJob runs the job once and records the outcome
+func (s *Scheduler) runJob(ctx context.Context, job *job) {
+	started := time.Now()
+	err := runRecovered(ctx, job)
+	duration := time.Since(started)
+
+	run := database.RecordJobRunParams{
+		Job:        job.name,
+		Started:    pgtype.Timestamptz{Time: started, Valid: true},
+		DurationMs: duration.Milliseconds(),
+	}
+
+	if err != nil {
+		run.Error = err.Error()
+		log.Printf("Scheduler: Job %s failed after %s\n%v", job.name, duration, err)
+	}
+
+	recordCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), recordTimeout)
+	defer cancel()
+
+	if err := s.db.RecordJobRun(recordCtx, run); err != nil {
+		log.Printf("Scheduler: Failed to record the run of job %s\n%v", job.name, err)
+	}
+}
+
+// runRecovered runs the job, turning a panic into an error so it does not stop the bot
+func runRecovered(ctx context.Context, job *job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v\n%s", r, debug.Stack())
+		}
+	}()
+
+	return job.run(ctx)
+}
+
+// Status returns the status of every job, in the order they were registered. Runs are read from
+// the database, so every instance reports the runs of whichever instance ran the jobs.
+func (s *Scheduler) Status(ctx context.Context) ([]JobStatus, error) {
+	rows, err := s.db.GetJobRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make(map[string]database.JobRun, len(rows))
+	for _, row := range rows {
+		runs[row.Job] = row
+	}
+
+	now := time.Now()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		status := JobStatus{
+			Name:     job.name,
+			Schedule: job.spec,
+			NextRun:  job.schedule.Next(now),
+		}
+
+		if row, ok := runs[job.name]; ok {
+			status.LastRun = &JobRun{
+				Started:    row.Started.Time,
+				DurationMs: row.DurationMs,
+				Failed:     row.Error != "",
+				Error:      row.Error,
+			}
+
+			// Intervals count from the end of the previous run
+			finished := row.Started.Time.Add(time.Duration(row.DurationMs) * time.Millisecond)
+			if next := job.schedule.Next(finished); next.After(now) {
+				status.NextRun = next
+			}
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
@@ -0,0 +1,147 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/pkg/storage"
+	"github.com/aloop/discord-bot/internal/testing/fakes"
+)
+
+func TestSchedulerRunsOnStart(t *testing.T) {
+	db := fakes.NewDB()
+	scheduler := New(storage.NewPostgres(db))
+
+	ran := make(chan struct{}, 2)
+	err := scheduler.Register("failing", "@every 1h", true, func(context.Context) error {
+		ran <- struct{}{}
+		return errors.New("store is down")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = scheduler.Register("waiting", "@every 1h", false, func(context.Context) error {
+		ran <- struct{}{}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	wait := scheduler.Start(ctx)
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("expected the job to run on start")
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(db.Calls("RecordJobRun")) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("expected the run to be recorded")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	calls := db.Calls("RecordJobRun")
+	if len(calls) != 1 || calls[0][0] != "failing" || calls[0][3] != "store is down" {
+		t.Errorf("expected only the failed run to be recorded, got %v", calls)
+	}
+
+	if len(ran) != 0 {
+		t.Error("expected the job not running on start to wait for its schedule")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wait()
+		close(stopped)
+	}()
+
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("expected the jobs to stop once cancelled")
+	}
+}
+
+func TestSchedulerRecoversPanics(t *testing.T) {
+	db := fakes.NewDB()
+	scheduler := New(storage.NewPostgres(db))
+
+	err := scheduler.Register("panicking", "@every 1h", false, func(context.Context) error {
+		panic("oops")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scheduler.runJob(context.Background(), scheduler.jobs[0])
+
+	calls := db.Calls("RecordJobRun")
+	if len(calls) != 1 || calls[0][3] == "" {
+		t.Errorf("expected the panic to be recorded as an error, got %v", calls)
+	}
+}
+
+func TestSchedulerStatus(t *testing.T) {
+	started := time.Now().Add(-10 * time.Minute).Truncate(time.Millisecond)
+
+	db := fakes.NewDB()
+	db.SetRows("GetJobRuns", database.JobRun{
+		Job:        "ran",
+		Started:    pgtype.Timestamptz{Time: started, Valid: true},
+		DurationMs: 1500,
+		Error:      "connection refused",
+	})
+
+	scheduler := New(storage.NewPostgres(db))
+	for _, name := range []string{"ran", "new"} {
+		if err := scheduler.Register(name, "@every 1h", false, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	statuses, err := scheduler.Status(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(statuses) != 2 || statuses[0].Name != "ran" || statuses[1].Name != "new" {
+		t.Fatalf("expected a status for each job in order, got %+v", statuses)
+	}
+
+	ran := statuses[0]
+	if ran.LastRun == nil || !ran.LastRun.Started.Equal(started) || ran.LastRun.DurationMs != 1500 {
+		t.Errorf("expected the recorded run, got %+v", ran.LastRun)
+	}
+
+	encoded, err := json.Marshal(ran.LastRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !ran.LastRun.Failed || strings.Contains(string(encoded), "connection refused") {
+		t.Errorf("expected the run to be failed without the error being exposed, got %s", encoded)
+	}
+
+	if want := started.Add(time.Hour + 1500*time.Millisecond); !ran.NextRun.Equal(want) {
+		t.Errorf("expected the next run an hour after the last one finished, got %s", ran.NextRun)
+	}
+
+	if statuses[1].LastRun != nil || statuses[1].NextRun.IsZero() {
+		t.Errorf("expected a job that never ran to only have a next run, got %+v", statuses[1])
+	}
+}
@@ -12,18 +12,34 @@ import (
 type Secrets struct {
 	Discord  DiscordSecrets  `json:"discord"`
 	Channels ChannelsSecrets `json:"channels"`
+	Roles    RolesSecrets    `json:"roles"`
 	Blizzard BlizzardSecrets `json:"blizzard"`
 	Database DatabaseSecrets `json:"database"`
 }
 
 type DiscordSecrets struct {
 	ClientID string `json:"clientId"`
-	GuildID  string `json:"guildId"`
 	Token    string `json:"token"`
+	// Optional, the server of the bot owner, the only one the commands that are not global are
+	// registered in
+	GuildID string `json:"guildId"`
+	// Hex encoded application public key, only needed when receiving interactions over HTTP
+	PublicKey string `json:"publicKey"`
 }
 
 type ChannelsSecrets struct {
+	// Optional, free games are announced here in addition to the deals channel of every guild
+	// configured with /config
 	Deals string `json:"deals"`
+	// Optional, receives the WoW token price alerts of the guild set in guildId, or of every
+	// guild when that is unset, unless the guild set its own channel through /config. Alerts
+	// without a channel are sent as direct messages.
+	Alerts string `json:"alerts"`
+}
+
+type RolesSecrets struct {
+	// Optional, pinged by free game reminders in the deals channel when set
+	FreeGameReminders string `json:"freeGameReminders"`
 }
 
 type BlizzardSecrets struct {
@@ -64,18 +80,10 @@ func (secrets *Secrets) Validate() {
 		log.Fatal("Secrets: Discord Client ID not set! Exiting...")
 	}
 
-	if secrets.Discord.GuildID == "" {
-		log.Fatal("Secrets: Discord Guild ID not set! Exiting...")
-	}
-
 	if secrets.Discord.Token == "" {
 		log.Fatal("Secrets: Discord Token not set! Exiting...")
 	}
 
-	if secrets.Channels.Deals == "" {
-		log.Fatal("Secrets: Deals Channel ID not set! Exiting...")
-	}
-
 	if secrets.Blizzard.ClientID == "" {
 		log.Fatal("Secrets: Blizzard Client ID not set! Exiting...")
 	}
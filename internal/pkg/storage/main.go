@@ -0,0 +1,147 @@
+// Package storage defines the queries the bot needs from its database, implemented by a
+// Postgres backend and an embedded SQLite backend. The backend is chosen by the connection
+// string passed to Open.
+package storage
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+)
+
+// ErrNotFound is returned by queries for a single row when there is no such row. Both backends
+// return pgx.ErrNoRows, so existing checks against either keep working.
+var ErrNotFound = pgx.ErrNoRows
+
+// TokenPrices stores the WoW token price history of each region
+type TokenPrices interface {
+	AddTokenPrice(ctx context.Context, arg database.AddTokenPriceParams) (database.WowTokenPrice, error)
+	GetAllTokenPricesSince(
+		ctx context.Context,
+		arg database.GetAllTokenPricesSinceParams,
+	) ([]database.GetAllTokenPricesSinceRow, error)
+	GetLatestTokenPrice(ctx context.Context, region string) (database.WowTokenPrice, error)
+	GetTokenPricesBetween(
+		ctx context.Context,
+		arg database.GetTokenPricesBetweenParams,
+	) ([]database.GetTokenPricesBetweenRow, error)
+	GetTokenPricesPage(
+		ctx context.Context,
+		arg database.GetTokenPricesPageParams,
+	) ([]database.WowTokenPrice, error)
+}
+
+// TokenAlerts stores the WoW token price alerts set up by users
+type TokenAlerts interface {
+	AddTokenAlert(ctx context.Context, arg database.AddTokenAlertParams) (database.WowTokenAlert, error)
+	DeleteTokenAlert(ctx context.Context, arg database.DeleteTokenAlertParams) (int64, error)
+	GetTokenAlertsForRegion(ctx context.Context, region string) ([]database.WowTokenAlert, error)
+	GetTokenAlertsForUser(ctx context.Context, userID string) ([]database.WowTokenAlert, error)
+	SetTokenAlertTriggered(ctx context.Context, arg database.SetTokenAlertTriggeredParams) error
+}
+
+// FreeGames stores the free games announced by the bot and the announcements waiting to be posted
+type FreeGames interface {
+	AddFreeGame(ctx context.Context, arg database.AddFreeGameParams) (database.EgsFreeGame, error)
+	AddFreeGameAnnouncement(ctx context.Context, arg database.AddFreeGameAnnouncementParams) error
+	AddFreeGameOutbox(ctx context.Context, arg database.AddFreeGameOutboxParams) error
+	ClaimFreeGameOutbox(ctx context.Context, arg database.ClaimFreeGameOutboxParams) (int64, error)
+	ExtendFreeGame(ctx context.Context, arg database.ExtendFreeGameParams) error
+	GetAllFreeGames(ctx context.Context) ([]database.EgsFreeGame, error)
+	GetCurrentFreeGames(ctx context.Context) ([]database.EgsFreeGame, error)
+	GetExpiredFreeGameAnnouncements(
+		ctx context.Context,
+	) ([]database.GetExpiredFreeGameAnnouncementsRow, error)
+	GetFreeGamesEndingBefore(
+		ctx context.Context,
+		endDate pgtype.Timestamptz,
+	) ([]database.EgsFreeGame, error)
+	GetOpenEndedFreeGame(
+		ctx context.Context,
+		arg database.GetOpenEndedFreeGameParams,
+	) (database.EgsFreeGame, error)
+	GetPendingFreeGameOutbox(
+		ctx context.Context,
+		attempts int32,
+	) ([]database.GetPendingFreeGameOutboxRow, error)
+	GetUnexpiredFreeGames(ctx context.Context) ([]database.EgsFreeGame, error)
+	MarkFreeGameAnnouncementExpired(ctx context.Context, id int64) error
+	MarkFreeGameLive(ctx context.Context, id int64) (int64, error)
+	MarkFreeGameOutboxFailed(ctx context.Context, arg database.MarkFreeGameOutboxFailedParams) error
+	MarkFreeGameOutboxSent(ctx context.Context, arg database.MarkFreeGameOutboxSentParams) error
+	MarkFreeGameReminded(ctx context.Context, id int64) (int64, error)
+	RenewFreeGame(ctx context.Context, arg database.RenewFreeGameParams) error
+}
+
+// GuildSettings stores the settings each guild configured through /config
+type GuildSettings interface {
+	GetAllGuildSettings(ctx context.Context) ([]database.GuildSetting, error)
+	GetGuildSettings(ctx context.Context, guildID string) (database.GuildSetting, error)
+	UpsertGuildSettings(
+		ctx context.Context,
+		arg database.UpsertGuildSettingsParams,
+	) (database.GuildSetting, error)
+}
+
+// JobRuns stores the latest run of each background job
+type JobRuns interface {
+	GetJobRuns(ctx context.Context) ([]database.JobRun, error)
+	RecordJobRun(ctx context.Context, arg database.RecordJobRunParams) error
+}
+
+// Transactor runs queries in a transaction
+type Transactor interface {
+	// InTx runs fn with a Store bound to a new transaction, which is committed when fn returns
+	// nil and rolled back otherwise. Only the Store passed to fn may be used until fn returns.
+	InTx(ctx context.Context, fn func(Store) error) error
+}
+
+// Store holds every query used by the bot. The Postgres backend embeds *database.Queries, which
+// keeps it a thin wrapper around the sqlc generated code.
+type Store interface {
+	TokenPrices
+	TokenAlerts
+	FreeGames
+	GuildSettings
+	JobRuns
+	Transactor
+}
+
+// Lease is the leadership of this instance among the instances sharing a database
+type Lease interface {
+	// Check returns an error once the leadership has been lost, such as when the connection
+	// holding it dropped
+	Check(ctx context.Context) error
+	// Release gives up the leadership
+	Release()
+}
+
+// DB is an open database of either backend
+type DB interface {
+	Store
+	// Migrate applies any pending migrations for the backend
+	Migrate(ctx context.Context) error
+	// TryAcquireLeadership makes this instance the leader, unless another instance sharing the
+	// database leads already, in which case the Lease is nil
+	TryAcquireLeadership(ctx context.Context) (Lease, error)
+	Close()
+}
+
+// Open connects to the database at url. Urls starting with "sqlite:" or "file:" open an
+// SQLite database, such as "sqlite://bot.db" or "sqlite://:memory:", anything else is treated
+// as a Postgres connection string.
+func Open(ctx context.Context, url string) (DB, error) {
+	if path, ok := strings.CutPrefix(url, "sqlite:"); ok {
+		return openSQLite(ctx, strings.TrimPrefix(path, "//"))
+	}
+
+	if strings.HasPrefix(url, "file:") {
+		return openSQLite(ctx, url)
+	}
+
+	return openPostgres(ctx, url)
+}
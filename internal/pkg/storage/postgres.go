@@ -0,0 +1,106 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/internal/pkg/migrations"
+)
+
+// PostgresConn is a connection to Postgres that can start transactions, such as a connection
+// pool or a transaction itself
+type PostgresConn interface {
+	database.DBTX
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// postgresDB runs the sqlc generated queries directly, only adding transactions on top
+type postgresDB struct {
+	*database.Queries
+	conn PostgresConn
+	// Set for the connection pool opened by Open, which owns the migrations and the connections
+	pool *pgxpool.Pool
+}
+
+// NewPostgres runs the queries over an existing Postgres connection
+func NewPostgres(conn PostgresConn) Store {
+	return &postgresDB{
+		Queries: database.New(conn),
+		conn:    conn,
+	}
+}
+
+func openPostgres(ctx context.Context, url string) (DB, error) {
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+
+	return &postgresDB{
+		Queries: database.New(pool),
+		conn:    pool,
+		pool:    pool,
+	}, nil
+}
+
+func (db *postgresDB) InTx(ctx context.Context, fn func(Store) error) error {
+	// Starting a transaction within a transaction creates a savepoint
+	return pgx.BeginFunc(ctx, db.conn, func(tx pgx.Tx) error {
+		return fn(&postgresDB{
+			Queries: db.Queries.WithTx(tx),
+			conn:    tx,
+		})
+	})
+}
+
+func (db *postgresDB) Migrate(ctx context.Context) error {
+	return migrations.Run(ctx, db.pool)
+}
+
+// leaderLockID is the advisory lock held by the leader. The value is arbitrary, it only has to be
+// the same for every instance.
+const leaderLockID int64 = 0x646973636f7264
+
+// postgresLease holds a session level advisory lock on a connection of its own. Postgres releases
+// the lock when the connection drops, which lets another instance take over.
+type postgresLease struct {
+	conn *pgxpool.Conn
+}
+
+func (db *postgresDB) TryAcquireLeadership(ctx context.Context) (Lease, error) {
+	conn, err := db.pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var acquired bool
+	err = conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", leaderLockID).Scan(&acquired)
+	if err != nil || !acquired {
+		conn.Release()
+		return nil, err
+	}
+
+	return &postgresLease{conn: conn}, nil
+}
+
+func (l *postgresLease) Check(ctx context.Context) error {
+	return l.conn.Ping(ctx)
+}
+
+func (l *postgresLease) Release() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Closing the connection releases the lock even when the connection is broken, and keeps
+	// the pool from handing out a connection that still holds it
+	l.conn.Conn().Close(ctx)
+	l.conn.Release()
+}
+
+func (db *postgresDB) Close() {
+	db.pool.Close()
+}
@@ -0,0 +1,572 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	_ "modernc.org/sqlite"
+
+	"github.com/aloop/discord-bot/database"
+	"github.com/aloop/discord-bot/database/sqlitedb"
+	"github.com/aloop/discord-bot/internal/pkg/migrations"
+)
+
+// sqlitePragmas are applied to every connection. The busy timeout lets other processes, such as
+// the export subcommand, wait for a write to finish instead of failing right away.
+var sqlitePragmas = []string{
+	"busy_timeout(5000)",
+	"foreign_keys(1)",
+	"journal_mode(WAL)",
+}
+
+// sqliteDB converts between the sqlc generated SQLite queries, which store timestamps as unix
+// milliseconds, and the Postgres types used throughout the bot
+type sqliteDB struct {
+	db *sql.DB
+	q  *sqlitedb.Queries
+	// The transaction the queries are bound to, if any
+	tx *sql.Tx
+}
+
+func openSQLite(ctx context.Context, path string) (DB, error) {
+	params := make([]string, 0, len(sqlitePragmas))
+	for _, pragma := range sqlitePragmas {
+		params = append(params, "_pragma="+pragma)
+	}
+
+	separator := "?"
+	if strings.Contains(path, "?") {
+		separator = "&"
+	}
+
+	db, err := sql.Open("sqlite", path+separator+strings.Join(params, "&"))
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite only allows a single writer, and every connection to an in-memory database
+	// would get its own empty database
+	db.SetMaxOpenConns(1)
+
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &sqliteDB{db: db, q: sqlitedb.New(db)}, nil
+}
+
+func (s *sqliteDB) Migrate(ctx context.Context) error {
+	return migrations.RunSQLite(ctx, s.db)
+}
+
+func (s *sqliteDB) Close() {
+	s.db.Close()
+}
+
+// sqliteLease is always held, as an SQLite database belongs to a single instance
+type sqliteLease struct{}
+
+func (s *sqliteDB) TryAcquireLeadership(context.Context) (Lease, error) {
+	return sqliteLease{}, nil
+}
+
+func (sqliteLease) Check(context.Context) error {
+	return nil
+}
+
+func (sqliteLease) Release() {}
+
+func (s *sqliteDB) InTx(ctx context.Context, fn func(Store) error) error {
+	// The database only has a single connection, which the outer transaction holds on to, so
+	// nested calls join it instead
+	if s.tx != nil {
+		return fn(s)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(&sqliteDB{db: s.db, q: s.q.WithTx(tx), tx: tx}); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func toTimestamp(ms int64) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: time.UnixMilli(ms), Valid: true}
+}
+
+func fromTimestamp(t pgtype.Timestamptz) int64 {
+	return t.Time.UnixMilli()
+}
+
+// notFound translates the database/sql error for a missing row into ErrNotFound
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+
+	return err
+}
+
+func toTokenPrice(p sqlitedb.WowTokenPrice) database.WowTokenPrice {
+	return database.WowTokenPrice{
+		ID:      p.ID,
+		Updated: toTimestamp(p.Updated),
+		Price:   p.Price,
+		Region:  p.Region,
+	}
+}
+
+func toTokenAlert(a sqlitedb.WowTokenAlert) database.WowTokenAlert {
+	return database.WowTokenAlert{
+		ID:        a.ID,
+		UserID:    a.UserID,
+		GuildID:   a.GuildID,
+		Region:    a.Region,
+		Direction: a.Direction,
+		Threshold: a.Threshold,
+		Triggered: a.Triggered,
+		Created:   toTimestamp(a.Created),
+	}
+}
+
+func toFreeGame(g sqlitedb.EgsFreeGame) database.EgsFreeGame {
+	return database.EgsFreeGame{
+		ID:           g.ID,
+		StoreID:      g.StoreID,
+		Title:        g.Title,
+		Description:  g.Description,
+		Url:          g.Url,
+		ThumbnailUrl: g.ThumbnailUrl,
+		StartDate:    toTimestamp(g.StartDate),
+		EndDate:      toTimestamp(g.EndDate),
+		Status:       g.Status,
+		Reminded:     g.Reminded,
+		Source:       g.Source,
+		OpenEnded:    g.OpenEnded,
+	}
+}
+
+func toGuildSetting(g sqlitedb.GuildSetting) database.GuildSetting {
+	return database.GuildSetting{
+		GuildID:         g.GuildID,
+		DealsChannelID:  g.DealsChannelID,
+		AlertsChannelID: g.AlertsChannelID,
+		Region:          g.Region,
+		Locale:          g.Locale,
+		PingRoleID:      g.PingRoleID,
+		Updated:         toTimestamp(g.Updated),
+	}
+}
+
+func toTokenAlerts(alerts []sqlitedb.WowTokenAlert) []database.WowTokenAlert {
+	converted := make([]database.WowTokenAlert, 0, len(alerts))
+	for _, alert := range alerts {
+		converted = append(converted, toTokenAlert(alert))
+	}
+
+	return converted
+}
+
+func toFreeGames(games []sqlitedb.EgsFreeGame) []database.EgsFreeGame {
+	converted := make([]database.EgsFreeGame, 0, len(games))
+	for _, game := range games {
+		converted = append(converted, toFreeGame(game))
+	}
+
+	return converted
+}
+
+func (s *sqliteDB) AddTokenPrice(
+	ctx context.Context,
+	arg database.AddTokenPriceParams,
+) (database.WowTokenPrice, error) {
+	price, err := s.q.AddTokenPrice(ctx, sqlitedb.AddTokenPriceParams{
+		Region:  arg.Region,
+		Updated: fromTimestamp(arg.Updated),
+		Price:   arg.Price,
+	})
+
+	return toTokenPrice(price), err
+}
+
+func (s *sqliteDB) GetAllTokenPricesSince(
+	ctx context.Context,
+	arg database.GetAllTokenPricesSinceParams,
+) ([]database.GetAllTokenPricesSinceRow, error) {
+	rows, err := s.q.GetAllTokenPricesSince(ctx, sqlitedb.GetAllTokenPricesSinceParams{
+		Region:  arg.Region,
+		Updated: fromTimestamp(arg.Updated),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make([]database.GetAllTokenPricesSinceRow, 0, len(rows))
+	for _, row := range rows {
+		prices = append(prices, database.GetAllTokenPricesSinceRow{
+			Price:   row.Price,
+			Updated: toTimestamp(row.Updated),
+		})
+	}
+
+	return prices, nil
+}
+
+func (s *sqliteDB) GetLatestTokenPrice(
+	ctx context.Context,
+	region string,
+) (database.WowTokenPrice, error) {
+	price, err := s.q.GetLatestTokenPrice(ctx, region)
+
+	return toTokenPrice(price), notFound(err)
+}
+
+func (s *sqliteDB) GetTokenPricesBetween(
+	ctx context.Context,
+	arg database.GetTokenPricesBetweenParams,
+) ([]database.GetTokenPricesBetweenRow, error) {
+	rows, err := s.q.GetTokenPricesBetween(ctx, sqlitedb.GetTokenPricesBetweenParams{
+		Region: arg.Region,
+		Since:  fromTimestamp(arg.Since),
+		Until:  fromTimestamp(arg.Until),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make([]database.GetTokenPricesBetweenRow, 0, len(rows))
+	for _, row := range rows {
+		prices = append(prices, database.GetTokenPricesBetweenRow{
+			Price:   row.Price,
+			Updated: toTimestamp(row.Updated),
+		})
+	}
+
+	return prices, nil
+}
+
+func (s *sqliteDB) GetTokenPricesPage(
+	ctx context.Context,
+	arg database.GetTokenPricesPageParams,
+) ([]database.WowTokenPrice, error) {
+	rows, err := s.q.GetTokenPricesPage(ctx, sqlitedb.GetTokenPricesPageParams{
+		ID:    arg.ID,
+		Limit: int64(arg.Limit),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make([]database.WowTokenPrice, 0, len(rows))
+	for _, row := range rows {
+		prices = append(prices, toTokenPrice(row))
+	}
+
+	return prices, nil
+}
+
+func (s *sqliteDB) AddTokenAlert(
+	ctx context.Context,
+	arg database.AddTokenAlertParams,
+) (database.WowTokenAlert, error) {
+	alert, err := s.q.AddTokenAlert(ctx, sqlitedb.AddTokenAlertParams{
+		UserID:    arg.UserID,
+		GuildID:   arg.GuildID,
+		Region:    arg.Region,
+		Direction: arg.Direction,
+		Threshold: arg.Threshold,
+	})
+
+	return toTokenAlert(alert), err
+}
+
+func (s *sqliteDB) DeleteTokenAlert(
+	ctx context.Context,
+	arg database.DeleteTokenAlertParams,
+) (int64, error) {
+	return s.q.DeleteTokenAlert(ctx, sqlitedb.DeleteTokenAlertParams{
+		ID:     arg.ID,
+		UserID: arg.UserID,
+	})
+}
+
+func (s *sqliteDB) GetTokenAlertsForRegion(
+	ctx context.Context,
+	region string,
+) ([]database.WowTokenAlert, error) {
+	alerts, err := s.q.GetTokenAlertsForRegion(ctx, region)
+	if err != nil {
+		return nil, err
+	}
+
+	return toTokenAlerts(alerts), nil
+}
+
+func (s *sqliteDB) GetTokenAlertsForUser(
+	ctx context.Context,
+	userID string,
+) ([]database.WowTokenAlert, error) {
+	alerts, err := s.q.GetTokenAlertsForUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return toTokenAlerts(alerts), nil
+}
+
+func (s *sqliteDB) SetTokenAlertTriggered(
+	ctx context.Context,
+	arg database.SetTokenAlertTriggeredParams,
+) error {
+	return s.q.SetTokenAlertTriggered(ctx, sqlitedb.SetTokenAlertTriggeredParams{
+		Triggered: arg.Triggered,
+		ID:        arg.ID,
+	})
+}
+
+func (s *sqliteDB) AddFreeGame(
+	ctx context.Context,
+	arg database.AddFreeGameParams,
+) (database.EgsFreeGame, error) {
+	game, err := s.q.AddFreeGame(ctx, sqlitedb.AddFreeGameParams{
+		StoreID:      arg.StoreID,
+		Title:        arg.Title,
+		Description:  arg.Description,
+		Url:          arg.Url,
+		ThumbnailUrl: arg.ThumbnailUrl,
+		StartDate:    fromTimestamp(arg.StartDate),
+		EndDate:      fromTimestamp(arg.EndDate),
+		Status:       arg.Status,
+		Source:       arg.Source,
+		OpenEnded:    arg.OpenEnded,
+	})
+
+	return toFreeGame(game), notFound(err)
+}
+
+func (s *sqliteDB) AddFreeGameAnnouncement(
+	ctx context.Context,
+	arg database.AddFreeGameAnnouncementParams,
+) error {
+	return s.q.AddFreeGameAnnouncement(ctx, sqlitedb.AddFreeGameAnnouncementParams(arg))
+}
+
+func (s *sqliteDB) AddFreeGameOutbox(
+	ctx context.Context,
+	arg database.AddFreeGameOutboxParams,
+) error {
+	return s.q.AddFreeGameOutbox(ctx, sqlitedb.AddFreeGameOutboxParams(arg))
+}
+
+func (s *sqliteDB) ExtendFreeGame(ctx context.Context, arg database.ExtendFreeGameParams) error {
+	return s.q.ExtendFreeGame(ctx, sqlitedb.ExtendFreeGameParams{
+		EndDate: fromTimestamp(arg.EndDate),
+		ID:      arg.ID,
+	})
+}
+
+func (s *sqliteDB) GetAllFreeGames(ctx context.Context) ([]database.EgsFreeGame, error) {
+	rows, err := s.q.GetAllFreeGames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toFreeGames(rows), nil
+}
+
+func (s *sqliteDB) GetCurrentFreeGames(ctx context.Context) ([]database.EgsFreeGame, error) {
+	rows, err := s.q.GetCurrentFreeGames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toFreeGames(rows), nil
+}
+
+func (s *sqliteDB) GetExpiredFreeGameAnnouncements(
+	ctx context.Context,
+) ([]database.GetExpiredFreeGameAnnouncementsRow, error) {
+	rows, err := s.q.GetExpiredFreeGameAnnouncements(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]database.GetExpiredFreeGameAnnouncementsRow, 0, len(rows))
+	for _, row := range rows {
+		converted = append(converted, database.GetExpiredFreeGameAnnouncementsRow(row))
+	}
+
+	return converted, nil
+}
+
+func (s *sqliteDB) GetFreeGamesEndingBefore(
+	ctx context.Context,
+	endDate pgtype.Timestamptz,
+) ([]database.EgsFreeGame, error) {
+	rows, err := s.q.GetFreeGamesEndingBefore(ctx, fromTimestamp(endDate))
+	if err != nil {
+		return nil, err
+	}
+
+	return toFreeGames(rows), nil
+}
+
+func (s *sqliteDB) GetOpenEndedFreeGame(
+	ctx context.Context,
+	arg database.GetOpenEndedFreeGameParams,
+) (database.EgsFreeGame, error) {
+	game, err := s.q.GetOpenEndedFreeGame(ctx, sqlitedb.GetOpenEndedFreeGameParams(arg))
+	return toFreeGame(game), notFound(err)
+}
+
+func (s *sqliteDB) GetPendingFreeGameOutbox(
+	ctx context.Context,
+	attempts int32,
+) ([]database.GetPendingFreeGameOutboxRow, error) {
+	rows, err := s.q.GetPendingFreeGameOutbox(ctx, int64(attempts))
+	if err != nil {
+		return nil, err
+	}
+
+	converted := make([]database.GetPendingFreeGameOutboxRow, 0, len(rows))
+	for _, row := range rows {
+		converted = append(converted, database.GetPendingFreeGameOutboxRow{
+			ID:          row.ID,
+			ChannelID:   row.ChannelID,
+			Kind:        row.Kind,
+			PingRoleID:  row.PingRoleID,
+			Attempts:    int32(row.Attempts),
+			EgsFreeGame: toFreeGame(row.EgsFreeGame),
+		})
+	}
+
+	return converted, nil
+}
+
+func (s *sqliteDB) GetUnexpiredFreeGames(ctx context.Context) ([]database.EgsFreeGame, error) {
+	rows, err := s.q.GetUnexpiredFreeGames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return toFreeGames(rows), nil
+}
+
+func (s *sqliteDB) MarkFreeGameLive(ctx context.Context, id int64) (int64, error) {
+	return s.q.MarkFreeGameLive(ctx, id)
+}
+
+func (s *sqliteDB) MarkFreeGameAnnouncementExpired(ctx context.Context, id int64) error {
+	return s.q.MarkFreeGameAnnouncementExpired(ctx, id)
+}
+
+func (s *sqliteDB) ClaimFreeGameOutbox(
+	ctx context.Context,
+	arg database.ClaimFreeGameOutboxParams,
+) (int64, error) {
+	return s.q.ClaimFreeGameOutbox(ctx, sqlitedb.ClaimFreeGameOutboxParams{
+		NextAttempt: fromTimestamp(arg.NextAttempt),
+		ID:          arg.ID,
+	})
+}
+
+func (s *sqliteDB) MarkFreeGameOutboxFailed(
+	ctx context.Context,
+	arg database.MarkFreeGameOutboxFailedParams,
+) error {
+	return s.q.MarkFreeGameOutboxFailed(ctx, sqlitedb.MarkFreeGameOutboxFailedParams{
+		NextAttempt: fromTimestamp(arg.NextAttempt),
+		ID:          arg.ID,
+	})
+}
+
+func (s *sqliteDB) MarkFreeGameOutboxSent(
+	ctx context.Context,
+	arg database.MarkFreeGameOutboxSentParams,
+) error {
+	return s.q.MarkFreeGameOutboxSent(ctx, sqlitedb.MarkFreeGameOutboxSentParams{
+		MessageID: arg.MessageID,
+		ID:        arg.ID,
+	})
+}
+
+func (s *sqliteDB) MarkFreeGameReminded(ctx context.Context, id int64) (int64, error) {
+	return s.q.MarkFreeGameReminded(ctx, id)
+}
+
+func (s *sqliteDB) RenewFreeGame(ctx context.Context, arg database.RenewFreeGameParams) error {
+	return s.q.RenewFreeGame(ctx, sqlitedb.RenewFreeGameParams{
+		StartDate: fromTimestamp(arg.StartDate),
+		EndDate:   fromTimestamp(arg.EndDate),
+		ID:        arg.ID,
+	})
+}
+
+func (s *sqliteDB) GetAllGuildSettings(ctx context.Context) ([]database.GuildSetting, error) {
+	rows, err := s.q.GetAllGuildSettings(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := make([]database.GuildSetting, 0, len(rows))
+	for _, row := range rows {
+		settings = append(settings, toGuildSetting(row))
+	}
+
+	return settings, nil
+}
+
+func (s *sqliteDB) GetGuildSettings(
+	ctx context.Context,
+	guildID string,
+) (database.GuildSetting, error) {
+	settings, err := s.q.GetGuildSettings(ctx, guildID)
+	return toGuildSetting(settings), notFound(err)
+}
+
+func (s *sqliteDB) UpsertGuildSettings(
+	ctx context.Context,
+	arg database.UpsertGuildSettingsParams,
+) (database.GuildSetting, error) {
+	settings, err := s.q.UpsertGuildSettings(ctx, sqlitedb.UpsertGuildSettingsParams(arg))
+	return toGuildSetting(settings), err
+}
+
+func (s *sqliteDB) GetJobRuns(ctx context.Context) ([]database.JobRun, error) {
+	rows, err := s.q.GetJobRuns(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	runs := make([]database.JobRun, 0, len(rows))
+	for _, row := range rows {
+		runs = append(runs, database.JobRun{
+			Job:        row.Job,
+			Started:    toTimestamp(row.Started),
+			DurationMs: row.DurationMs,
+			Error:      row.Error,
+		})
+	}
+
+	return runs, nil
+}
+
+func (s *sqliteDB) RecordJobRun(ctx context.Context, arg database.RecordJobRunParams) error {
+	return s.q.RecordJobRun(ctx, sqlitedb.RecordJobRunParams{
+		Job:        arg.Job,
+		Started:    fromTimestamp(arg.Started),
+		DurationMs: arg.DurationMs,
+		Error:      arg.Error,
+	})
+}
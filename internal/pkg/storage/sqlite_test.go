@@ -0,0 +1,744 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/aloop/discord-bot/database"
+)
+
+func openTestSQLite(t *testing.T) DB {
+	t.Helper()
+
+	db, err := Open(context.Background(), "sqlite://:memory:")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	if err := db.Migrate(context.Background()); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	return db
+}
+
+func timestamp(t time.Time) pgtype.Timestamptz {
+	return pgtype.Timestamptz{Time: t, Valid: true}
+}
+
+func TestSQLiteMigrationsAreIdempotent(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := Open(ctx, "sqlite://"+filepath.Join(t.TempDir(), "bot.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	for range 2 {
+		if err := db.Migrate(ctx); err != nil {
+			t.Fatalf("failed to migrate database: %v", err)
+		}
+	}
+}
+
+func TestSQLiteTokenPrices(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	if _, err := db.GetLatestTokenPrice(ctx, "us"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound without any prices, got %v", err)
+	}
+
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 5 {
+		_, err := db.AddTokenPrice(ctx, database.AddTokenPriceParams{
+			Region:  "us",
+			Updated: timestamp(start.Add(time.Duration(i) * time.Hour)),
+			Price:   int64(250_000 + i*1_000),
+		})
+		if err != nil {
+			t.Fatalf("failed to add token price: %v", err)
+		}
+	}
+
+	_, err := db.AddTokenPrice(ctx, database.AddTokenPriceParams{
+		Region:  "eu",
+		Updated: timestamp(start.Add(500 * time.Millisecond)),
+		Price:   300_000,
+	})
+	if err != nil {
+		t.Fatalf("failed to add token price: %v", err)
+	}
+
+	_, err = db.AddTokenPrice(ctx, database.AddTokenPriceParams{
+		Region:  "us",
+		Updated: timestamp(start),
+		Price:   1,
+	})
+	if err == nil {
+		t.Error("expected a duplicate price to be rejected")
+	}
+
+	latest, err := db.GetLatestTokenPrice(ctx, "us")
+	if err != nil {
+		t.Fatalf("failed to get latest token price: %v", err)
+	}
+
+	if latest.Price != 254_000 || !latest.Updated.Time.Equal(start.Add(4*time.Hour)) {
+		t.Errorf("unexpected latest price %+v", latest)
+	}
+
+	eu, err := db.GetLatestTokenPrice(ctx, "eu")
+	if err != nil {
+		t.Fatalf("failed to get latest token price: %v", err)
+	}
+
+	if !eu.Updated.Time.Equal(start.Add(500 * time.Millisecond)) {
+		t.Errorf("expected milliseconds to be kept, got %s", eu.Updated.Time)
+	}
+
+	between, err := db.GetTokenPricesBetween(ctx, database.GetTokenPricesBetweenParams{
+		Region: "us",
+		Since:  timestamp(start.Add(time.Hour)),
+		Until:  timestamp(start.Add(3 * time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("failed to get token prices: %v", err)
+	}
+
+	if len(between) != 3 || between[0].Price != 251_000 || between[2].Price != 253_000 {
+		t.Errorf("unexpected prices between %+v", between)
+	}
+
+	since, err := db.GetAllTokenPricesSince(ctx, database.GetAllTokenPricesSinceParams{
+		Region:  "us",
+		Updated: timestamp(start.Add(3 * time.Hour)),
+	})
+	if err != nil {
+		t.Fatalf("failed to get token prices: %v", err)
+	}
+
+	if len(since) != 2 || since[0].Price != 254_000 {
+		t.Errorf("unexpected prices since %+v", since)
+	}
+
+	page, err := db.GetTokenPricesPage(ctx, database.GetTokenPricesPageParams{ID: 3, Limit: 5})
+	if err != nil {
+		t.Fatalf("failed to get token price page: %v", err)
+	}
+
+	if len(page) != 3 || page[0].ID != 4 || page[2].Region != "eu" {
+		t.Errorf("unexpected page %+v", page)
+	}
+}
+
+func TestSQLiteTokenAlerts(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	alert, err := db.AddTokenAlert(ctx, database.AddTokenAlertParams{
+		UserID:    "user",
+		GuildID:   "guild",
+		Region:    "us",
+		Direction: "above",
+		Threshold: 300_000,
+	})
+	if err != nil {
+		t.Fatalf("failed to add token alert: %v", err)
+	}
+
+	if alert.Triggered || time.Since(alert.Created.Time) > time.Minute {
+		t.Errorf("unexpected new alert %+v", alert)
+	}
+
+	err = db.SetTokenAlertTriggered(ctx, database.SetTokenAlertTriggeredParams{
+		ID:        alert.ID,
+		Triggered: true,
+	})
+	if err != nil {
+		t.Fatalf("failed to trigger token alert: %v", err)
+	}
+
+	alerts, err := db.GetTokenAlertsForRegion(ctx, "us")
+	if err != nil {
+		t.Fatalf("failed to get token alerts: %v", err)
+	}
+
+	if len(alerts) != 1 || !alerts[0].Triggered {
+		t.Errorf("expected the alert to be triggered, got %+v", alerts)
+	}
+
+	removed, err := db.DeleteTokenAlert(ctx, database.DeleteTokenAlertParams{
+		ID:     alert.ID,
+		UserID: "someone else",
+	})
+	if err != nil || removed != 0 {
+		t.Errorf("expected other users not to remove the alert, removed %d: %v", removed, err)
+	}
+
+	removed, err = db.DeleteTokenAlert(ctx, database.DeleteTokenAlertParams{
+		ID:     alert.ID,
+		UserID: "user",
+	})
+	if err != nil || removed != 1 {
+		t.Errorf("expected the alert to be removed, removed %d: %v", removed, err)
+	}
+
+	alerts, err = db.GetTokenAlertsForUser(ctx, "user")
+	if err != nil || len(alerts) != 0 {
+		t.Errorf("expected no alerts to be left, got %+v: %v", alerts, err)
+	}
+}
+
+func TestSQLiteFreeGames(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	now := time.Now()
+	games := []struct {
+		storeID    string
+		status     string
+		start, end time.Time
+	}{
+		{"current", "live", now.Add(-time.Hour), now.Add(time.Hour)},
+		{"expired", "live", now.Add(-2 * time.Hour), now.Add(-time.Hour)},
+		{"upcoming", "upcoming", now.Add(time.Hour), now.Add(2 * time.Hour)},
+	}
+
+	ids := make(map[string]int64, len(games))
+	for _, game := range games {
+		stored, err := db.AddFreeGame(ctx, database.AddFreeGameParams{
+			StoreID:   game.storeID,
+			Title:     game.storeID,
+			StartDate: timestamp(game.start),
+			EndDate:   timestamp(game.end),
+			Status:    game.status,
+		})
+		if err != nil {
+			t.Fatalf("failed to add free game: %v", err)
+		}
+		ids[game.storeID] = stored.ID
+	}
+
+	current, err := db.GetCurrentFreeGames(ctx)
+	if err != nil {
+		t.Fatalf("failed to get current free games: %v", err)
+	}
+
+	if len(current) != 1 || current[0].StoreID != "current" {
+		t.Errorf("expected only the current game, got %+v", current)
+	}
+
+	unexpired, err := db.GetUnexpiredFreeGames(ctx)
+	if err != nil {
+		t.Fatalf("failed to get unexpired free games: %v", err)
+	}
+
+	if len(unexpired) != 2 || unexpired[0].StoreID != "upcoming" || unexpired[0].Status != "upcoming" {
+		t.Errorf("expected the upcoming and current games, got %+v", unexpired)
+	}
+
+	for _, want := range []int64{1, 0} {
+		marked, err := db.MarkFreeGameLive(ctx, ids["upcoming"])
+		if err != nil || marked != want {
+			t.Errorf("expected %d games to be marked live, marked %d: %v", want, marked, err)
+		}
+	}
+
+	if marked, _ := db.MarkFreeGameLive(ctx, ids["current"]); marked != 0 {
+		t.Errorf("expected a live game not to be marked again, marked %d", marked)
+	}
+}
+
+func TestSQLiteFreeGamesUniqueKey(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Millisecond)
+	game := database.AddFreeGameParams{
+		StoreID:   "game",
+		Title:     "Game",
+		StartDate: timestamp(start),
+		EndDate:   timestamp(start.Add(2 * time.Hour)),
+		Status:    "live",
+		Source:    "epic",
+	}
+
+	if _, err := db.AddFreeGame(ctx, game); err != nil {
+		t.Fatalf("failed to add free game: %v", err)
+	}
+
+	if _, err := db.AddFreeGame(ctx, game); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the same promotion not to be stored twice, got %v", err)
+	}
+
+	// The same game can be free again later, or in another store
+	game.Source = "steam"
+	if _, err := db.AddFreeGame(ctx, game); err != nil {
+		t.Errorf("expected the game to be stored for another store: %v", err)
+	}
+
+	game.StartDate = timestamp(start.Add(24 * time.Hour))
+	if _, err := db.AddFreeGame(ctx, game); err != nil {
+		t.Errorf("expected a later promotion to be stored: %v", err)
+	}
+}
+
+func TestSQLiteOpenEndedFreeGamesUniqueKey(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	seenOn := time.Now().Truncate(24 * time.Hour)
+	game := database.AddFreeGameParams{
+		StoreID:   "game",
+		Title:     "Game",
+		StartDate: timestamp(seenOn),
+		EndDate:   timestamp(time.Now().Add(time.Hour).Truncate(time.Millisecond)),
+		Status:    "live",
+		Source:    "gog",
+		OpenEnded: true,
+	}
+
+	stored, err := db.AddFreeGame(ctx, game)
+	if err != nil {
+		t.Fatalf("failed to add free game: %v", err)
+	}
+
+	// Open-ended promotions are stored once per game, however they are dated
+	game.StartDate = timestamp(seenOn.Add(24 * time.Hour))
+	if _, err := db.AddFreeGame(ctx, game); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected the open ended game not to be stored twice, got %v", err)
+	}
+
+	err = db.RenewFreeGame(ctx, database.RenewFreeGameParams{
+		ID:        stored.ID,
+		StartDate: game.StartDate,
+		EndDate:   game.EndDate,
+	})
+	if err != nil {
+		t.Fatalf("failed to renew free game: %v", err)
+	}
+
+	renewed, err := db.GetOpenEndedFreeGame(ctx, database.GetOpenEndedFreeGameParams{
+		Source:  "gog",
+		StoreID: "game",
+	})
+	if err != nil || renewed.ID != stored.ID || !renewed.StartDate.Time.Equal(game.StartDate.Time) {
+		t.Errorf("expected the stored game to be renewed, got %+v: %v", renewed, err)
+	}
+
+	_, err = db.GetOpenEndedFreeGame(ctx, database.GetOpenEndedFreeGameParams{
+		Source:  "steam",
+		StoreID: "game",
+	})
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a game of another store, got %v", err)
+	}
+}
+
+func TestSQLiteInTx(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	add := func(store Store, storeID string) error {
+		_, err := store.AddFreeGame(ctx, database.AddFreeGameParams{
+			StoreID:   storeID,
+			Title:     storeID,
+			StartDate: timestamp(time.Now()),
+			EndDate:   timestamp(time.Now().Add(time.Hour)),
+			Status:    "live",
+			Source:    "epic",
+		})
+		return err
+	}
+
+	failure := errors.New("failure")
+	err := db.InTx(ctx, func(tx Store) error {
+		if err := add(tx, "rolled-back"); err != nil {
+			return err
+		}
+
+		return failure
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the error of the transaction, got %v", err)
+	}
+
+	err = db.InTx(ctx, func(tx Store) error {
+		// Nested transactions join the outer one
+		return tx.InTx(ctx, func(tx Store) error {
+			return add(tx, "committed")
+		})
+	})
+	if err != nil {
+		t.Fatalf("failed to commit transaction: %v", err)
+	}
+
+	games, err := db.GetAllFreeGames(ctx)
+	if err != nil {
+		t.Fatalf("failed to get free games: %v", err)
+	}
+
+	if len(games) != 1 || games[0].StoreID != "committed" {
+		t.Errorf("expected only the committed game to be stored, got %+v", games)
+	}
+}
+
+func TestSQLiteAlwaysLeads(t *testing.T) {
+	lease, err := openTestSQLite(t).TryAcquireLeadership(context.Background())
+	if err != nil || lease == nil {
+		t.Fatalf("expected to always lead, got %v %v", lease, err)
+	}
+
+	if err := lease.Check(context.Background()); err != nil {
+		t.Errorf("expected the leadership never to be lost, got %v", err)
+	}
+}
+
+func TestSQLiteOpenEndedFreeGames(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	now := time.Now()
+	add := func(storeID string, openEnded bool) database.EgsFreeGame {
+		t.Helper()
+
+		game, err := db.AddFreeGame(ctx, database.AddFreeGameParams{
+			StoreID:   storeID,
+			Title:     storeID,
+			StartDate: timestamp(now.Add(-time.Hour)),
+			EndDate:   timestamp(now.Add(time.Hour)),
+			Status:    "live",
+			Source:    "gog",
+			OpenEnded: openEnded,
+		})
+		if err != nil {
+			t.Fatalf("failed to add free game: %v", err)
+		}
+
+		return game
+	}
+
+	openEnded := add("open-ended", true)
+	fixed := add("fixed", false)
+
+	if openEnded.Source != "gog" || !openEnded.OpenEnded || fixed.OpenEnded {
+		t.Errorf("expected the source and open endedness to be stored, got %+v %+v",
+			openEnded, fixed)
+	}
+
+	toRemind, err := db.GetFreeGamesEndingBefore(ctx, timestamp(now.Add(24*time.Hour)))
+	if err != nil {
+		t.Fatalf("failed to get free games to remind about: %v", err)
+	}
+
+	if len(toRemind) != 1 || toRemind[0].ID != fixed.ID {
+		t.Errorf("expected open ended games not to be reminded about, got %+v", toRemind)
+	}
+
+	extended := now.Add(6 * time.Hour).Truncate(time.Millisecond)
+	for _, id := range []int64{openEnded.ID, fixed.ID} {
+		err := db.ExtendFreeGame(ctx, database.ExtendFreeGameParams{
+			ID:      id,
+			EndDate: timestamp(extended),
+		})
+		if err != nil {
+			t.Fatalf("failed to extend free game: %v", err)
+		}
+	}
+
+	games, err := db.GetAllFreeGames(ctx)
+	if err != nil {
+		t.Fatalf("failed to get free games: %v", err)
+	}
+
+	for _, game := range games {
+		if extends := game.ID == openEnded.ID; game.EndDate.Time.Equal(extended) != extends {
+			t.Errorf("expected only the open ended game to be extended, got %+v", game)
+		}
+	}
+}
+
+func TestSQLiteFreeGameAnnouncements(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	now := time.Now()
+	add := func(storeID, status string, end time.Time) int64 {
+		t.Helper()
+
+		game, err := db.AddFreeGame(ctx, database.AddFreeGameParams{
+			StoreID:   storeID,
+			Title:     storeID,
+			StartDate: timestamp(now.Add(-24 * time.Hour)),
+			EndDate:   timestamp(end),
+			Status:    status,
+		})
+		if err != nil {
+			t.Fatalf("failed to add free game: %v", err)
+		}
+
+		return game.ID
+	}
+
+	ending := add("ending", "live", now.Add(time.Hour))
+	add("later", "live", now.Add(48*time.Hour))
+	add("upcoming", "upcoming", now.Add(time.Hour))
+	expired := add("expired", "live", now.Add(-time.Hour))
+	add("unannounced", "live", now.Add(-time.Hour))
+
+	toRemind, err := db.GetFreeGamesEndingBefore(ctx, timestamp(now.Add(24*time.Hour)))
+	if err != nil {
+		t.Fatalf("failed to get free games to remind about: %v", err)
+	}
+
+	if len(toRemind) != 1 || toRemind[0].ID != ending || toRemind[0].Reminded {
+		t.Fatalf("expected only the live game ending soon, got %+v", toRemind)
+	}
+
+	for _, want := range []int64{1, 0} {
+		marked, err := db.MarkFreeGameReminded(ctx, ending)
+		if err != nil || marked != want {
+			t.Errorf("expected %d games to be marked reminded, marked %d: %v", want, marked, err)
+		}
+	}
+
+	toRemind, _ = db.GetFreeGamesEndingBefore(ctx, timestamp(now.Add(24*time.Hour)))
+	if len(toRemind) != 0 {
+		t.Errorf("expected reminded games not to be reminded again, got %+v", toRemind)
+	}
+
+	for _, announcement := range []database.AddFreeGameAnnouncementParams{
+		{FreeGameID: expired, ChannelID: "deals", MessageID: "first"},
+		{FreeGameID: expired, ChannelID: "other-deals", MessageID: "second"},
+		{FreeGameID: ending, ChannelID: "deals", MessageID: "third"},
+	} {
+		if err := db.AddFreeGameAnnouncement(ctx, announcement); err != nil {
+			t.Fatalf("failed to store announcement: %v", err)
+		}
+	}
+
+	announcements, err := db.GetExpiredFreeGameAnnouncements(ctx)
+	if err != nil {
+		t.Fatalf("failed to get expired announcements: %v", err)
+	}
+
+	if len(announcements) != 2 || announcements[0].MessageID != "first" ||
+		announcements[1].ChannelID != "other-deals" || announcements[1].Title != "expired" {
+		t.Fatalf("expected only the announcements of the expired game, got %+v", announcements)
+	}
+
+	for _, announcement := range announcements {
+		if err := db.MarkFreeGameAnnouncementExpired(ctx, announcement.ID); err != nil {
+			t.Fatalf("failed to mark announcement expired: %v", err)
+		}
+	}
+
+	if announcements, _ := db.GetExpiredFreeGameAnnouncements(ctx); len(announcements) != 0 {
+		t.Errorf("expected expired announcements not to be returned again, got %+v", announcements)
+	}
+}
+
+func TestSQLiteFreeGameOutbox(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	now := time.Now()
+	add := func(storeID string, end time.Time) int64 {
+		t.Helper()
+
+		game, err := db.AddFreeGame(ctx, database.AddFreeGameParams{
+			StoreID:   storeID,
+			Title:     storeID,
+			StartDate: timestamp(now.Add(-24 * time.Hour)),
+			EndDate:   timestamp(end),
+			Status:    "live",
+			Source:    "epic",
+		})
+		if err != nil {
+			t.Fatalf("failed to add free game: %v", err)
+		}
+
+		return game.ID
+	}
+
+	free := add("free", now.Add(time.Hour))
+	ended := add("ended", now.Add(-time.Hour))
+
+	for _, queued := range []database.AddFreeGameOutboxParams{
+		{FreeGameID: free, ChannelID: "deals", Kind: "live"},
+		{FreeGameID: free, ChannelID: "other-deals", Kind: "live"},
+		{FreeGameID: ended, ChannelID: "deals", Kind: "live"},
+		{FreeGameID: free, ChannelID: "deals", Kind: "reminder", PingRoleID: "role"},
+	} {
+		if err := db.AddFreeGameOutbox(ctx, queued); err != nil {
+			t.Fatalf("failed to queue announcement: %v", err)
+		}
+	}
+
+	pending, err := db.GetPendingFreeGameOutbox(ctx, 3)
+	if err != nil {
+		t.Fatalf("failed to get pending announcements: %v", err)
+	}
+
+	if len(pending) != 3 || pending[0].ChannelID != "deals" ||
+		pending[1].ChannelID != "other-deals" || pending[0].EgsFreeGame.ID != free ||
+		pending[0].EgsFreeGame.Title != "free" || pending[0].Kind != "live" {
+		t.Fatalf("expected only the announcements of the free game, got %+v", pending)
+	}
+
+	if reminder := pending[2]; reminder.Kind != "reminder" || reminder.PingRoleID != "role" {
+		t.Errorf("expected the reminder to ping its role, got %+v", reminder)
+	}
+
+	err = db.MarkFreeGameOutboxSent(ctx, database.MarkFreeGameOutboxSentParams{
+		ID:        pending[2].ID,
+		MessageID: "reminder",
+	})
+	if err != nil {
+		t.Fatalf("failed to mark reminder sent: %v", err)
+	}
+
+	// Only the first claim of a due announcement succeeds
+	for i, want := range []int64{1, 0} {
+		claimed, err := db.ClaimFreeGameOutbox(ctx, database.ClaimFreeGameOutboxParams{
+			ID:          pending[0].ID,
+			NextAttempt: timestamp(now.Add(10 * time.Minute)),
+		})
+		if err != nil {
+			t.Fatalf("failed to claim announcement: %v", err)
+		}
+
+		if claimed != want {
+			t.Errorf("claim %d claimed %d announcements, want %d", i+1, claimed, want)
+		}
+	}
+
+	err = db.MarkFreeGameOutboxSent(ctx, database.MarkFreeGameOutboxSentParams{
+		ID:        pending[0].ID,
+		MessageID: "message",
+	})
+	if err != nil {
+		t.Fatalf("failed to mark announcement sent: %v", err)
+	}
+
+	// The second failure schedules a retry that is not due yet
+	for _, next := range []time.Time{now.Add(-time.Minute), now.Add(time.Minute)} {
+		err := db.MarkFreeGameOutboxFailed(ctx, database.MarkFreeGameOutboxFailedParams{
+			ID:          pending[1].ID,
+			NextAttempt: timestamp(next),
+		})
+		if err != nil {
+			t.Fatalf("failed to mark announcement failed: %v", err)
+		}
+	}
+
+	if pending, _ := db.GetPendingFreeGameOutbox(ctx, 3); len(pending) != 0 {
+		t.Errorf("expected no announcement to be due, got %+v", pending)
+	}
+
+	err = db.MarkFreeGameOutboxFailed(ctx, database.MarkFreeGameOutboxFailedParams{
+		ID:          pending[1].ID,
+		NextAttempt: timestamp(now.Add(-time.Minute)),
+	})
+	if err != nil {
+		t.Fatalf("failed to mark announcement failed: %v", err)
+	}
+
+	if pending, _ := db.GetPendingFreeGameOutbox(ctx, 3); len(pending) != 0 {
+		t.Errorf("expected the announcement to be given up on, got %+v", pending)
+	}
+
+	retried, _ := db.GetPendingFreeGameOutbox(ctx, 4)
+	if len(retried) != 1 || retried[0].Attempts != 3 {
+		t.Errorf("expected the announcement to have been attempted 3 times, got %+v", retried)
+	}
+}
+
+func TestSQLiteGuildSettings(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	if _, err := db.GetGuildSettings(ctx, "guild"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a guild without settings, got %v", err)
+	}
+
+	settings, err := db.UpsertGuildSettings(ctx, database.UpsertGuildSettingsParams{
+		GuildID:        "guild",
+		DealsChannelID: "deals",
+		Region:         "eu",
+	})
+	if err != nil {
+		t.Fatalf("failed to add guild settings: %v", err)
+	}
+
+	if settings.DealsChannelID != "deals" || settings.Region != "eu" || !settings.Updated.Valid {
+		t.Errorf("unexpected guild settings %+v", settings)
+	}
+
+	_, err = db.UpsertGuildSettings(ctx, database.UpsertGuildSettingsParams{
+		GuildID:    "guild",
+		Locale:     "de",
+		PingRoleID: "role",
+	})
+	if err != nil {
+		t.Fatalf("failed to update guild settings: %v", err)
+	}
+
+	settings, err = db.GetGuildSettings(ctx, "guild")
+	if err != nil {
+		t.Fatalf("failed to get guild settings: %v", err)
+	}
+
+	if settings.DealsChannelID != "" || settings.Locale != "de" || settings.PingRoleID != "role" {
+		t.Errorf("expected the settings to be replaced, got %+v", settings)
+	}
+
+	if _, err := db.UpsertGuildSettings(ctx, database.UpsertGuildSettingsParams{
+		GuildID: "another",
+	}); err != nil {
+		t.Fatalf("failed to add guild settings: %v", err)
+	}
+
+	all, err := db.GetAllGuildSettings(ctx)
+	if err != nil {
+		t.Fatalf("failed to get all guild settings: %v", err)
+	}
+
+	if len(all) != 2 || all[0].GuildID != "another" || all[1].GuildID != "guild" {
+		t.Errorf("expected the settings of both guilds, got %+v", all)
+	}
+}
+
+func TestSQLiteJobRuns(t *testing.T) {
+	ctx := context.Background()
+	db := openTestSQLite(t)
+
+	started := time.Now().Truncate(time.Millisecond)
+	for _, run := range []database.RecordJobRunParams{
+		{Job: "freeGames", Started: timestamp(started.Add(-time.Hour)), DurationMs: 100},
+		{Job: "wowTokenPrices", Started: timestamp(started), DurationMs: 20, Error: "down"},
+		{Job: "freeGames", Started: timestamp(started), DurationMs: 300},
+	} {
+		if err := db.RecordJobRun(ctx, run); err != nil {
+			t.Fatalf("failed to record job run: %v", err)
+		}
+	}
+
+	runs, err := db.GetJobRuns(ctx)
+	if err != nil {
+		t.Fatalf("failed to get job runs: %v", err)
+	}
+
+	if len(runs) != 2 || runs[0].Job != "freeGames" || !runs[0].Started.Time.Equal(started) ||
+		runs[0].DurationMs != 300 || runs[0].Error != "" || runs[1].Error != "down" {
+		t.Errorf("expected the latest run of each job, got %+v", runs)
+	}
+}
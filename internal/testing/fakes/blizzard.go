@@ -0,0 +1,179 @@
+package fakes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	blizzardAuthEndpoint  = "auth"
+	blizzardPriceEndpoint = "price"
+)
+
+type blizzardTokenPrice struct {
+	gold    int64
+	updated time.Time
+}
+
+// BlizzardAPI emulates the Blizzard OAuth token endpoint and the WoW token index endpoint.
+//
+// Auth tokens are only issued for the configured client credentials, and price requests are
+// only answered for tokens that were issued and not expired.
+type BlizzardAPI struct {
+	ClientID     string
+	ClientSecret string
+
+	server *httptest.Server
+	queue  *responseQueue
+
+	mu            sync.Mutex
+	prices        map[string]blizzardTokenPrice
+	tokenLifetime int64
+	tokens        map[string]bool
+}
+
+// NewBlizzardAPI starts a fake Blizzard API, which is stopped once the test finishes
+func NewBlizzardAPI(t testing.TB) *BlizzardAPI {
+	api := &BlizzardAPI{
+		ClientID:      "client-id",
+		ClientSecret:  "client-secret",
+		queue:         newResponseQueue(),
+		prices:        make(map[string]blizzardTokenPrice),
+		tokenLifetime: 86399,
+		tokens:        make(map[string]bool),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /oauth/token", api.handleAuthToken)
+	mux.HandleFunc("GET /data/wow/token/index", api.handleTokenPrice)
+
+	api.server = httptest.NewServer(mux)
+	t.Cleanup(api.server.Close)
+
+	return api
+}
+
+func (api *BlizzardAPI) AuthTokenURL() string {
+	return api.server.URL + "/oauth/token?grant_type=client_credentials"
+}
+
+func (api *BlizzardAPI) TokenPriceURL(region string) string {
+	return api.server.URL + "/data/wow/token/index?namespace=dynamic-" + region
+}
+
+// TokenPriceURLs returns the token price URLs for the given regions, as used in the config
+func (api *BlizzardAPI) TokenPriceURLs(regions ...string) map[string]string {
+	urls := make(map[string]string, len(regions))
+	for _, region := range regions {
+		urls[region] = api.TokenPriceURL(region)
+	}
+
+	return urls
+}
+
+// SetPrice sets the token price of a region, in gold
+func (api *BlizzardAPI) SetPrice(region string, gold int64, updated time.Time) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.prices[region] = blizzardTokenPrice{gold: gold, updated: updated}
+}
+
+// SetTokenLifetime sets the expires_in of newly issued auth tokens, in seconds
+func (api *BlizzardAPI) SetTokenLifetime(seconds int64) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.tokenLifetime = seconds
+}
+
+// ExpireTokens makes every auth token issued so far invalid, regardless of its lifetime
+func (api *BlizzardAPI) ExpireTokens() {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	clear(api.tokens)
+}
+
+// QueueAuthResponses scripts the next responses of the OAuth token endpoint
+func (api *BlizzardAPI) QueueAuthResponses(responses ...Response) {
+	api.queue.push(blizzardAuthEndpoint, responses...)
+}
+
+// QueuePriceResponses scripts the next responses of the token index endpoint
+func (api *BlizzardAPI) QueuePriceResponses(responses ...Response) {
+	api.queue.push(blizzardPriceEndpoint, responses...)
+}
+
+// AuthRequests returns the number of requests made to the OAuth token endpoint
+func (api *BlizzardAPI) AuthRequests() int {
+	return api.queue.count(blizzardAuthEndpoint)
+}
+
+// PriceRequests returns the number of requests made to the token index endpoint
+func (api *BlizzardAPI) PriceRequests() int {
+	return api.queue.count(blizzardPriceEndpoint)
+}
+
+func (api *BlizzardAPI) handleAuthToken(w http.ResponseWriter, req *http.Request) {
+	if resp, ok := api.queue.next(blizzardAuthEndpoint); ok {
+		resp.write(w)
+		return
+	}
+
+	clientID, clientSecret, ok := req.BasicAuth()
+	if !ok || clientID != api.ClientID || clientSecret != api.ClientSecret {
+		http.Error(w, `{"error":"unauthorized"}`, http.StatusUnauthorized)
+		return
+	}
+
+	api.mu.Lock()
+	token := fmt.Sprintf("token-%d", api.queue.count(blizzardAuthEndpoint))
+	api.tokens[token] = true
+	lifetime := api.tokenLifetime
+	api.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"access_token": token,
+		"token_type":   "bearer",
+		"expires_in":   lifetime,
+	})
+}
+
+func (api *BlizzardAPI) handleTokenPrice(w http.ResponseWriter, req *http.Request) {
+	if resp, ok := api.queue.next(blizzardPriceEndpoint); ok {
+		resp.write(w)
+		return
+	}
+
+	token, _ := strings.CutPrefix(req.Header.Get("Authorization"), "Bearer ")
+
+	api.mu.Lock()
+	valid := api.tokens[token]
+	price, found := api.prices[strings.TrimPrefix(req.URL.Query().Get("namespace"), "dynamic-")]
+	api.mu.Unlock()
+
+	if !valid {
+		http.Error(w, `{"code":401,"type":"BLZWEBAPI00000401"}`, http.StatusUnauthorized)
+		return
+	}
+
+	if !found {
+		http.Error(w, `{"code":404,"type":"BLZWEBAPI00000404"}`, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"last_updated_timestamp": price.updated.UnixMilli(),
+		// Prices are given in copper
+		"price": price.gold * 100 * 100,
+	})
+}
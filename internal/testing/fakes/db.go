@@ -0,0 +1,274 @@
+package fakes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DB implements database.DBTX, answering queries with canned rows keyed by the sqlc query name,
+// such as "GetLatestTokenPrice". It can start transactions, so it can back storage.NewPostgres.
+type DB struct {
+	mu        sync.Mutex
+	rows      map[string][][]any
+	errs      map[string]error
+	calls     map[string][][]any
+	commits   int
+	rollbacks int
+}
+
+func NewDB() *DB {
+	return &DB{
+		rows:  make(map[string][][]any),
+		errs:  make(map[string]error),
+		calls: make(map[string][][]any),
+	}
+}
+
+// SetRows sets the rows returned by a query. Rows are the row structs sqlc generates for the
+// query, such as database.EgsFreeGame, or bare values for queries returning a single column.
+// Queries returning a single row use the first one, or pgx.ErrNoRows without any.
+func (db *DB) SetRows(query string, rows ...any) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	values := make([][]any, len(rows))
+	for i, row := range rows {
+		values[i] = rowValues(reflect.ValueOf(row))
+	}
+
+	db.rows[query] = values
+}
+
+// SetError makes a query fail with err instead of returning its rows
+func (db *DB) SetError(query string, err error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.errs[query] = err
+}
+
+// Calls returns the arguments of every call made to a query
+func (db *DB) Calls(query string) [][]any {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.calls[query]
+}
+
+// Commits returns the number of transactions committed so far
+func (db *DB) Commits() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.commits
+}
+
+// Rollbacks returns the number of transactions rolled back so far
+func (db *DB) Rollbacks() int {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.rollbacks
+}
+
+// Begin starts a fake transaction. Its queries are answered by db like any other query, as the
+// canned rows cannot be rolled back, but commits and rollbacks are counted.
+func (db *DB) Begin(context.Context) (pgx.Tx, error) {
+	return &fakeTx{db: db}, nil
+}
+
+// queryName extracts the name from the "-- name: <name> :<kind>" comment sqlc starts queries with
+func queryName(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) < 3 || fields[0] != "--" || fields[1] != "name:" {
+		return sql
+	}
+
+	return fields[2]
+}
+
+func (db *DB) record(sql string, args []any) ([][]any, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	name := queryName(sql)
+	db.calls[name] = append(db.calls[name], args)
+
+	return db.rows[name], db.errs[name]
+}
+
+func (db *DB) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	rows, err := db.record(sql, args)
+	if err != nil {
+		return pgconn.CommandTag{}, err
+	}
+
+	// The number of rows is reported as the number of affected rows
+	return pgconn.NewCommandTag(fmt.Sprintf("UPDATE %d", len(rows))), nil
+}
+
+func (db *DB) Query(_ context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := db.record(sql, args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &fakeRows{rows: rows}, nil
+}
+
+func (db *DB) QueryRow(_ context.Context, sql string, args ...any) pgx.Row {
+	rows, err := db.record(sql, args)
+
+	return &fakeRow{rows: rows, err: err}
+}
+
+var (
+	scannerType = reflect.TypeFor[sql.Scanner]()
+	timeType    = reflect.TypeFor[time.Time]()
+)
+
+// rowValues flattens a row struct into its values in the order sqlc scans them, which is the
+// order of its fields. Models embedded through sqlc.embed are flattened in place.
+func rowValues(row reflect.Value) []any {
+	isValue := !row.IsValid() ||
+		row.Kind() != reflect.Struct ||
+		row.Type() == timeType ||
+		reflect.PointerTo(row.Type()).Implements(scannerType)
+	if isValue {
+		if !row.IsValid() {
+			return []any{nil}
+		}
+
+		return []any{row.Interface()}
+	}
+
+	var values []any
+	for i := range row.NumField() {
+		values = append(values, rowValues(row.Field(i))...)
+	}
+
+	return values
+}
+
+func scanValues(row []any, dest []any) error {
+	if len(row) != len(dest) {
+		return fmt.Errorf("fake row has %d values, but %d were scanned", len(row), len(dest))
+	}
+
+	for i, value := range row {
+		target := reflect.ValueOf(dest[i]).Elem()
+		if value == nil {
+			target.SetZero()
+			continue
+		}
+
+		v := reflect.ValueOf(value)
+		if !v.Type().AssignableTo(target.Type()) {
+			return fmt.Errorf("cannot scan %T into %s", value, target.Type())
+		}
+		target.Set(v)
+	}
+
+	return nil
+}
+
+type fakeRow struct {
+	rows [][]any
+	err  error
+}
+
+func (r *fakeRow) Scan(dest ...any) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if len(r.rows) == 0 {
+		return pgx.ErrNoRows
+	}
+
+	return scanValues(r.rows[0], dest)
+}
+
+type fakeRows struct {
+	rows    [][]any
+	current int
+}
+
+func (r *fakeRows) Close()                                       {}
+func (r *fakeRows) Err() error                                   { return nil }
+func (r *fakeRows) CommandTag() pgconn.CommandTag                { return pgconn.CommandTag{} }
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription { return nil }
+func (r *fakeRows) Values() ([]any, error)                       { return r.rows[r.current-1], nil }
+func (r *fakeRows) RawValues() [][]byte                          { return nil }
+func (r *fakeRows) Conn() *pgx.Conn                              { return nil }
+
+func (r *fakeRows) Next() bool {
+	r.current++
+	return r.current <= len(r.rows)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	return scanValues(r.rows[r.current-1], dest)
+}
+
+type fakeTx struct {
+	// Embedded for the methods the queries never use, which panic when called
+	pgx.Tx
+
+	db     *DB
+	closed bool
+}
+
+func (tx *fakeTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return tx.db.Begin(ctx)
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+
+	tx.closed = true
+
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	tx.db.commits++
+
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if tx.closed {
+		return pgx.ErrTxClosed
+	}
+
+	tx.closed = true
+
+	tx.db.mu.Lock()
+	defer tx.db.mu.Unlock()
+
+	tx.db.rollbacks++
+
+	return nil
+}
+
+func (tx *fakeTx) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	return tx.db.Exec(ctx, sql, args...)
+}
+
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	return tx.db.Query(ctx, sql, args...)
+}
+
+func (tx *fakeTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return tx.db.QueryRow(ctx, sql, args...)
+}
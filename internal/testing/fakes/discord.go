@@ -0,0 +1,143 @@
+package fakes
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Discord stands in for the channel message endpoints of a Discord session, keeping every
+// message sent so tests can inspect and edit them
+type Discord struct {
+	mu       sync.Mutex
+	messages map[string]*discordgo.Message
+	sent     []*discordgo.Message
+	edits    int
+	sendErr  error
+}
+
+func NewDiscord() *Discord {
+	return &Discord{
+		messages: make(map[string]*discordgo.Message),
+	}
+}
+
+// AddMessage stores a message as if it had been posted earlier
+func (d *Discord) AddMessage(message *discordgo.Message) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.messages[message.ID] = message
+}
+
+// DeleteMessage removes a message, so fetching or editing it fails with a 404 like Discord does
+func (d *Discord) DeleteMessage(messageID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	delete(d.messages, messageID)
+}
+
+// Message returns the current state of a message, or nil when there is no such message
+func (d *Discord) Message(messageID string) *discordgo.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.messages[messageID]
+}
+
+// Sent returns the messages sent so far, in order
+func (d *Discord) Sent() []*discordgo.Message {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]*discordgo.Message(nil), d.sent...)
+}
+
+// Edits returns the number of messages edited so far
+func (d *Discord) Edits() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.edits
+}
+
+// SetSendError makes sending messages fail with err until it is set back to nil
+func (d *Discord) SetSendError(err error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.sendErr = err
+}
+
+func (d *Discord) ChannelMessage(
+	channelID, messageID string,
+	_ ...discordgo.RequestOption,
+) (*discordgo.Message, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	message, ok := d.messages[messageID]
+	if !ok || message.ChannelID != channelID {
+		return nil, notFound()
+	}
+
+	return message, nil
+}
+
+func (d *Discord) ChannelMessageSendComplex(
+	channelID string,
+	data *discordgo.MessageSend,
+	_ ...discordgo.RequestOption,
+) (*discordgo.Message, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.sendErr != nil {
+		return nil, d.sendErr
+	}
+
+	message := &discordgo.Message{
+		ID:        fmt.Sprintf("message-%d", len(d.sent)+1),
+		ChannelID: channelID,
+		Content:   data.Content,
+		Embeds:    data.Embeds,
+	}
+
+	if data.AllowedMentions != nil {
+		message.MentionRoles = data.AllowedMentions.Roles
+	}
+
+	d.sent = append(d.sent, message)
+	d.messages[message.ID] = message
+
+	return message, nil
+}
+
+func (d *Discord) ChannelMessageEditEmbeds(
+	channelID, messageID string,
+	embeds []*discordgo.MessageEmbed,
+	_ ...discordgo.RequestOption,
+) (*discordgo.Message, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	message, ok := d.messages[messageID]
+	if !ok || message.ChannelID != channelID {
+		return nil, notFound()
+	}
+
+	message.Embeds = embeds
+	d.edits++
+
+	return message, nil
+}
+
+func notFound() error {
+	return &discordgo.RESTError{
+		Response: &http.Response{StatusCode: http.StatusNotFound, Status: "404 Not Found"},
+		Message:  &discordgo.APIErrorMessage{Code: discordgo.ErrCodeUnknownMessage},
+	}
+}
@@ -0,0 +1,177 @@
+package fakes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const egsFreeGamesEndpoint = "freeGames"
+
+// EGSGame is a game listed by the fake Epic Games Store API
+type EGSGame struct {
+	ID            string
+	Title         string
+	Description   string
+	ProductSlug   string
+	ThumbnailURL  string
+	OriginalPrice int
+	// The price during the promotion, upcoming games are listed at their original price until
+	// the promotion starts
+	DiscountPrice int
+	// The promotion, games without a start time are listed without any promotions
+	Start time.Time
+	End   time.Time
+	// Whether the promotion is listed as an upcoming offer rather than a current one
+	Upcoming bool
+}
+
+// EGSAPI emulates the Epic Games Store freeGamesPromotions endpoint
+type EGSAPI struct {
+	server *httptest.Server
+	queue  *responseQueue
+
+	mu    sync.Mutex
+	games []EGSGame
+}
+
+// NewEGSAPI starts a fake Epic Games Store API, which is stopped once the test finishes
+func NewEGSAPI(t testing.TB) *EGSAPI {
+	api := &EGSAPI{
+		queue: newResponseQueue(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /freeGamesPromotions", api.handleFreeGames)
+
+	api.server = httptest.NewServer(mux)
+	t.Cleanup(api.server.Close)
+
+	return api
+}
+
+func (api *EGSAPI) FreeGamesURL() string {
+	return api.server.URL + "/freeGamesPromotions?locale=en-US&country=US&allowCountries=US"
+}
+
+func (api *EGSAPI) ProductBaseURL() string {
+	return api.server.URL + "/product/"
+}
+
+// SetGames sets the games listed by the API
+func (api *EGSAPI) SetGames(games ...EGSGame) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.games = games
+}
+
+// QueueResponses scripts the next responses of the API
+func (api *EGSAPI) QueueResponses(responses ...Response) {
+	api.queue.push(egsFreeGamesEndpoint, responses...)
+}
+
+// Requests returns the number of requests made to the API
+func (api *EGSAPI) Requests() int {
+	return api.queue.count(egsFreeGamesEndpoint)
+}
+
+func (api *EGSAPI) handleFreeGames(w http.ResponseWriter, req *http.Request) {
+	if resp, ok := api.queue.next(egsFreeGamesEndpoint); ok {
+		resp.write(w)
+		return
+	}
+
+	api.mu.Lock()
+	elements := make([]map[string]any, 0, len(api.games))
+	for _, game := range api.games {
+		elements = append(elements, egsElement(game))
+	}
+	api.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"data": map[string]any{
+			"Catalog": map[string]any{
+				"searchStore": map[string]any{
+					"elements": elements,
+				},
+			},
+		},
+	})
+}
+
+func egsElement(game EGSGame) map[string]any {
+	currentPrice := game.DiscountPrice
+	if game.Upcoming {
+		currentPrice = game.OriginalPrice
+	}
+
+	// The promotional price as a percentage of the original price
+	discountPercentage := 0
+	if game.OriginalPrice > 0 {
+		discountPercentage = game.DiscountPrice * 100 / game.OriginalPrice
+	}
+
+	element := map[string]any{
+		"title":       game.Title,
+		"id":          game.ID,
+		"description": game.Description,
+		"productSlug": game.ProductSlug,
+		"urlSlug":     game.ProductSlug,
+		"offerType":   "BASE_GAME",
+		"keyImages": []map[string]string{
+			{"type": "Thumbnail", "url": game.ThumbnailURL},
+		},
+		"catalogNs": map[string]any{
+			"mappings": []map[string]string{
+				{"pageSlug": game.ProductSlug, "pageType": "productHome"},
+			},
+		},
+		"price": map[string]any{
+			"totalPrice": map[string]any{
+				"originalPrice": game.OriginalPrice,
+				"discountPrice": currentPrice,
+			},
+		},
+		// The API returns null for games without any promotions
+		"promotions": nil,
+	}
+
+	if game.Start.IsZero() {
+		return element
+	}
+
+	offers := []map[string]any{
+		{
+			"promotionalOffers": []map[string]any{
+				{
+					"startDate": game.Start.UTC().Format(time.RFC3339Nano),
+					"endDate":   game.End.UTC().Format(time.RFC3339Nano),
+					"discountSetting": map[string]any{
+						"discountType":       "PERCENTAGE",
+						"discountPercentage": discountPercentage,
+					},
+				},
+			},
+		},
+	}
+
+	promotions := map[string]any{
+		"promotionalOffers":         []any{},
+		"upcomingPromotionalOffers": []any{},
+	}
+
+	if game.Upcoming {
+		promotions["upcomingPromotionalOffers"] = offers
+	} else {
+		promotions["promotionalOffers"] = offers
+	}
+
+	element["promotions"] = promotions
+
+	return element
+}
@@ -0,0 +1,102 @@
+package fakes
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+const gogCatalogEndpoint = "catalog"
+
+// GOGProduct is a product listed by the fake GOG catalog API, with prices in cents
+type GOGProduct struct {
+	ID           string
+	Title        string
+	Slug         string
+	CoverURL     string
+	BasePrice    int
+	DiscountedTo int
+}
+
+// GOGAPI emulates the GOG catalog search endpoint
+type GOGAPI struct {
+	server *httptest.Server
+	queue  *responseQueue
+
+	mu       sync.Mutex
+	products []GOGProduct
+}
+
+// NewGOGAPI starts a fake GOG catalog API, which is stopped once the test finishes
+func NewGOGAPI(t testing.TB) *GOGAPI {
+	api := &GOGAPI{
+		queue: newResponseQueue(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/catalog", api.handleCatalog)
+
+	api.server = httptest.NewServer(mux)
+	t.Cleanup(api.server.Close)
+
+	return api
+}
+
+func (api *GOGAPI) CatalogURL() string {
+	return api.server.URL + "/v1/catalog?price=between:0,0&discounted=eq:true"
+}
+
+func (api *GOGAPI) ProductBaseURL() string {
+	return api.server.URL + "/game/"
+}
+
+// SetProducts sets the products listed by the API
+func (api *GOGAPI) SetProducts(products ...GOGProduct) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.products = products
+}
+
+// QueueResponses scripts the next responses of the API
+func (api *GOGAPI) QueueResponses(responses ...Response) {
+	api.queue.push(gogCatalogEndpoint, responses...)
+}
+
+func (api *GOGAPI) handleCatalog(w http.ResponseWriter, req *http.Request) {
+	if resp, ok := api.queue.next(gogCatalogEndpoint); ok {
+		resp.write(w)
+		return
+	}
+
+	api.mu.Lock()
+	products := make([]map[string]any, 0, len(api.products))
+	for _, product := range api.products {
+		products = append(products, map[string]any{
+			"id":              product.ID,
+			"slug":            product.Slug,
+			"title":           product.Title,
+			"coverHorizontal": product.CoverURL,
+			"price": map[string]any{
+				"final":      gogAmount(product.DiscountedTo),
+				"base":       gogAmount(product.BasePrice),
+				"finalMoney": map[string]string{"amount": gogAmount(product.DiscountedTo)},
+				"baseMoney":  map[string]string{"amount": gogAmount(product.BasePrice)},
+			},
+		})
+	}
+	api.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"products": products,
+	})
+}
+
+// gogAmount formats cents the way the API formats amounts of money
+func gogAmount(cents int) string {
+	return fmt.Sprintf("%d.%02d", cents/100, cents%100)
+}
@@ -0,0 +1,81 @@
+// Package fakes provides stand-ins for the database and the external APIs used by the bot, so
+// tests can run without a Postgres server or network access.
+package fakes
+
+import (
+	"net/http"
+	"sync"
+)
+
+// Response is a scripted HTTP response, returned by a fake API server instead of its regular
+// response
+type Response struct {
+	Status int
+	Body   string
+	Header http.Header
+}
+
+func (r Response) write(w http.ResponseWriter) {
+	for name, values := range r.Header {
+		for _, value := range values {
+			w.Header().Add(name, value)
+		}
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
+
+	status := r.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	w.WriteHeader(status)
+	w.Write([]byte(r.Body))
+}
+
+// responseQueue holds scripted responses per endpoint, which are used up in order
+type responseQueue struct {
+	mu        sync.Mutex
+	responses map[string][]Response
+	requests  map[string]int
+}
+
+func newResponseQueue() *responseQueue {
+	return &responseQueue{
+		responses: make(map[string][]Response),
+		requests:  make(map[string]int),
+	}
+}
+
+func (q *responseQueue) push(endpoint string, responses ...Response) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.responses[endpoint] = append(q.responses[endpoint], responses...)
+}
+
+// next counts a request to the endpoint and returns the next scripted response for it, if any
+func (q *responseQueue) next(endpoint string) (Response, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.requests[endpoint]++
+
+	queued := q.responses[endpoint]
+	if len(queued) == 0 {
+		return Response{}, false
+	}
+
+	q.responses[endpoint] = queued[1:]
+
+	return queued[0], true
+}
+
+func (q *responseQueue) count(endpoint string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.requests[endpoint]
+}
@@ -0,0 +1,107 @@
+package fakes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+const steamFeaturedEndpoint = "featuredcategories"
+
+// SteamItem is a special featured by the fake Steam store API, with prices in cents
+type SteamItem struct {
+	ID              int64
+	Name            string
+	HeaderImage     string
+	OriginalPrice   int
+	DiscountPercent int
+	// When the discount ends, the API leaves the expiration out when zero
+	Expires time.Time
+}
+
+// SteamAPI emulates the featuredcategories endpoint of the Steam store API
+type SteamAPI struct {
+	server *httptest.Server
+	queue  *responseQueue
+
+	mu    sync.Mutex
+	items []SteamItem
+}
+
+// NewSteamAPI starts a fake Steam store API, which is stopped once the test finishes
+func NewSteamAPI(t testing.TB) *SteamAPI {
+	api := &SteamAPI{
+		queue: newResponseQueue(),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/featuredcategories", api.handleFeatured)
+
+	api.server = httptest.NewServer(mux)
+	t.Cleanup(api.server.Close)
+
+	return api
+}
+
+func (api *SteamAPI) FeaturedURL() string {
+	return api.server.URL + "/api/featuredcategories?cc=US&l=english"
+}
+
+func (api *SteamAPI) AppBaseURL() string {
+	return api.server.URL + "/app/"
+}
+
+// SetItems sets the specials featured by the API
+func (api *SteamAPI) SetItems(items ...SteamItem) {
+	api.mu.Lock()
+	defer api.mu.Unlock()
+
+	api.items = items
+}
+
+// QueueResponses scripts the next responses of the API
+func (api *SteamAPI) QueueResponses(responses ...Response) {
+	api.queue.push(steamFeaturedEndpoint, responses...)
+}
+
+func (api *SteamAPI) handleFeatured(w http.ResponseWriter, req *http.Request) {
+	if resp, ok := api.queue.next(steamFeaturedEndpoint); ok {
+		resp.write(w)
+		return
+	}
+
+	api.mu.Lock()
+	items := make([]map[string]any, 0, len(api.items))
+	for _, item := range api.items {
+		special := map[string]any{
+			"id":               item.ID,
+			"type":             0,
+			"name":             item.Name,
+			"discounted":       item.DiscountPercent > 0,
+			"discount_percent": item.DiscountPercent,
+			"original_price":   item.OriginalPrice,
+			"final_price":      item.OriginalPrice * (100 - item.DiscountPercent) / 100,
+			"currency":         "USD",
+			"header_image":     item.HeaderImage,
+		}
+
+		if !item.Expires.IsZero() {
+			special["discount_expiration"] = item.Expires.Unix()
+		}
+
+		items = append(items, special)
+	}
+	api.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]any{
+		"specials": map[string]any{
+			"id":    "cat_specials",
+			"name":  "Specials",
+			"items": items,
+		},
+	})
+}
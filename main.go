@@ -16,6 +16,13 @@ func main() {
 		log.Printf("Could not determine working directory \"%v\"", err)
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := bot.Export(ctx, os.Args[2:], os.Getenv, wd, os.Stdout); err != nil {
+			log.Fatalf("%s\n", err)
+		}
+		return
+	}
+
 	if err := bot.Run(ctx, os.Getenv, wd); err != nil {
 		log.Fatalf("%s\n", err)
 	}
@@ -1,17 +1,17 @@
 -- name: GetLatestTokenPrice :one
-SELECT * FROM wow_token_prices ORDER BY id DESC LIMIT 1;
+SELECT * FROM wow_token_prices WHERE region = $1 ORDER BY id DESC LIMIT 1;
 
 -- name: GetAllTokenPrices :many
 SELECT * FROM wow_token_prices ORDER BY id DESC;
 
 -- name: GetAllTokenPricesSince :many
-SELECT price, updated FROM wow_token_prices WHERE updated >= $1 ORDER BY id DESC;
+SELECT price, updated FROM wow_token_prices WHERE region = $1 AND updated >= $2 ORDER BY id DESC;
 
 -- name: AddTokenPrice :one
 INSERT INTO wow_token_prices (
-    updated, price
+    region, updated, price
 ) VALUES (
-    $1, $2
+    $1, $2, $3
 )
 RETURNING *;
 
@@ -21,6 +21,70 @@ SELECT * from egs_free_games WHERE start_date < NOW() AND end_date > NOW() ORDER
 -- name: GetAllFreeGames :many
 SELECT * from egs_free_games ORDER BY id DESC;
 
+-- name: GetUnexpiredFreeGames :many
+SELECT * from egs_free_games WHERE end_date > NOW() ORDER BY id DESC;
+
+-- name: MarkFreeGameLive :execrows
+UPDATE egs_free_games SET status = 'live' WHERE id = $1 AND status = 'upcoming';
+
+-- name: GetFreeGamesEndingBefore :many
+SELECT * from egs_free_games
+WHERE status = 'live' AND NOT reminded AND NOT open_ended AND end_date > NOW() AND end_date <= $1
+ORDER BY end_date;
+
+-- name: MarkFreeGameReminded :execrows
+UPDATE egs_free_games SET reminded = TRUE WHERE id = $1 AND NOT reminded;
+
+-- name: ExtendFreeGame :exec
+UPDATE egs_free_games SET end_date = $2 WHERE id = $1 AND open_ended;
+
+-- name: GetOpenEndedFreeGame :one
+SELECT * FROM egs_free_games WHERE source = $1 AND store_id = $2 AND open_ended FOR UPDATE;
+
+-- name: RenewFreeGame :exec
+UPDATE egs_free_games SET start_date = $2, end_date = $3 WHERE id = $1 AND open_ended;
+
+-- name: AddFreeGameAnnouncement :exec
+INSERT INTO egs_free_game_announcements (
+    free_game_id, channel_id, message_id
+) VALUES (
+    $1, $2, $3
+);
+
+-- name: GetExpiredFreeGameAnnouncements :many
+SELECT a.id, a.channel_id, a.message_id, g.title
+FROM egs_free_game_announcements a
+JOIN egs_free_games g ON g.id = a.free_game_id
+WHERE g.end_date <= NOW() AND NOT a.expired
+ORDER BY a.id;
+
+-- name: MarkFreeGameAnnouncementExpired :exec
+UPDATE egs_free_game_announcements SET expired = TRUE WHERE id = $1;
+
+-- name: AddFreeGameOutbox :exec
+INSERT INTO egs_free_game_outbox (
+    free_game_id, channel_id, kind, ping_role_id
+) VALUES (
+    $1, $2, $3, $4
+);
+
+-- name: GetPendingFreeGameOutbox :many
+SELECT o.id, o.channel_id, o.kind, o.ping_role_id, o.attempts, sqlc.embed(g)
+FROM egs_free_game_outbox o
+JOIN egs_free_games g ON g.id = o.free_game_id
+WHERE NOT o.sent AND o.attempts < $1 AND o.next_attempt <= NOW() AND g.end_date > NOW()
+ORDER BY o.id;
+
+-- name: ClaimFreeGameOutbox :execrows
+UPDATE egs_free_game_outbox SET next_attempt = $2
+WHERE id = $1 AND NOT sent AND next_attempt <= NOW();
+
+-- name: MarkFreeGameOutboxSent :exec
+UPDATE egs_free_game_outbox SET sent = TRUE, message_id = $2 WHERE id = $1;
+
+-- name: MarkFreeGameOutboxFailed :exec
+UPDATE egs_free_game_outbox SET attempts = attempts + 1, next_attempt = $2 WHERE id = $1;
+
 -- name: AddFreeGame :one
 INSERT INTO egs_free_games (
     store_id,
@@ -29,8 +93,75 @@ INSERT INTO egs_free_games (
     url,
     thumbnail_url,
     start_date,
-    end_date
+    end_date,
+    status,
+    source,
+    open_ended
+) VALUES (
+    $1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+)
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetTokenAlertsForRegion :many
+SELECT * FROM wow_token_alerts WHERE region = $1 ORDER BY id;
+
+-- name: GetTokenAlertsForUser :many
+SELECT * FROM wow_token_alerts WHERE user_id = $1 ORDER BY id;
+
+-- name: AddTokenAlert :one
+INSERT INTO wow_token_alerts (
+    user_id, guild_id, region, direction, threshold
 ) VALUES (
-    $1, $2, $3, $4, $5, $6, $7
+    $1, $2, $3, $4, $5
 )
-RETURNING *;
\ No newline at end of file
+RETURNING *;
+
+-- name: SetTokenAlertTriggered :exec
+UPDATE wow_token_alerts SET triggered = $2 WHERE id = $1;
+
+-- name: DeleteTokenAlert :execrows
+DELETE FROM wow_token_alerts WHERE id = $1 AND user_id = $2;
+
+-- name: GetTokenPricesBetween :many
+SELECT price, updated FROM wow_token_prices
+WHERE region = sqlc.arg(region) AND updated >= sqlc.arg(since) AND updated <= sqlc.arg(until)
+ORDER BY updated ASC;
+
+-- name: GetTokenPricesPage :many
+SELECT * FROM wow_token_prices WHERE id > $1 ORDER BY id ASC LIMIT $2;
+
+-- name: GetGuildSettings :one
+SELECT * FROM guild_settings WHERE guild_id = $1;
+
+-- name: GetAllGuildSettings :many
+SELECT * FROM guild_settings ORDER BY guild_id;
+
+-- name: UpsertGuildSettings :one
+INSERT INTO guild_settings (
+    guild_id, deals_channel_id, alerts_channel_id, region, locale, ping_role_id
+) VALUES (
+    $1, $2, $3, $4, $5, $6
+)
+ON CONFLICT (guild_id) DO UPDATE SET
+    deals_channel_id = excluded.deals_channel_id,
+    alerts_channel_id = excluded.alerts_channel_id,
+    region = excluded.region,
+    locale = excluded.locale,
+    ping_role_id = excluded.ping_role_id,
+    updated = NOW()
+RETURNING *;
+
+-- name: RecordJobRun :exec
+INSERT INTO job_runs (
+    job, started, duration_ms, error
+) VALUES (
+    $1, $2, $3, $4
+)
+ON CONFLICT (job) DO UPDATE SET
+    started = excluded.started,
+    duration_ms = excluded.duration_ms,
+    error = excluded.error;
+
+-- name: GetJobRuns :many
+SELECT * FROM job_runs ORDER BY job;
@@ -0,0 +1,180 @@
+-- Queries for the SQLite backend, kept in step with query.sql. Timestamps are unix milliseconds.
+
+-- name: GetLatestTokenPrice :one
+SELECT * FROM wow_token_prices WHERE region = ? ORDER BY id DESC LIMIT 1;
+
+-- name: GetAllTokenPrices :many
+SELECT * FROM wow_token_prices ORDER BY id DESC;
+
+-- name: GetAllTokenPricesSince :many
+SELECT price, updated FROM wow_token_prices WHERE region = ? AND updated >= ? ORDER BY id DESC;
+
+-- name: AddTokenPrice :one
+INSERT INTO wow_token_prices (
+    region, updated, price
+) VALUES (
+    ?, ?, ?
+)
+RETURNING *;
+
+-- name: GetCurrentFreeGames :many
+SELECT * from egs_free_games
+WHERE start_date < CAST(unixepoch('subsec') * 1000 AS INTEGER)
+    AND end_date > CAST(unixepoch('subsec') * 1000 AS INTEGER)
+ORDER BY id DESC;
+
+-- name: GetAllFreeGames :many
+SELECT * from egs_free_games ORDER BY id DESC;
+
+-- name: GetUnexpiredFreeGames :many
+SELECT * from egs_free_games
+WHERE end_date > CAST(unixepoch('subsec') * 1000 AS INTEGER)
+ORDER BY id DESC;
+
+-- name: MarkFreeGameLive :execrows
+UPDATE egs_free_games SET status = 'live' WHERE id = ? AND status = 'upcoming';
+
+-- name: GetFreeGamesEndingBefore :many
+SELECT * from egs_free_games
+WHERE status = 'live'
+    AND NOT reminded
+    AND NOT open_ended
+    AND end_date > CAST(unixepoch('subsec') * 1000 AS INTEGER)
+    AND end_date <= ?
+ORDER BY end_date;
+
+-- name: MarkFreeGameReminded :execrows
+UPDATE egs_free_games SET reminded = TRUE WHERE id = ? AND NOT reminded;
+
+-- name: ExtendFreeGame :exec
+UPDATE egs_free_games SET end_date = ? WHERE id = ? AND open_ended;
+
+-- name: GetOpenEndedFreeGame :one
+SELECT * FROM egs_free_games WHERE source = ? AND store_id = ? AND open_ended;
+
+-- name: RenewFreeGame :exec
+UPDATE egs_free_games SET start_date = ?, end_date = ? WHERE id = ? AND open_ended;
+
+-- name: AddFreeGameAnnouncement :exec
+INSERT INTO egs_free_game_announcements (
+    free_game_id, channel_id, message_id
+) VALUES (
+    ?, ?, ?
+);
+
+-- name: GetExpiredFreeGameAnnouncements :many
+SELECT a.id, a.channel_id, a.message_id, g.title
+FROM egs_free_game_announcements a
+JOIN egs_free_games g ON g.id = a.free_game_id
+WHERE g.end_date <= CAST(unixepoch('subsec') * 1000 AS INTEGER) AND NOT a.expired
+ORDER BY a.id;
+
+-- name: MarkFreeGameAnnouncementExpired :exec
+UPDATE egs_free_game_announcements SET expired = TRUE WHERE id = ?;
+
+-- name: AddFreeGameOutbox :exec
+INSERT INTO egs_free_game_outbox (
+    free_game_id, channel_id, kind, ping_role_id
+) VALUES (
+    ?, ?, ?, ?
+);
+
+-- name: GetPendingFreeGameOutbox :many
+SELECT o.id, o.channel_id, o.kind, o.ping_role_id, o.attempts, sqlc.embed(g)
+FROM egs_free_game_outbox o
+JOIN egs_free_games g ON g.id = o.free_game_id
+WHERE NOT o.sent AND o.attempts < ?
+    AND o.next_attempt <= CAST(unixepoch('subsec') * 1000 AS INTEGER)
+    AND g.end_date > CAST(unixepoch('subsec') * 1000 AS INTEGER)
+ORDER BY o.id;
+
+-- name: ClaimFreeGameOutbox :execrows
+UPDATE egs_free_game_outbox SET next_attempt = ?
+WHERE id = ? AND NOT sent AND next_attempt <= CAST(unixepoch('subsec') * 1000 AS INTEGER);
+
+-- name: MarkFreeGameOutboxSent :exec
+UPDATE egs_free_game_outbox SET sent = TRUE, message_id = ? WHERE id = ?;
+
+-- name: MarkFreeGameOutboxFailed :exec
+UPDATE egs_free_game_outbox SET attempts = attempts + 1, next_attempt = ? WHERE id = ?;
+
+-- name: AddFreeGame :one
+INSERT INTO egs_free_games (
+    store_id,
+    title,
+    description,
+    url,
+    thumbnail_url,
+    start_date,
+    end_date,
+    status,
+    source,
+    open_ended
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetTokenAlertsForRegion :many
+SELECT * FROM wow_token_alerts WHERE region = ? ORDER BY id;
+
+-- name: GetTokenAlertsForUser :many
+SELECT * FROM wow_token_alerts WHERE user_id = ? ORDER BY id;
+
+-- name: AddTokenAlert :one
+INSERT INTO wow_token_alerts (
+    user_id, guild_id, region, direction, threshold
+) VALUES (
+    ?, ?, ?, ?, ?
+)
+RETURNING *;
+
+-- name: SetTokenAlertTriggered :exec
+UPDATE wow_token_alerts SET triggered = ? WHERE id = ?;
+
+-- name: DeleteTokenAlert :execrows
+DELETE FROM wow_token_alerts WHERE id = ? AND user_id = ?;
+
+-- name: GetTokenPricesBetween :many
+SELECT price, updated FROM wow_token_prices
+WHERE region = sqlc.arg(region) AND updated >= sqlc.arg(since) AND updated <= sqlc.arg(until)
+ORDER BY updated ASC;
+
+-- name: GetTokenPricesPage :many
+SELECT * FROM wow_token_prices WHERE id > ? ORDER BY id ASC LIMIT ?;
+
+-- name: GetGuildSettings :one
+SELECT * FROM guild_settings WHERE guild_id = ?;
+
+-- name: GetAllGuildSettings :many
+SELECT * FROM guild_settings ORDER BY guild_id;
+
+-- name: UpsertGuildSettings :one
+INSERT INTO guild_settings (
+    guild_id, deals_channel_id, alerts_channel_id, region, locale, ping_role_id
+) VALUES (
+    ?, ?, ?, ?, ?, ?
+)
+ON CONFLICT (guild_id) DO UPDATE SET
+    deals_channel_id = excluded.deals_channel_id,
+    alerts_channel_id = excluded.alerts_channel_id,
+    region = excluded.region,
+    locale = excluded.locale,
+    ping_role_id = excluded.ping_role_id,
+    updated = CAST(unixepoch('subsec') * 1000 AS INTEGER)
+RETURNING *;
+
+-- name: RecordJobRun :exec
+INSERT INTO job_runs (
+    job, started, duration_ms, error
+) VALUES (
+    ?, ?, ?, ?
+)
+ON CONFLICT (job) DO UPDATE SET
+    started = excluded.started,
+    duration_ms = excluded.duration_ms,
+    error = excluded.error;
+
+-- name: GetJobRuns :many
+SELECT * FROM job_runs ORDER BY job;